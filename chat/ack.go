@@ -0,0 +1,217 @@
+package chat
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"go_chat_client/util/clock"
+
+	"github.com/samber/lo"
+)
+
+// represents bounds of time to wait for post message response before retrying to send the message.
+const (
+	minAckTimeout     = time.Second * 2
+	maxAckTimeout     = time.Second * 30
+	defaultAckTimeout = time.Second * 10
+)
+
+// defaultPostAttempts is the maximum amount of attempts to send message, used if it's not set in config.
+const defaultPostAttempts = 3
+
+// pendingMsg represents sent message waiting for confirmation from server.
+type pendingMsg struct {
+	id       int64
+	msg      string
+	action   bool
+	attempts int
+	timer    clock.Timer
+}
+
+// pendingMsgs represents sent messages waiting for confirmation from server, by their client-generated IDs.
+type pendingMsgs struct {
+	mu        sync.Mutex
+	lastID    int64
+	msgs      map[int64]*pendingMsg
+	afterFunc func(d time.Duration, f func()) clock.Timer // Schedules timeouts of messages
+	emptyCh   chan struct{}                               // Closed once there are no pending messages, if not nil
+	untracked bool                                        // Server doesn't return message IDs, see untrack
+}
+
+// newID returns new unique message ID.
+func (p *pendingMsgs) newID() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastID++
+	return p.lastID
+}
+
+// push adds message <msg> with <id>, which is action message if <action> is true, to pending messages or counts one
+// more attempt to send it if it's already pending. If <timeout> is not 0, it runs <onTimeout> if message is not
+// confirmed within <timeout>. It returns amount of messages waiting for confirmation.
+func (p *pendingMsgs) push(id int64, msg string, action bool, timeout time.Duration, onTimeout func()) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.msgs == nil {
+		p.msgs = map[int64]*pendingMsg{}
+	}
+	pending, ok := p.msgs[id]
+	if !ok {
+		pending = &pendingMsg{id: id, msg: msg, action: action}
+		p.msgs[id] = pending
+	}
+	pending.attempts++
+	if pending.timer != nil {
+		pending.timer.Stop()
+		pending.timer = nil
+	}
+	if timeout != 0 {
+		pending.timer = p.afterFunc(timeout, onTimeout)
+	}
+	return len(p.msgs)
+}
+
+// pop removes message with <id> from pending messages, marking it as confirmed. It returns amount of messages still
+// waiting for confirmation.
+func (p *pendingMsgs) pop(id int64) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pending, ok := p.msgs[id]; ok {
+		if pending.timer != nil {
+			pending.timer.Stop()
+		}
+		delete(p.msgs, id)
+	}
+	p.notifyEmpty()
+	return len(p.msgs)
+}
+
+// untrack stops waiting for confirmation of messages, since server doesn't return their IDs and it's unknown which
+// message response is for. Pending messages are removed and their IDs are returned. Subsequent calls return nil.
+func (p *pendingMsgs) untrack() []int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.untracked {
+		return nil
+	}
+	p.untracked = true
+	ids := lo.Keys(p.msgs)
+	for _, pending := range p.msgs {
+		if pending.timer != nil {
+			pending.timer.Stop()
+		}
+	}
+	p.msgs = nil
+	p.notifyEmpty()
+	slices.Sort(ids)
+	return ids
+}
+
+// tracked returns false if messages are not waiting for confirmation since untrack is called.
+func (p *pendingMsgs) tracked() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return !p.untracked
+}
+
+// attempts returns amount of attempts to send message with <id>, or 0 if it's not pending.
+func (p *pendingMsgs) attempts(id int64) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pending, ok := p.msgs[id]; ok {
+		return pending.attempts
+	}
+	return 0
+}
+
+// stop stops waiting for confirmation of pending messages without running their timeout functions. Messages stay
+// pending, so they can be retried.
+func (p *pendingMsgs) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, pending := range p.msgs {
+		if pending.timer != nil {
+			pending.timer.Stop()
+			pending.timer = nil
+		}
+	}
+}
+
+// len returns amount of messages waiting for confirmation.
+func (p *pendingMsgs) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.msgs)
+}
+
+// retries returns pending messages sent less than <maxAttempts> times, oldest first, and removes the rest. It also
+// returns IDs of removed messages.
+func (p *pendingMsgs) retries(maxAttempts int) ([]pendingMsg, []int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var retries []pendingMsg
+	var dropped []int64
+	for id, pending := range p.msgs {
+		if pending.attempts < maxAttempts {
+			retries = append(retries, *pending)
+			continue
+		}
+		delete(p.msgs, id)
+		dropped = append(dropped, id)
+	}
+	slices.SortFunc(retries, func(a, b pendingMsg) int {
+		return int(a.id - b.id)
+	})
+	p.notifyEmpty()
+	return retries, dropped
+}
+
+// empty returns channel which is closed once there are no messages waiting for confirmation. If there are none
+// already, returned channel is closed.
+func (p *pendingMsgs) empty() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.emptyCh == nil {
+		p.emptyCh = make(chan struct{})
+	}
+	ch := p.emptyCh
+	p.notifyEmpty()
+	return ch
+}
+
+// notifyEmpty closes channels returned by empty if there are no pending messages. Must be called with mu locked.
+func (p *pendingMsgs) notifyEmpty() {
+	if p.emptyCh != nil && len(p.msgs) == 0 {
+		close(p.emptyCh)
+		p.emptyCh = nil
+	}
+}
+
+// ackTimeout returns time to wait for message confirmation, adapted to round-trip time <rtt>. It's 3 times <rtt>, but
+// no less than minAckTimeout and no more than maxAckTimeout. If <rtt> wasn't measured yet, defaultAckTimeout is used.
+func ackTimeout(rtt time.Duration) time.Duration {
+	if rtt <= 0 {
+		return defaultAckTimeout
+	}
+	return min(max(rtt*3, minAckTimeout), maxAckTimeout)
+}
+
+// Flush blocks until all sent messages are confirmed by server and returns true, or returns false if some of them are
+// still not confirmed after <timeout>. Used before closing connection, so messages sent right before exit are not
+// lost.
+func (h *Handler) Flush(timeout time.Duration) bool {
+	empty := h.pending.empty()
+	select {
+	case <-empty:
+		return true
+	case <-h.Clock.After(timeout):
+		// Messages may be confirmed right at deadline.
+		select {
+		case <-empty:
+			return true
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package chat
+
+import (
+	"testing"
+	"time"
+
+	"go_chat_client/config"
+	"go_chat_client/protocol"
+)
+
+func TestAckTimeout(t *testing.T) {
+	tests := []struct {
+		rtt  time.Duration
+		want time.Duration
+	}{
+		{rtt: 0, want: defaultAckTimeout},
+		{rtt: time.Millisecond * 10, want: minAckTimeout},
+		{rtt: time.Second * 2, want: time.Second * 6},
+		{rtt: time.Minute, want: maxAckTimeout},
+	}
+	for _, tt := range tests {
+		if got := ackTimeout(tt.rtt); got != tt.want {
+			t.Errorf("ackTimeout(%v) is %v, want %v", tt.rtt, got, tt.want)
+		}
+	}
+}
+
+func TestPostRetriedUntilConfirmed(t *testing.T) {
+	h, transport, fake := newTestHandler(t, &config.Config{Nickname: "alice"})
+	h.HandlePostMessageResponse()
+
+	h.PostMessage("hi")
+	first := transport.nextOfType(t, protocol.TypePostMessageReq)
+	fake.Advance(defaultAckTimeout - time.Millisecond)
+	transport.assertNoRequest(t, protocol.TypePostMessageReq)
+
+	fake.Advance(time.Millisecond)
+	retry := transport.nextOfType(t, protocol.TypePostMessageReq)
+	if retry["id"] != first["id"] || retry["msg"] != "hi" {
+		t.Errorf("Retry is %v, want the same message as %v", retry, first)
+	}
+
+	transport.deliver(postResp(retry["id"]))
+	if n := h.pending.len(); n != 0 {
+		t.Errorf("%v messages are pending after confirmation, want 0", n)
+	}
+	fake.Advance(defaultAckTimeout)
+	transport.assertNoRequest(t, protocol.TypePostMessageReq)
+}
+
+func TestPostDroppedAfterAttempts(t *testing.T) {
+	h, transport, fake := newTestHandler(t, &config.Config{Nickname: "alice", PostAttempts: 2})
+
+	h.PostMessage("hi")
+	transport.nextOfType(t, protocol.TypePostMessageReq)
+	fake.Advance(defaultAckTimeout)
+	transport.nextOfType(t, protocol.TypePostMessageReq)
+	fake.Advance(defaultAckTimeout)
+
+	transport.assertNoRequest(t, protocol.TypePostMessageReq)
+	if n := h.pending.len(); n != 0 {
+		t.Errorf("%v messages are pending after the last attempt, want 0", n)
+	}
+	if waiters := fake.Waiters(); waiters != 0 {
+		t.Errorf("%v timers are waiting after the last attempt, want 0", waiters)
+	}
+}
+
+func TestPendingStop(t *testing.T) {
+	h, transport, fake := newTestHandler(t, &config.Config{Nickname: "alice"})
+
+	h.PostMessage("hi")
+	transport.nextOfType(t, protocol.TypePostMessageReq)
+	h.pending.stop()
+	fake.Advance(defaultAckTimeout)
+
+	transport.assertNoRequest(t, protocol.TypePostMessageReq)
+	retries, dropped := h.pending.retries(h.postAttempts)
+	if len(retries) != 1 || len(dropped) != 0 {
+		t.Errorf("Pending message is not kept for retry after stop: retries %v, dropped %v", retries, dropped)
+	}
+}
+
+func TestFlushWaitsForConfirmation(t *testing.T) {
+	h, transport, _ := newTestHandler(t, &config.Config{Nickname: "alice"})
+	h.HandlePostMessageResponse()
+	if !h.Flush(0) {
+		t.Fatal("Flush without pending messages returned false")
+	}
+
+	h.PostMessage("hi")
+	req := transport.nextOfType(t, protocol.TypePostMessageReq)
+	flushed := make(chan bool, 1)
+	go func() {
+		flushed <- h.Flush(time.Minute)
+	}()
+	select {
+	case <-flushed:
+		t.Fatal("Flush returned before message is confirmed")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	transport.deliver(postResp(req["id"]))
+	select {
+	case ok := <-flushed:
+		if !ok {
+			t.Error("Flush returned false once message is confirmed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Flush didn't return once message is confirmed")
+	}
+}
+
+func TestFlushTimeout(t *testing.T) {
+	h, transport, fake := newTestHandler(t, &config.Config{Nickname: "alice"})
+	h.HandlePostMessageResponse()
+
+	h.PostMessage("hi")
+	transport.nextOfType(t, protocol.TypePostMessageReq)
+	flushed := make(chan bool, 1)
+	go func() {
+		flushed <- h.Flush(time.Second)
+	}()
+	// Ack timeout of message and Flush timeout.
+	waitForWaiters(t, fake, 2)
+	fake.Advance(time.Second)
+
+	select {
+	case ok := <-flushed:
+		if ok {
+			t.Error("Flush returned true while message is not confirmed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Flush didn't return after timeout")
+	}
+}
+
+func TestRetriesDisabledWithoutMessageIDs(t *testing.T) {
+	h, transport, fake := newTestHandler(t, &config.Config{Nickname: "alice"})
+	h.HandlePostMessageResponse()
+
+	h.PostMessage("first")
+	transport.nextOfType(t, protocol.TypePostMessageReq)
+	h.PostMessage("second")
+	transport.nextOfType(t, protocol.TypePostMessageReq)
+	transport.deliver(postResp(0))
+	if n := h.pending.len(); n != 0 {
+		t.Errorf("%v messages are pending once server responds without ID, want 0", n)
+	}
+
+	h.PostMessage("third")
+	transport.nextOfType(t, protocol.TypePostMessageReq)
+	if n := h.pending.len(); n != 0 {
+		t.Errorf("%v messages are pending after server responded without ID, want 0", n)
+	}
+	fake.Advance(maxAckTimeout)
+	transport.assertNoRequest(t, protocol.TypePostMessageReq)
+	if !h.Flush(0) {
+		t.Error("Flush returned false while messages are not tracked")
+	}
+}
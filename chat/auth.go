@@ -0,0 +1,35 @@
+package chat
+
+import (
+	"go_chat_client/connection"
+	"go_chat_client/protocol"
+
+	"github.com/cockroachdb/errors"
+)
+
+// ErrNicknameRejected is returned by Handler.LoginAndWaitForToken if server rejects nickname, e.g. because it's taken.
+var ErrNicknameRejected = errors.New("Nickname is rejected by server")
+
+// ErrAuthFailed is returned by Handler.LoginAndWaitForToken if server requires password and it's missing or wrong.
+var ErrAuthFailed = errors.New("Password is missing or wrong")
+
+// Authenticator represents method of logging in to server. Server responds to login request with login response,
+// containing access token if login was successful.
+type Authenticator interface {
+	// Login sends login request to server using connection <conn>.
+	Login(conn connection.Transport) error
+}
+
+// nicknameAuthenticator logs in with nickname from config and password, if it's set. Used by default.
+type nicknameAuthenticator struct {
+	h *Handler
+}
+
+// Login sends login request with nickname and password to server using connection <conn>. Used to implement
+// Authenticator interface.
+func (a nicknameAuthenticator) Login(conn connection.Transport) error {
+	err := conn.WriteJSON(protocol.LoginReq{
+		Type: protocol.TypeLoginReq, Nickname: a.h.cfg.Nickname, Password: a.h.Password,
+	})
+	return errors.Wrap(err, "Send login request")
+}
@@ -0,0 +1,58 @@
+package chat
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultAutoreplyCooldown is the minimum interval between autoreplies to the same trigger, used if it's not set in
+// config.
+const defaultAutoreplyCooldown = time.Minute
+
+// autoreplier represents set of trigger to response pairs used to reply to incoming messages automatically. Each
+// trigger fires at most once per <cooldown> to avoid reply loops.
+type autoreplier struct {
+	mu       sync.Mutex
+	replies  map[string]string
+	cooldown time.Duration
+	lastSent map[string]time.Time
+	now      func() time.Time
+}
+
+// newAutoreplier returns new autoreplier with <replies> as trigger to response pairs and <cooldown> between replies
+// to the same trigger, using <now> to get current time. Triggers are matched ignoring case.
+func newAutoreplier(replies map[string]string, cooldown time.Duration, now func() time.Time) *autoreplier {
+	lowered := make(map[string]string, len(replies))
+	for trigger, response := range replies {
+		if trigger = strings.ToLower(strings.TrimSpace(trigger)); trigger != "" {
+			lowered[trigger] = response
+		}
+	}
+	return &autoreplier{replies: lowered, cooldown: cooldown, lastSent: map[string]time.Time{}, now: now}
+}
+
+// reply returns response to message <msg> and true if <msg> contains any trigger which is not on cooldown. If several
+// triggers match, the longest one is used.
+func (a *autoreplier) reply(msg string) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	msg = strings.ToLower(msg)
+	var matched string
+	for trigger := range a.replies {
+		if strings.Contains(msg, trigger) && len(trigger) > len(matched) {
+			matched = trigger
+		}
+	}
+	if matched == "" {
+		return "", false
+	}
+
+	now := a.now()
+	if last, ok := a.lastSent[matched]; ok && now.Sub(last) < a.cooldown {
+		return "", false
+	}
+	a.lastSent[matched] = now
+	return a.replies[matched], true
+}
@@ -0,0 +1,53 @@
+package chat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAutoreplyTriggers(t *testing.T) {
+	replies := map[string]string{"Ping": "pong", " hello ": "hi", "hello there": "general Kenobi", "": "empty"}
+	tests := []struct {
+		msg    string
+		want   string
+		wantOk bool
+	}{
+		{"ping", "pong", true},
+		{"PING me", "pong", true},
+		{"well, hello", "hi", true},
+		{"Hello there!", "general Kenobi", true},
+		{"nothing here", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			a := newAutoreplier(replies, time.Minute, time.Now)
+			if got, ok := a.reply(tt.msg); got != tt.want || ok != tt.wantOk {
+				t.Errorf("reply(%q) = %q, %v, want %q, %v", tt.msg, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestAutoreplyCooldown(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	a := newAutoreplier(map[string]string{"ping": "pong", "hello": "hi"}, time.Minute, func() time.Time { return now })
+	steps := []struct {
+		after  time.Duration
+		msg    string
+		wantOk bool
+	}{
+		{0, "ping", true},
+		{time.Second, "ping", false},
+		{0, "hello", true},
+		{time.Minute - 2*time.Second, "ping", false},
+		{time.Second, "ping", true},
+		{time.Second, "ping", false},
+	}
+	for i, step := range steps {
+		now = now.Add(step.after)
+		if _, ok := a.reply(step.msg); ok != step.wantOk {
+			t.Errorf("Step %v: reply to %q is sent %v, want %v", i, step.msg, ok, step.wantOk)
+		}
+	}
+}
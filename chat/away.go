@@ -0,0 +1,196 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go_chat_client/connection"
+	"go_chat_client/protocol"
+	"go_chat_client/util/clock"
+
+	"github.com/cockroachdb/errors"
+)
+
+// awayReq represents request to server to set or clear away status of user.
+type awayReq struct {
+	Type   float64 `json:"type"`
+	Token  string  `json:"token"`
+	Away   bool    `json:"away"`
+	Reason string  `json:"reason"`
+}
+
+// autoAwayReason is the reason of away status set by Handler.AutoAway.
+const autoAwayReason = "idle"
+
+// autoAwayCheckInterval is the interval between checks of user inactivity by Handler.AutoAway.
+const autoAwayCheckInterval = time.Second
+
+// away represents away status of user.
+type away struct {
+	mu     sync.Mutex
+	active bool
+	sticky bool // Set by /away, so status is kept until /back instead of being cleared by input
+	auto   bool // Set by Handler.AutoAway, so status is cleared on the next key press
+	timer  clock.Timer
+}
+
+// setAway sets away status with optional duration and reason from <args>, e.g. "10m lunch". Status is cleared after
+// the duration, if it's specified, or when user sends any input.
+func (h *Handler) setAway(args string) error {
+	var d time.Duration
+	reason := args
+	first, rest, _ := strings.Cut(args, " ")
+	if parsed, err := time.ParseDuration(first); err == nil {
+		if parsed <= 0 {
+			return errUsage
+		}
+		d, reason = parsed, strings.TrimSpace(rest)
+	}
+	return h.goAway(d, reason, false)
+}
+
+// setAwayUntilBack sets away status with optional reason <args>, e.g. "lunch". Status is kept until /back command.
+func (h *Handler) setAwayUntilBack(args string) error {
+	return h.goAway(0, args, true)
+}
+
+// goAway sets away status with <reason>. Status is cleared after <d>, if it's not 0. If <sticky> is true, it's kept
+// until /back command, otherwise it's cleared when user sends any input.
+func (h *Handler) goAway(d time.Duration, reason string, sticky bool) error {
+	h.away.mu.Lock()
+	defer h.away.mu.Unlock()
+
+	req := awayReq{Type: protocol.TypeAwayReq, Token: h.token.get(), Away: true, Reason: reason}
+	if err := h.conn.WriteJSON(req); err != nil {
+		return errors.Wrap(err, "Send away request")
+	}
+	h.away.active = true
+	h.away.sticky = sticky
+	h.away.auto = false
+	if h.away.timer != nil {
+		h.away.timer.Stop()
+		h.away.timer = nil
+	}
+	if d != 0 {
+		h.away.timer = h.Clock.AfterFunc(d, h.clearAway)
+	}
+	h.showAway(true, reason)
+	switch {
+	case d != 0:
+		h.log.Infof("You are away until %v", h.now().Add(d).Format("15:04:05"))
+	case sticky:
+		h.log.Info("You are away until /back")
+	default:
+		h.log.Info("You are away until you send anything")
+	}
+	return nil
+}
+
+// comeBack clears away status. Used as /back command.
+func (h *Handler) comeBack(string) error {
+	h.away.mu.Lock()
+	active := h.away.active
+	h.away.mu.Unlock()
+	if !active {
+		h.log.Info("You are not away")
+		return nil
+	}
+	h.clearAway()
+	return nil
+}
+
+// clearAwayOnInput clears away status, unless it's set by /away command.
+func (h *Handler) clearAwayOnInput() {
+	h.away.mu.Lock()
+	sticky := h.away.sticky
+	h.away.mu.Unlock()
+	if !sticky {
+		h.clearAway()
+	}
+}
+
+// clearAway clears away status, if it's set.
+func (h *Handler) clearAway() {
+	h.away.mu.Lock()
+	defer h.away.mu.Unlock()
+
+	if !h.away.active {
+		return
+	}
+	h.away.active = false
+	h.away.sticky = false
+	h.away.auto = false
+	if h.away.timer != nil {
+		h.away.timer.Stop()
+		h.away.timer = nil
+	}
+	if err := h.conn.WriteJSON(awayReq{Type: protocol.TypeAwayReq, Token: h.token.get(), Away: false}); err != nil {
+		h.log.WithError(err).Error("Send away request")
+	}
+	h.showAway(false, "")
+	h.log.Info("You are back")
+}
+
+// showAway shows whether user is <away> with <reason> in status bar of chat UI, if it's initialized.
+func (h *Handler) showAway(away bool, reason string) {
+	if h.ChatUI() != nil {
+		h.ChatUI().SetAway(away, reason)
+	}
+}
+
+// AutoAway sets away status once user doesn't press any key in input field for config.Config.AutoAway seconds and
+// clears it on the next key press. Away status set by commands is not changed. It does nothing if
+// config.Config.AutoAway is 0. It blocks current goroutine until <ctx> is cancelled.
+func (h *Handler) AutoAway(ctx context.Context) {
+	if h.cfg.AutoAway <= 0 {
+		return
+	}
+	idle := time.Second * time.Duration(h.cfg.AutoAway)
+	activeAt, awayAt := h.now(), time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.Clock.After(autoAwayCheckInterval):
+		}
+		if keyAt := h.ChatUI().LastKeyAt(); keyAt.After(activeAt) {
+			activeAt = keyAt
+		}
+		if h.connState() != connection.StateConnected {
+			continue
+		}
+		h.away.mu.Lock()
+		active, auto := h.away.active, h.away.auto
+		h.away.mu.Unlock()
+		switch {
+		case auto && activeAt.After(awayAt):
+			h.clearAway()
+		case !active && h.now().Sub(activeAt) >= idle:
+			if h.goAutoAway(idle) {
+				awayAt = h.now()
+			}
+		}
+	}
+}
+
+// goAutoAway sets away status after <idle> time of inactivity and returns true, unless user is away already.
+func (h *Handler) goAutoAway(idle time.Duration) bool {
+	h.away.mu.Lock()
+	defer h.away.mu.Unlock()
+
+	if h.away.active {
+		return false
+	}
+	req := awayReq{Type: protocol.TypeAwayReq, Token: h.token.get(), Away: true, Reason: autoAwayReason}
+	if err := h.conn.WriteJSON(req); err != nil {
+		h.log.WithError(err).Error("Send away request")
+		return false
+	}
+	h.away.active = true
+	h.away.auto = true
+	h.showAway(true, autoAwayReason)
+	h.log.Infof("You are away after %v of inactivity until you press any key", idle)
+	return true
+}
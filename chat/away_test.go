@@ -0,0 +1,47 @@
+package chat
+
+import (
+	"testing"
+	"time"
+
+	"go_chat_client/config"
+	"go_chat_client/protocol"
+)
+
+func TestAwayExpires(t *testing.T) {
+	h, transport, fake := newTestHandler(t, &config.Config{Nickname: "alice"})
+
+	if err := h.setAway("10m lunch"); err != nil {
+		t.Fatal(err)
+	}
+	if req := transport.nextOfType(t, protocol.TypeAwayReq); req["away"] != true || req["reason"] != "lunch" {
+		t.Errorf("Away request is %v, want away with reason lunch", req)
+	}
+
+	fake.Advance(time.Minute * 10)
+	if req := transport.nextOfType(t, protocol.TypeAwayReq); req["away"] != false {
+		t.Errorf("Away request after duration is %v, want back", req)
+	}
+	if h.away.active {
+		t.Error("User is still away after duration")
+	}
+}
+
+func TestBackStopsAwayTimer(t *testing.T) {
+	h, transport, fake := newTestHandler(t, &config.Config{Nickname: "alice"})
+
+	if err := h.setAway("10m"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.comeBack(""); err != nil {
+		t.Fatal(err)
+	}
+	transport.nextOfType(t, protocol.TypeAwayReq)
+	transport.nextOfType(t, protocol.TypeAwayReq)
+
+	if waiters := fake.Waiters(); waiters != 0 {
+		t.Errorf("%v timers are waiting after /back, want 0", waiters)
+	}
+	fake.Advance(time.Minute * 10)
+	transport.assertNoRequest(t, protocol.TypeAwayReq)
+}
@@ -0,0 +1,63 @@
+package chat
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"go_chat_client/ui"
+
+	"github.com/samber/lo"
+)
+
+// setNicknameColor sets color of nickname to color named in <args>, e.g. "alice hi_blue", or removes it if color is
+// ui.DefaultNicknameColor. Color is saved to config.
+func (h *Handler) setNicknameColor(args string) error {
+	nickname, colorName, _ := strings.Cut(args, " ")
+	colorName = strings.ToLower(strings.TrimSpace(colorName))
+	if nickname == "" || colorName == "" {
+		return errUsage
+	}
+	if err := h.ChatUI().SetNicknameColor(nickname, colorName); err != nil {
+		h.log.WithError(err).Warn("Type /colors to see available colors")
+		return nil
+	}
+	if colorName == ui.DefaultNicknameColor {
+		delete(h.cfg.ColorOverrides, nickname)
+		h.log.Infof("Color of %v is reset", nickname)
+	} else {
+		if h.cfg.ColorOverrides == nil {
+			h.cfg.ColorOverrides = map[string]string{}
+		}
+		h.cfg.ColorOverrides[nickname] = colorName
+		h.log.Infof("Color of %v is set to %v", nickname, colorName)
+	}
+	h.runConfigChangeListeners()
+	return nil
+}
+
+// showColors prints names of available colors and colors set for nicknames with /color command to chat box.
+func (h *Handler) showColors(args string) error {
+	lines := []string{"Available colors: " + strings.Join(ui.ColorNames(), ", ")}
+	nicknames := lo.Keys(h.cfg.ColorOverrides)
+	slices.Sort(nicknames)
+	for _, nickname := range nicknames {
+		lines = append(lines, fmt.Sprintf("%v - %v", nickname, h.cfg.ColorOverrides[nickname]))
+	}
+	if len(nicknames) == 0 {
+		lines = append(lines, "No nickname colors are set, use /color to set them")
+	}
+	return h.printSystemLines(lines)
+}
+
+// AddOnConfigChangeListener registers function <l> to be run when config is changed by command, e.g. to save it.
+func (h *Handler) AddOnConfigChangeListener(l func()) {
+	h.onChange = append(h.onChange, l)
+}
+
+// runConfigChangeListeners runs functions registered with AddOnConfigChangeListener.
+func (h *Handler) runConfigChangeListeners() {
+	for _, listener := range h.onChange {
+		listener()
+	}
+}
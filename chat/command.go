@@ -0,0 +1,263 @@
+package chat
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"go_chat_client/connection"
+	"go_chat_client/util/clipboard"
+
+	"github.com/cockroachdb/errors"
+)
+
+// commandEscape is the prefix of message starting with "/" which is not a command, e.g. "//path" is sent as "/path".
+const commandEscape = "//"
+
+// errUsage is returned by command if it's arguments are invalid.
+var errUsage = errors.New("Invalid command arguments")
+
+// command represents chat command typed in input field, e.g. "/msg alice hi".
+type command struct {
+	name        string
+	args        string
+	description string
+	run         func(args string) error
+}
+
+// commands returns list of all chat commands.
+func (h *Handler) commands() []command {
+	return []command{
+		{name: "help", description: "Show commands", run: h.showHelp},
+		{name: "me", args: "<text>", description: "Send action message, e.g. /me waves", run: h.sendAction},
+		{name: "msg", args: "<nickname> <text>", description: "Send private message", run: h.sendPrivateMessage},
+		{name: "join", args: "<room>", description: "Join room and switch to it", run: h.joinRoom},
+		{name: "leave", args: "<room>", description: "Leave room", run: h.leaveRoom},
+		{name: "room", args: "[room]", description: "Switch to joined room or list joined rooms", run: h.selectRoom},
+		{name: "edit", args: "<id> <text>", description: "Replace text of your message", run: h.editMessage},
+		{name: "delete", args: "<id>", description: "Delete your message", run: h.deleteMessage},
+		{name: "report", args: "<id> <reason>", description: "Report message to moderators", run: h.reportMessage},
+		{name: "keys", description: "Show keybindings", run: h.showKeys},
+		{name: "clear", description: "Clear chat box", run: h.clearChatBox},
+		{name: "copyonline", description: "Copy list of online users to clipboard", run: h.copyOnlineUsers},
+		{name: "onlinemode", args: "names|detailed", description: "Show online users details", run: h.setOnlineMode},
+		{name: "color", args: "<nickname> <color>|default", description: "Set color of nickname", run: h.setNicknameColor},
+		{name: "colors", description: "Show available colors and colors set for nicknames", run: h.showColors},
+		{name: "mute", args: "<nickname>", description: "Hide messages of user", run: h.muteUser},
+		{name: "unmute", args: "<nickname>", description: "Show messages of muted user again", run: h.unmuteUser},
+		{name: "muted", description: "Show muted users", run: h.showMuted},
+		{name: "afk", args: "[duration] [reason]", description: "Set away status until you send anything", run: h.setAway},
+		{name: "away", args: "[message]", description: "Set away status until /back", run: h.setAwayUntilBack},
+		{name: "back", description: "Clear away status", run: h.comeBack},
+		{name: "mutenotif", args: "<duration>", description: "Mute notifications, e.g. for 30m", run: h.muteNotifications},
+		{name: "reconnect", description: "Reconnect to server now, e.g. after network is back", run: h.reconnect},
+		{name: "netdiag", description: "Show network diagnostics", run: h.showNetDiag},
+		{name: "whoami", description: "Show your nickname, server and connection state", run: h.showWhoami},
+		{name: "status", description: "Show local state, queued and unread messages", run: h.showStatus},
+	}
+}
+
+// HandleInput runs command if <input> starts with "/" and posts it as a message otherwise, with commandEscape replaced
+// by "/". Macro is posted as a message with it's expansion, if there is no command with the same name. Any input except
+// away commands clears away status set by /afk command.
+func (h *Handler) HandleInput(input string) {
+	name, args, ok := parseCommand(input)
+	if !slices.Contains([]string{"afk", "away", "back"}, name) {
+		h.clearAwayOnInput()
+	}
+	if !ok {
+		h.PostMessage(unescapeCommand(input))
+		return
+	}
+	for _, cmd := range h.commands() {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(args)
+		if errors.Is(err, errUsage) {
+			h.log.Warnf("Usage: /%v %v", cmd.name, cmd.args)
+		} else if err != nil {
+			h.log.WithError(err).Error()
+		}
+		return
+	}
+	if text, ok := h.expandMacro(name, args); ok {
+		h.PostMessage(text)
+		return
+	}
+	h.log.Warnf("Unknown command /%v. To send it as a message, start it with %v", name, commandEscape)
+}
+
+// welcomeLines is the message shown on the first run.
+var welcomeLines = []string{
+	"Welcome! Type a message and press Enter to send it.",
+	"Press Tab to complete nicknames, F2 to show online users and Ctrl+C to exit.",
+	"Type /help to see all commands and /keys to see all keybindings.",
+}
+
+// PrintWelcome prints welcome message explaining basic usage to chat box.
+func (h *Handler) PrintWelcome() {
+	for _, line := range welcomeLines {
+		if err := h.ChatUI().PrintToChatBox("", line, true, false); err != nil {
+			h.log.WithError(err).Error()
+			return
+		}
+	}
+}
+
+// showHelp prints list of commands and macros to chat box.
+func (h *Handler) showHelp(args string) error {
+	for _, cmd := range h.commands() {
+		line := strings.TrimSpace(fmt.Sprintf("/%v %v", cmd.name, cmd.args)) + " - " + cmd.description
+		if err := h.ChatUI().PrintToChatBox("", line, true, false); err != nil {
+			return err
+		}
+	}
+	for _, name := range h.macroNames() {
+		line := fmt.Sprintf("/%v [text] - Send %v", name, h.macros[name])
+		if err := h.ChatUI().PrintToChatBox("", line, true, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// showKeys prints keybindings to chat box.
+func (h *Handler) showKeys(args string) error {
+	for _, line := range h.ChatUI().KeybindingsHelp() {
+		if err := h.ChatUI().PrintToChatBox("", line, true, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clearChatBox clears chat box. History stored on server is not affected.
+func (h *Handler) clearChatBox(args string) error {
+	h.ChatUI().ClearChatBox()
+	return nil
+}
+
+// sendAction posts action message <args>, printed as "* nickname <args>".
+func (h *Handler) sendAction(args string) error {
+	text := strings.TrimSpace(args)
+	if text == "" {
+		return errUsage
+	}
+	h.postAction(text)
+	return nil
+}
+
+// copyOnlineUsers copies the last received list of online users to clipboard.
+func (h *Handler) copyOnlineUsers(args string) error {
+	users := h.ChatUI().OnlineUsers()
+	if users == nil {
+		h.log.Warn("List of online users is not received yet, open online users window first")
+		return nil
+	}
+	if err := clipboard.Write(formatOnlineUsers(users)); err != nil {
+		return err
+	}
+	h.log.Infof("Copied %v online users to clipboard", len(users))
+	return nil
+}
+
+// formatOnlineUsers returns sorted <users> separated by new line.
+func formatOnlineUsers(users []string) string {
+	users = slices.Clone(users)
+	slices.Sort(users)
+	return strings.Join(users, "\n")
+}
+
+// setOnlineMode switches online users box between showing only nicknames and detailed information, depending on
+// <args>.
+func (h *Handler) setOnlineMode(args string) error {
+	switch strings.ToLower(args) {
+	case "names":
+		h.ChatUI().SetDetailedOnlineBox(false)
+	case "detailed":
+		h.ChatUI().SetDetailedOnlineBox(true)
+	default:
+		return errUsage
+	}
+	return nil
+}
+
+// muteNotifications mutes notifications for duration in <args>, e.g. "30m" or "1h". Zero duration unmutes them.
+func (h *Handler) muteNotifications(args string) error {
+	d, err := time.ParseDuration(args)
+	if err != nil || d < 0 {
+		return errUsage
+	}
+	h.ChatUI().MuteNotifications(d)
+	if d == 0 {
+		h.log.Info("Notifications are unmuted")
+	} else {
+		h.log.Infof("Notifications are muted until %v", h.now().Add(d).Format("15:04:05"))
+	}
+	return nil
+}
+
+// parseCommand returns name and arguments of command in <input> and true if <input> is a command. Input starting with
+// commandEscape is not a command.
+func parseCommand(input string) (string, string, bool) {
+	if !strings.HasPrefix(input, "/") || strings.HasPrefix(input, commandEscape) {
+		return "", "", false
+	}
+	// Arguments can start on the next line of multi-line input
+	name, args := input[1:], ""
+	if i := strings.IndexFunc(name, unicode.IsSpace); i != -1 {
+		name, args = name[:i], name[i+1:]
+	}
+	return strings.ToLower(name), strings.TrimSpace(args), true
+}
+
+// unescapeCommand returns <input> with commandEscape at the start replaced by "/".
+func unescapeCommand(input string) string {
+	if strings.HasPrefix(input, commandEscape) {
+		return input[1:]
+	}
+	return input
+}
+
+// reconnect reconnects to server immediately: resumes reconnecting if it was given up after exceeding connection
+// attempts limit, skips waiting before the next attempt or drops current connection to establish a new one.
+func (h *Handler) reconnect(args string) error {
+	if notice, kicked := h.kick.get(); kicked {
+		h.log.Warn(kickText(notice))
+		return nil
+	}
+	select {
+	case h.retryCh <- struct{}{}:
+		return nil
+	default:
+	}
+	r, ok := h.conn.(connection.Reconnector)
+	if !ok {
+		h.log.Warn("Connection doesn't support reconnecting")
+		return nil
+	}
+	if !r.Reconnect() {
+		h.log.Warn("Connection attempt is already in progress")
+	}
+	return nil
+}
+
+// showNetDiag prints connection diagnostics to chat box.
+func (h *Handler) showNetDiag(args string) error {
+	return h.printSystemLines(h.netDiag().lines())
+}
+
+// netDiag returns snapshot of connection diagnostics. If transport doesn't measure them, they are zero.
+func (h *Handler) netDiag() netDiag {
+	conn := h.diagnostics()
+	return netDiag{
+		state:          h.connState(),
+		rtt:            conn.RTT(),
+		reconnects:     conn.Reconnects(),
+		lastDisconnect: conn.LastDisconnectErr(),
+		pending:        h.pending.len(),
+	}
+}
@@ -0,0 +1,179 @@
+package chat
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"go_chat_client/config"
+	"go_chat_client/protocol"
+)
+
+func TestWelcomeMentionsCommands(t *testing.T) {
+	h, _, _ := newTestHandler(t, &config.Config{Nickname: "alice"})
+	mentioned := regexp.MustCompile(`/(\w+)`).FindAllStringSubmatch(strings.Join(welcomeLines, "\n"), -1)
+	if len(mentioned) == 0 {
+		t.Fatal("Welcome message doesn't mention any command")
+	}
+	for _, match := range mentioned {
+		found := false
+		for _, cmd := range h.commands() {
+			found = found || cmd.name == match[1]
+		}
+		if !found {
+			t.Errorf("Welcome message mentions unknown command /%v", match[1])
+		}
+	}
+}
+
+func TestFormatOnlineUsers(t *testing.T) {
+	tests := []struct {
+		name  string
+		users []string
+		want  string
+	}{
+		{"empty", []string{}, ""},
+		{"single", []string{"alice"}, "alice"},
+		{"sorted", []string{"carol", "alice", "bob"}, "alice\nbob\ncarol"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := slices.Clone(tt.users)
+			if got := formatOnlineUsers(users); got != tt.want {
+				t.Errorf("formatOnlineUsers(%q) = %q, want %q", tt.users, got, tt.want)
+			}
+			if !slices.Equal(users, tt.users) {
+				t.Errorf("formatOnlineUsers changed list of users to %q", users)
+			}
+		})
+	}
+}
+
+func TestReportMessage(t *testing.T) {
+	tests := []struct {
+		args    string
+		wantID  float64
+		wantErr bool
+	}{
+		{args: "#12 spam  ", wantID: 12},
+		{args: "12 rude words", wantID: 12},
+		{args: "12", wantErr: true},
+		{args: "#0 spam", wantErr: true},
+		{args: "abc spam", wantErr: true},
+		{args: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			h, transport, _ := newTestHandler(t, &config.Config{Nickname: "alice"})
+			err := h.reportMessage(tt.args)
+			if tt.wantErr {
+				if !errors.Is(err, errUsage) {
+					t.Errorf("reportMessage returned %v, want %v", err, errUsage)
+				}
+				transport.assertNoRequest(t, protocol.TypeReportReq)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := transport.nextOfType(t, protocol.TypeReportReq)
+			_, wantReason, _ := strings.Cut(tt.args, " ")
+			if req["msgId"] != tt.wantID || req["reason"] != strings.TrimSpace(wantReason) || req["token"] != "token" {
+				t.Errorf("Report request is %v, want message %v with reason %q", req, tt.wantID, wantReason)
+			}
+		})
+	}
+}
+
+func TestReportResponse(t *testing.T) {
+	tests := []struct {
+		status float64
+		want   string
+	}{
+		{protocol.StatusOk, "Message #12 is reported to moderators"},
+		{protocol.StatusMessageNotFound, "Report failed, message #12 not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			h, transport, _ := newTestHandler(t, &config.Config{Nickname: "alice"})
+			out := &bytes.Buffer{}
+			h.log.SetOutput(out)
+			h.HandleReportResponse()
+
+			transport.deliver(reportResp{Type: protocol.TypeReportResp, Status: tt.status, MsgID: 12})
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("Log is %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantName string
+		wantArgs string
+		wantOk   bool
+	}{
+		{"/MSG bob  hi ", "msg", "bob  hi", true},
+		{"/help", "help", "", true},
+		{"hello", "", "", false},
+		{"//path/to/file", "", "", false},
+		{"///", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			name, args, ok := parseCommand(tt.input)
+			if name != tt.wantName || args != tt.wantArgs || ok != tt.wantOk {
+				t.Errorf("parseCommand(%q) = %q, %q, %v, want %q, %q, %v", tt.input, name, args, ok, tt.wantName,
+					tt.wantArgs, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestHandleInputEscape(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"//path/to/file", "/path/to/file"},
+		{"///", "//"},
+		{"a //b", "a //b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			h, transport, _ := newTestHandler(t, &config.Config{Nickname: "alice"})
+			h.HandleInput(tt.input)
+			if req := transport.nextOfType(t, protocol.TypePostMessageReq); req["msg"] != tt.want {
+				t.Errorf("Posted message is %q, want %q", req["msg"], tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleInputUnknownCommand(t *testing.T) {
+	h, transport, _ := newTestHandler(t, &config.Config{Nickname: "alice"})
+	out := &bytes.Buffer{}
+	h.log.SetOutput(out)
+
+	h.HandleInput("/path/to/file")
+	transport.assertNoRequest(t, protocol.TypePostMessageReq)
+	if want := "start it with " + commandEscape; !strings.Contains(out.String(), want) {
+		t.Errorf("Log is %q, want hint %q", out, want)
+	}
+}
+
+func TestSetOnlineModeInvalid(t *testing.T) {
+	for _, args := range []string{"", "full", "names detailed"} {
+		t.Run(args, func(t *testing.T) {
+			h, _, _ := newTestHandler(t, &config.Config{Nickname: "alice"})
+			if err := h.setOnlineMode(args); !errors.Is(err, errUsage) {
+				t.Errorf("setOnlineMode(%q) returned %v, want %v", args, err, errUsage)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package chat
+
+import (
+	"fmt"
+	"sync"
+
+	"go_chat_client/protocol"
+)
+
+// seenLimit is the maximum amount of recently received messages remembered to skip their duplicates.
+const seenLimit = 500
+
+// cursor represents position of the newest chat message received from server, used to request only messages missed
+// while connection was lost. It also remembers recently received messages, so messages received both live and in
+// history are printed once.
+type cursor struct {
+	mu        sync.Mutex
+	id        int64
+	timestamp int64
+	seen      map[string]bool
+	order     []string
+}
+
+// add moves cursor to <msg> if it's newer than the current position and returns true if <msg> was not received yet.
+func (c *cursor) add(msg protocol.ChatMsgToClient) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := msgKey(msg)
+	if c.seen[key] {
+		return false
+	}
+	if c.seen == nil {
+		c.seen = map[string]bool{}
+	}
+	c.seen[key] = true
+	c.order = append(c.order, key)
+	if len(c.order) > seenLimit {
+		delete(c.seen, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.id = max(c.id, msg.ID)
+	c.timestamp = max(c.timestamp, msg.Timestamp)
+	return true
+}
+
+// position returns ID and unix milliseconds timestamp of the newest received message. Both are 0 if no messages were
+// received yet, and ID is 0 if server doesn't assign IDs to messages.
+func (c *cursor) position() (int64, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.id, c.timestamp
+}
+
+// msgKey returns key identifying <msg>: ID assigned by server or, if there is none, time, author and text of <msg>.
+func msgKey(msg protocol.ChatMsgToClient) string {
+	if msg.ID != 0 {
+		return fmt.Sprintf("#%v", msg.ID)
+	}
+	return fmt.Sprintf("%v %v %v %v", msg.Timestamp, msg.IsSystem, msg.Nickname, msg.Msg)
+}
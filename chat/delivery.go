@@ -0,0 +1,42 @@
+package chat
+
+import (
+	"sync"
+)
+
+// deliveries represents callers of Handler.PostMessageAndWait waiting for their messages to be confirmed by server, by
+// client-generated message IDs. It's safe for concurrent use.
+type deliveries struct {
+	mu      sync.Mutex
+	waiters map[int64]chan error
+}
+
+// watch returns channel receiving result of delivery of message with <id>, nil if it's confirmed by server.
+func (d *deliveries) watch(id int64) <-chan error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.waiters == nil {
+		d.waiters = map[int64]chan error{}
+	}
+	result := make(chan error, 1)
+	d.waiters[id] = result
+	return result
+}
+
+// unwatch stops waiting for delivery of message with <id>.
+func (d *deliveries) unwatch(id int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.waiters, id)
+}
+
+// resolve passes result of delivery <err> of message with <id> to caller waiting for it. Only the first result is
+// passed, it does nothing if nobody waits for it.
+func (d *deliveries) resolve(id int64, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if result, ok := d.waiters[id]; ok {
+		result <- err
+		delete(d.waiters, id)
+	}
+}
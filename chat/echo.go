@@ -0,0 +1,59 @@
+package chat
+
+import (
+	"slices"
+	"sync"
+)
+
+// echoLimit is the maximum amount of own messages printed locally and waiting for server to broadcast them back.
+const echoLimit = 100
+
+// echo represents own message printed to chat box as soon as it's sent, with client-generated <id>.
+type echo struct {
+	id     int64
+	msg    string
+	action bool
+}
+
+// echoes represents own messages printed to chat box as soon as they're sent, so when server broadcasts them back,
+// they're not printed again.
+type echoes struct {
+	mu   sync.Mutex
+	msgs []echo
+}
+
+// add adds message <msg> with <id>, which is action message if <action> is true, to echoes. The oldest echo is
+// forgotten if there are more than echoLimit of them, e.g. if server doesn't broadcast own messages back.
+func (e *echoes) add(id int64, msg string, action bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.msgs = append(e.msgs, echo{id: id, msg: msg, action: action})
+	if len(e.msgs) > echoLimit {
+		e.msgs = e.msgs[1:]
+	}
+}
+
+// remove removes echo with <id>, e.g. because message was not delivered and so will not be broadcast back.
+func (e *echoes) remove(id int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.msgs = slices.DeleteFunc(e.msgs, func(echo echo) bool {
+		return echo.id == id
+	})
+}
+
+// take removes the oldest echo of message <msg>, which is action message if <action> is true, and returns it's ID and
+// true if it was found.
+func (e *echoes) take(msg string, action bool) (int64, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	i := slices.IndexFunc(e.msgs, func(echo echo) bool {
+		return echo.msg == msg && echo.action == action
+	})
+	if i < 0 {
+		return 0, false
+	}
+	id := e.msgs[i].id
+	e.msgs = slices.Delete(e.msgs, i, i+1)
+	return id, true
+}
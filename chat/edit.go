@@ -0,0 +1,184 @@
+package chat
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"go_chat_client/protocol"
+
+	"github.com/cockroachdb/errors"
+	"github.com/mitchellh/mapstructure"
+)
+
+// editReq represents request to server to replace text of message with <ID>.
+type editReq struct {
+	Type  float64 `json:"type"`
+	Token string  `json:"token"`
+	ID    int64   `json:"id"`
+	Msg   string  `json:"msg"`
+}
+
+// deleteReq represents request to server to delete message with <ID>.
+type deleteReq struct {
+	Type  float64 `json:"type"`
+	Token string  `json:"token"`
+	ID    int64   `json:"id"`
+}
+
+// msgChangeResp represents response from server with status of edit or delete request of message with <ID>.
+type msgChangeResp struct {
+	Type   float64 `json:"type"`
+	Status float64 `json:"status"`
+	ID     int64   `json:"id"`
+}
+
+// msgChanged represents notice from server that message with <ID> is edited to <Msg> or deleted by it's author.
+type msgChanged struct {
+	Type float64 `json:"type"`
+	ID   int64   `json:"id"`
+	Msg  string  `json:"msg"`
+}
+
+// edits represents new texts of messages waiting for server to confirm edit, by message IDs.
+type edits struct {
+	mu    sync.Mutex
+	texts map[int64]string
+}
+
+// push stores new <text> of message with <id>.
+func (e *edits) push(id int64, text string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.texts == nil {
+		e.texts = map[int64]string{}
+	}
+	e.texts[id] = text
+}
+
+// pop removes and returns new text of message with <id>. It returns false if there is no edit of message pending.
+func (e *edits) pop(id int64) (string, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	text, ok := e.texts[id]
+	delete(e.texts, id)
+	return text, ok
+}
+
+// editMessage sends request to replace text of message. <args> should contain message ID optionally prefixed with "#"
+// and new text, e.g. "#42 fixed typo".
+func (h *Handler) editMessage(args string) error {
+	id, text, _ := strings.Cut(strings.TrimSpace(args), " ")
+	text = strings.TrimSpace(text)
+	msgID, err := parseMsgID(id)
+	if err != nil || text == "" {
+		return errUsage
+	}
+	if err := h.checkLength(text); err != nil {
+		return err
+	}
+	h.edits.push(msgID, text)
+	err = h.conn.WriteJSON(editReq{Type: protocol.TypeEditReq, Token: h.token.get(), ID: msgID, Msg: text})
+	return errors.Wrap(err, "Send edit request")
+}
+
+// deleteMessage sends request to delete message. <args> should contain message ID optionally prefixed with "#".
+func (h *Handler) deleteMessage(args string) error {
+	msgID, err := parseMsgID(strings.TrimSpace(args))
+	if err != nil {
+		return errUsage
+	}
+	err = h.conn.WriteJSON(deleteReq{Type: protocol.TypeDeleteReq, Token: h.token.get(), ID: msgID})
+	return errors.Wrap(err, "Send delete request")
+}
+
+// parseMsgID returns message ID from <id> optionally prefixed with "#", e.g. "#42".
+func parseMsgID(id string) (int64, error) {
+	msgID, err := strconv.ParseInt(strings.TrimPrefix(id, "#"), 10, 64)
+	if err == nil && msgID <= 0 {
+		err = errors.Newf("Message ID %v is not positive", msgID)
+	}
+	return msgID, err
+}
+
+// HandleMessageChanges performs actions to do when server responds with status of edit or delete request, or notifies
+// that message is edited or deleted by it's author: updates or removes the message in chat box.
+func (h *Handler) HandleMessageChanges() {
+	h.conn.AddOnTypeListener(protocol.TypeEditResp, func(resp map[string]any) {
+		var r msgChangeResp
+		if err := mapstructure.Decode(resp, &r); err != nil {
+			h.log.WithError(err).Error("Decode edit status response")
+			return
+		}
+		text, ok := h.edits.pop(r.ID)
+		if err := msgChangeErr("Edit", r); err != nil {
+			h.log.WithError(err).Error()
+			return
+		}
+		if ok && !h.applyEdit(r.ID, text) {
+			h.log.Infof("Message #%v is edited", r.ID)
+		}
+	})
+	h.conn.AddOnTypeListener(protocol.TypeDeleteResp, func(resp map[string]any) {
+		var r msgChangeResp
+		if err := mapstructure.Decode(resp, &r); err != nil {
+			h.log.WithError(err).Error("Decode delete status response")
+			return
+		}
+		if err := msgChangeErr("Delete", r); err != nil {
+			h.log.WithError(err).Error()
+			return
+		}
+		if !h.applyDelete(r.ID) {
+			h.log.Infof("Message #%v is deleted", r.ID)
+		}
+	})
+	h.conn.AddOnTypeListener(protocol.TypeMessageEdited, func(resp map[string]any) {
+		var r msgChanged
+		if err := mapstructure.Decode(resp, &r); err != nil {
+			h.log.WithError(err).Error("Decode message edit notice")
+			return
+		}
+		if !h.applyEdit(r.ID, r.Msg) {
+			h.log.Debugf("Message #%v is edited, but it's not in chat window", r.ID)
+		}
+	})
+	h.conn.AddOnTypeListener(protocol.TypeMessageDeleted, func(resp map[string]any) {
+		var r msgChanged
+		if err := mapstructure.Decode(resp, &r); err != nil {
+			h.log.WithError(err).Error("Decode message delete notice")
+			return
+		}
+		if !h.applyDelete(r.ID) {
+			h.log.Debugf("Message #%v is deleted, but it's not in chat window", r.ID)
+		}
+	})
+}
+
+// msgChangeErr returns error if <action> ("Edit" or "Delete") of message is rejected according to response <r>.
+func msgChangeErr(action string, r msgChangeResp) error {
+	switch r.Status {
+	case protocol.StatusOk:
+		return nil
+	case protocol.StatusMessageNotFound:
+		return errors.Newf("%v failed, message #%v not found", action, r.ID)
+	case protocol.StatusNotAllowed:
+		return errors.Newf("%v failed, message #%v is not yours", action, r.ID)
+	default:
+		return errors.Newf("%v failed, status: %v", action, r.Status)
+	}
+}
+
+// applyEdit replaces text of message with <id> with <text> in stored room messages and chat box. It returns false if
+// message is not in chat box.
+func (h *Handler) applyEdit(id int64, text string) bool {
+	h.rooms.edit(id, text)
+	return h.ChatUI() != nil && h.ChatUI().EditMessage(id, text)
+}
+
+// applyDelete removes message with <id> from stored room messages and chat box. It returns false if message is not in
+// chat box.
+func (h *Handler) applyDelete(id int64) bool {
+	h.rooms.remove(id)
+	return h.ChatUI() != nil && h.ChatUI().DeleteMessage(id)
+}
@@ -0,0 +1,20 @@
+package chat
+
+import "sync/atomic"
+
+// greeting represents configured login message, sent once user is logged in.
+type greeting struct {
+	sent atomic.Bool // Login message was sent at least once
+}
+
+// sendLoginMessage posts configured login message on the first login and, if config allows it, after connection is
+// restored. It's run as login listener, so access token is already received.
+func (h *Handler) sendLoginMessage() {
+	if h.cfg.LoginMessage == "" {
+		return
+	}
+	if h.greeting.sent.Swap(true) && !h.cfg.RepeatLoginMessage {
+		return
+	}
+	h.PostMessage(expandJoinMessage(h.cfg.LoginMessage, h.cfg.Nickname, h.cfg.ServerAddress))
+}
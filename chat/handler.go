@@ -1,11 +1,20 @@
 package chat
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"go_chat_client/config"
 	"go_chat_client/connection"
+	"go_chat_client/protocol"
 	"go_chat_client/ui"
+	"go_chat_client/util/clock"
 	stdinUtil "go_chat_client/util/stdin"
 
 	"github.com/cockroachdb/errors"
@@ -14,217 +23,942 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// loginReq represents login request to server.
-type loginReq struct {
-	Type     float64 `json:"type"`
-	Nickname string  `json:"nickname"`
+// onlineCount represents amount of online users pushed by server instead of full list.
+type onlineCount struct {
+	Type  float64 `json:"type"`
+	Count int     `json:"count"`
 }
 
-// loginResp represents login response from server.
-type loginResp struct {
-	Type   float64 `json:"type"`
-	Token  string  `json:"token"`
-	Status float64 `json:"status"`
+// privateMsgReq represents private message request to server.
+type privateMsgReq struct {
+	Type      float64 `json:"type"`
+	Token     string  `json:"token"`
+	Recipient string  `json:"recipient"`
+	Msg       string  `json:"msg"`
 }
 
-// postMsgReq respresents post message request to server.
-type postMsgReq struct {
-	Type  float64 `json:"type"`
-	Token string  `json:"token"`
-	Msg   string  `json:"msg"`
+// privateMsgResp represents private message response from server.
+type privateMsgResp struct {
+	Type      float64 `json:"type"`
+	Status    float64 `json:"status"`
+	Recipient string  `json:"recipient"`
 }
 
-// postMsgResp represents post message response from server.
-type postMsgResp struct {
-	Type   float64 `json:"type"`
-	Status float64 `json:"status"`
+// privateMsgToClient represents private message to print in client's chat box.
+type privateMsgToClient struct {
+	Type      float64 `json:"type"`
+	Nickname  string  `json:"nickname"`
+	Msg       string  `json:"msg"`
+	Timestamp int64   `json:"timestamp"`
 }
 
-// chatMsgToClient represents message to print in client's chat box.
-type chatMsgToClient struct {
+// typingReq represents signal to server that user is typing.
+type typingReq struct {
+	Type  float64 `json:"type"`
+	Token string  `json:"token"`
+}
+
+// typingToClient represents signal from server that another user is typing.
+type typingToClient struct {
 	Type     float64 `json:"type"`
 	Nickname string  `json:"nickname"`
-	Msg      string  `json:"msg"`
-	IsSystem bool    `json:"isSystem"`
 }
 
-// onlineUsersReq represents request for list of online users to send to server.
-type onlineUsersReq struct {
-	Type  float64 `json:"type"`
-	Token string  `json:"token"`
+// historyReq represents request for recent chat messages to send to server. If <SinceID> or <Since> unix milliseconds
+// timestamp is set, only messages newer than that are requested.
+type historyReq struct {
+	Type    float64 `json:"type"`
+	Token   string  `json:"token"`
+	Count   float64 `json:"count"`
+	SinceID int64   `json:"sinceId,omitempty"`
+	Since   int64   `json:"since,omitempty"`
 }
 
-// onlineUsers represent list of online users received from server.
-type onlineUsers struct {
-	Type   float64  `json:"type"`
-	Status float64  `json:"status"`
-	Users  []string `json:"users"`
+// history represents list of recent chat messages received from server, oldest first.
+type history struct {
+	Type     float64                    `json:"type"`
+	Status   float64                    `json:"status"`
+	Messages []protocol.ChatMsgToClient `json:"messages"`
 }
 
-// used to distinguish between types of various JSON requests and responses.
-const (
-	typeLoginReq float64 = iota + 1
-	typeLoginResp
-	typePostMessageReq
-	typePostMessageResp
-	typeChatMessageToClient
-	typeOnlineUsersReq
-	typeOnlineUsers
-)
+// reportReq represents request to server to report message with ID <MsgID> to moderators.
+type reportReq struct {
+	Type   float64 `json:"type"`
+	Token  string  `json:"token"`
+	MsgID  int64   `json:"msgId"`
+	Reason string  `json:"reason"`
+}
 
-// represents various statuses to receive in responses from server.
+// reportResp represents report response from server.
+type reportResp struct {
+	Type   float64 `json:"type"`
+	Status float64 `json:"status"`
+	MsgID  int64   `json:"msgId"`
+}
+
+// nicknameRejections maps login statuses, meaning that nickname is rejected by server, to their explanations.
+var nicknameRejections = map[float64]string{
+	protocol.StatusNameAlreadyTaken: "Name is already taken",
+	protocol.StatusNameIsEmpty:      "Name can't be empty",
+	protocol.StatusNameIsTooLong:    "Name is too long",
+}
+
+// joinMessageInterval is the minimum interval between two join messages, preventing spam on frequent reconnects.
+const joinMessageInterval = time.Minute
+
+// historyCount is the amount of recent chat messages to request on login.
+const historyCount = 50
+
+// represents message priorities. Messages without priority are treated as normal.
 const (
-	statusOk float64 = iota + 1
-	statusInvalidToken
-	statusNameAlreadyTaken
-	statusNameIsEmpty
-	statusNameIsTooLong
-	statusMessageIsEmpty
-	statusMessageIsTooLong
+	priorityNormal float64 = iota
+	priorityHigh
 )
 
 // Handler represents communication logic handler. It handles responses and sends requests.
 type Handler struct {
-	ChatUI  ui.Chat
-	log     *logrus.Logger
-	cfg     *config.Config
-	conn    *connection.Handler
-	tokenCh chan string
-	token   string
+	Password      string
+	Authenticator Authenticator
+	Prompter      *stdinUtil.Prompter
+	Clock         clock.Clock
+	log           *logrus.Logger
+	cfg           *config.Config
+	conn          connection.Transport
+	chatUI        atomic.Pointer[ui.Chat]
+	retryCh       chan struct{}
+	handshakeCh   chan handshakeResp
+	token         token
+	joinAt        time.Time
+	pending       pendingMsgs
+	deliveries    deliveries
+	onMessage     []func(protocol.ChatMsgToClient)
+	limiter       *rateLimiter
+	autoreplier   *autoreplier
+	macros        map[string]string
+	mutes         mutes
+	onLogin       loginListeners
+	greeting      greeting
+	onChange      []func()
+	transcript    *transcript
+	postAttempts  int
+	away          away
+	rooms         rooms
+	presence      presence
+	cursor        cursor
+	kick          kick
+	echoes        echoes
+	edits         edits
+	backlogMu     sync.Mutex
+	backlog       []protocol.ChatMsgToClient
 }
 
 // NewHandler returns new chat handler.
-func NewHandler(log *logrus.Logger, cfg *config.Config, conn *connection.Handler) Handler {
-	return Handler{log: log, cfg: cfg, conn: conn, tokenCh: make(chan string)}
+func NewHandler(log *logrus.Logger, cfg *config.Config, conn connection.Transport) *Handler {
+	h := &Handler{log: log, cfg: cfg, conn: conn, retryCh: make(chan struct{}),
+		handshakeCh: make(chan handshakeResp, 1)}
+	h.Authenticator = nicknameAuthenticator{h: h}
+	h.Prompter = stdinUtil.NewPrompter(log, os.Stdin)
+	h.Clock = clock.Real{}
+	messages := lo.Ternary(cfg.RateLimitMessages > 0, cfg.RateLimitMessages, defaultRateLimitMessages)
+	interval := lo.Ternary(cfg.RateLimitInterval > 0, time.Second*time.Duration(cfg.RateLimitInterval),
+		defaultRateLimitInterval)
+	h.limiter = newRateLimiter(messages, interval, h.now)
+	h.pending.afterFunc = h.afterFunc
+	h.postAttempts = lo.Ternary(cfg.PostAttempts > 0, cfg.PostAttempts, defaultPostAttempts)
+	cooldown := lo.Ternary(cfg.AutoreplyCooldown > 0, time.Second*time.Duration(cfg.AutoreplyCooldown),
+		defaultAutoreplyCooldown)
+	h.autoreplier = newAutoreplier(cfg.Autoreplies, cooldown, h.now)
+	h.macros = newMacros(cfg.Macros)
+	h.mutes.set(cfg.Muted)
+	h.warnMacroCollisions()
+	h.onLogin.add(h.sendLoginMessage)
+	return h
 }
 
-// HandleOnDisconnect performs actions to do when connection to server is lost.
-func (h *Handler) HandleOnDisconnect() {
+// SetChatUI sets chat UI to print messages to. Until it's set, messages are not printed. It's safe to call it while
+// listening for messages.
+func (h *Handler) SetChatUI(chatUI *ui.Chat) {
+	h.chatUI.Store(chatUI)
+}
+
+// ChatUI returns chat UI set with SetChatUI, or nil if it's not set yet.
+func (h *Handler) ChatUI() *ui.Chat {
+	return h.chatUI.Load()
+}
+
+// HandleOnDisconnect performs actions to do when connection to server is lost. It stops reconnecting when <ctx> is
+// cancelled. If connection attempts limit is exceeded, it waits for user to retry with /reconnect command. Once logged
+// in again, it requests messages newer than the last received one, which were missed while connection was lost, and
+// runs login listeners. If user was kicked (see HandleKicked), connection is not restored. If connection was dropped
+// with /reconnect command, it reconnects without waiting.
+func (h *Handler) HandleOnDisconnect(ctx context.Context) {
 	h.conn.AddOnDisconnectListener(func(err error) {
-		h.log.Error(errors.Wrap(err, "Lost connection to server"), " Retrying in 5 seconds.")
-		if !lo.IsEmpty(&h.ChatUI) {
-			h.ChatUI.OnlineUsersCh <- []string{}
+		if _, kicked := h.kick.get(); kicked {
+			h.log.WithError(err).Info("Connection closed by server after kick")
+			h.pending.stop()
+			h.setStatus(ui.StatusKicked)
+			return
 		}
-		time.Sleep(time.Second * 5)
-		h.conn.Connect()
+		isForced := errors.Is(err, connection.ErrReconnectRequested)
+		if isForced {
+			h.log.Info("Reconnecting to server")
+		} else {
+			h.log.WithError(err).Errorf("Lost connection to server, retrying in %v", h.retryDelay())
+		}
+		sinceID, since := h.cursor.position()
+		h.token.reset()
+		h.pending.stop()
+		h.presence.reset()
+		h.setStatus(ui.StatusDisconnected)
+		if h.ChatUI() != nil {
+			h.ChatUI().SetOnlineUsers([]ui.OnlineUser{})
+		}
+		if !isForced {
+			select {
+			case <-ctx.Done():
+				return
+			case <-h.Clock.After(h.retryDelay()):
+			case <-h.retryCh:
+			}
+		}
+		for {
+			h.setStatus(ui.StatusReconnecting)
+			err := h.conn.Connect(ctx)
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, connection.ErrAttemptsExceeded) {
+				h.log.WithError(err).Debug()
+				return
+			}
+			h.log.WithError(err).Error("Stop reconnecting, type /reconnect to try again")
+			h.setStatus(ui.StatusDisconnected)
+			select {
+			case <-ctx.Done():
+				return
+			case <-h.retryCh:
+			}
+		}
+		h.setStatus(ui.StatusLoggingIn)
 		if err := h.login(); err != nil {
-			h.log.Error(err)
+			h.log.WithError(err).Error()
 		}
 		go func() {
-			h.token = <-h.tokenCh
+			if !h.token.wait(ctx) {
+				if err := h.token.failure(); err != nil {
+					h.log.WithError(err).Error()
+				}
+				return // Otherwise connection is lost again before login
+			}
+			h.setStatus(ui.StatusOnline)
+			if h.cfg.ReconnectIndicator && h.ChatUI() != nil {
+				h.ChatUI().IndicateReconnect()
+			}
+			if since != 0 {
+				h.requestHistory(sinceID, since)
+			}
+			h.sendJoinMessage()
+			h.joinRooms()
+			h.retryPending()
+			h.onLogin.run()
 		}()
 	})
 }
 
-// HandleLoginResponse performs actions to do when server responds with login status and access token.
-func (h *Handler) HandleLoginResponse() {
-	h.conn.AddOnRespListener(func(resp map[string]any) {
-		if resp["type"] != typeLoginResp {
-			return
-		}
-		var r loginResp
+// HandleLoginResponse performs actions to do when server responds with login status and access token. If server
+// rejects credentials, new ones are asked with Handler.Prompter. If the prompt fails, e.g. user cancels it, <cancel> is
+// called with prompt error, so the program can exit. If Handler.Prompter is nil, login fails with ErrNicknameRejected
+// or ErrAuthFailed instead, see LoginAndWaitForToken.
+func (h *Handler) HandleLoginResponse(cancel context.CancelCauseFunc) {
+	h.conn.AddOnTypeListener(protocol.TypeLoginResp, func(resp map[string]any) {
+		var r protocol.LoginResp
 		err := mapstructure.Decode(resp, &r)
 		if err != nil {
-			h.log.Error(errors.Wrap(err, "Decode login status response"))
+			h.log.WithError(err).Error("Decode login status response")
 			return
 		}
 		switch r.Status {
-		case statusOk:
+		case protocol.StatusOk:
 			h.log.Info("Login successful")
-			h.tokenCh <- r.Token
-		case statusNameAlreadyTaken:
-			h.log.Warn("Name is already taken")
-			h.cfg.Nickname = stdinUtil.AskNickname(h.log)
+			h.token.set(r.Token)
+		case protocol.StatusNameAlreadyTaken, protocol.StatusNameIsEmpty, protocol.StatusNameIsTooLong:
+			if h.Prompter == nil {
+				h.token.fail(errors.Wrap(ErrNicknameRejected, nicknameRejections[r.Status]))
+				return
+			}
+			h.log.Warn(nicknameRejections[r.Status])
+			if h.cfg.Nickname, err = h.Prompter.AskNickname(ValidateNickname); err != nil {
+				cancel(err)
+				return
+			}
+			if h.ChatUI() != nil {
+				h.ChatUI().SetIdentity(h.cfg.Nickname, h.cfg.ServerAddress)
+			}
+			if err := h.login(); err != nil {
+				h.log.WithError(err).Error()
+			}
+		case protocol.StatusAuthFailed:
+			if h.Prompter == nil {
+				h.token.fail(ErrAuthFailed)
+				return
+			}
+			h.log.Warn(lo.Ternary(h.Password == "", "Password is required", "Wrong password"))
+			if h.Password, err = h.Prompter.AskPassword(); err != nil {
+				cancel(err)
+				return
+			}
 			if err := h.login(); err != nil {
-				h.log.Error(err)
+				h.log.WithError(err).Error()
 			}
 		default:
-			h.log.Error("Login failed, status: ", r.Status)
+			h.token.fail(errors.Newf("Login failed, status: %v", r.Status))
 		}
 	})
 }
 
-// LoginAndWaitForToken sends login request and blocks until access token is received back.
-func (h *Handler) LoginAndWaitForToken() {
+// LoginAndWaitForToken sends login request and blocks until access token is received back or connection is lost. It
+// returns error if login failed, e.g. ErrNicknameRejected, or cause of <ctx> cancellation if it's cancelled first,
+// e.g. by HandleLoginResponse once login prompt fails.
+func (h *Handler) LoginAndWaitForToken(ctx context.Context) error {
 	if err := h.login(); err != nil {
-		h.log.Error(err)
+		h.log.WithError(err).Error()
+	}
+	if h.token.wait(ctx) {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	return h.token.failure()
+}
+
+// HandleAll registers all handlers of server responses, see HandleOnDisconnect and HandleLoginResponse for <ctx> and
+// <cancel>. It should be called before listening starts, messages received before chat UI is set are kept in backlog.
+func (h *Handler) HandleAll(ctx context.Context, cancel context.CancelCauseFunc) {
+	h.HandleOnDisconnect(ctx)
+	h.HandleHandshake()
+	h.HandleLoginResponse(cancel)
+	h.HandleHistory()
+	h.HandleChatMsgToClient()
+	h.HandlePostMessageResponse()
+	h.HandleOnlineUsers()
+	h.HandleOnlineCount()
+	h.HandlePrivateMessage()
+	h.HandleTyping()
+	h.HandleReportResponse()
+	h.HandleKicked()
+	h.HandleMessageChanges()
+}
+
+// Start checks protocol version with server if handshake is enabled in config, logs in and performs post login
+// actions. It blocks until access token is received and returns error if server uses incompatible protocol or login
+// failed, see Handshake and LoginAndWaitForToken.
+func (h *Handler) Start(ctx context.Context) error {
+	if err := h.CheckProtocol(); err != nil {
+		return err
+	}
+	if err := h.LoginAndWaitForToken(ctx); err != nil {
+		return err
 	}
-	h.token = <-h.tokenCh
+	h.PostLogin()
+	return nil
 }
 
-// PostMessage sends post message request to server.
+// PostLogin performs actions to do after first successful login: switches to default room, requests chat history,
+// sends join message, joins configured rooms and runs login listeners.
+func (h *Handler) PostLogin() {
+	h.enterDefaultRoom()
+	h.requestHistory(0, 0)
+	h.sendJoinMessage()
+	h.joinRooms()
+	h.onLogin.run()
+}
+
+// PostMessage sends post message request to server, unless rate limit of outgoing messages is exceeded. Message is
+// retried if it's not confirmed by server in time.
 func (h *Handler) PostMessage(msg string) {
-	err := h.conn.WriteJSON(postMsgReq{Type: typePostMessageReq, Token: h.token, Msg: msg})
+	h.post(msg, false)
+}
+
+// PostMessageAndWait sends post message request to server like PostMessage, but blocks until message is confirmed by
+// server. It returns error if message is too long, rate limit is exceeded, server rejects message or doesn't confirm
+// it after all attempts, or <ctx> is cancelled first. If server doesn't confirm message IDs, it returns once message
+// is sent.
+func (h *Handler) PostMessageAndWait(ctx context.Context, msg string) error {
+	if err := h.checkPost(msg); err != nil {
+		return err
+	}
+	id := h.pending.newID()
+	delivered := h.deliveries.watch(id)
+	defer h.deliveries.unwatch(id)
+	h.echo(id, msg, false)
+	h.sendPending(id, msg, false)
+	select {
+	case err := <-delivered:
+		return err
+	case <-ctx.Done():
+		return errors.Wrap(context.Cause(ctx), "Wait for message confirmation")
+	}
+}
+
+// postAction sends post message request for action message <msg>, e.g. "waves" for "/me waves", printed as
+// "* nickname waves".
+func (h *Handler) postAction(msg string) {
+	h.post(msg, true)
+}
+
+// post sends post message request for <msg>, which is action message if <action> is true, unless <msg> is too long
+// or rate limit is exceeded.
+func (h *Handler) post(msg string, action bool) {
+	if err := h.checkPost(msg); err != nil {
+		h.log.WithError(err).Warn()
+		return
+	}
+	id := h.pending.newID()
+	h.echo(id, msg, action)
+	h.sendPending(id, msg, action)
+}
+
+// checkPost returns error if <msg> can't be sent, since it's too long or rate limit is exceeded. Otherwise it takes
+// rate limit token for the message.
+func (h *Handler) checkPost(msg string) error {
+	if err := h.checkLength(msg); err != nil {
+		return err
+	}
+	if !h.limiter.allow() {
+		return errors.New("Message is not sent: too many messages in a short time, try again later")
+	}
+	return nil
+}
+
+// echo prints own message <msg> with <id>, which is action message if <action> is true, to chat box as soon as it's
+// sent, with it's delivery status. The same message broadcast back by server is not printed again.
+func (h *Handler) echo(id int64, msg string, action bool) {
+	if h.ChatUI() == nil {
+		return
+	}
+	h.echoes.add(id, msg, action)
+	err := h.ChatUI().AppendMessage(ui.Message{
+		LocalID: id, Nickname: h.cfg.Nickname, Text: msg, Time: h.now(), IsAction: action,
+		Status: lo.Ternary(h.pending.tracked(), ui.DeliverySending, ui.DeliveryNone),
+	})
 	if err != nil {
-		h.log.Error(errors.Wrap(err, "Send post message request"))
+		h.log.WithError(err).Error()
+	}
+}
+
+// setDelivery shows delivery <status> of own message with <id> and passes it to PostMessageAndWait, if it waits for
+// it. Failed message is not expected to be broadcast back by server.
+func (h *Handler) setDelivery(id int64, status ui.DeliveryStatus) {
+	if status == ui.DeliveryFailed {
+		h.echoes.remove(id)
+		h.deliveries.resolve(id, errors.New("Message is not confirmed by server after all attempts"))
+	} else if status != ui.DeliverySending {
+		h.deliveries.resolve(id, nil)
+	}
+	if h.ChatUI() != nil {
+		h.ChatUI().SetDeliveryStatus(id, status)
+	}
+}
+
+// checkLength returns error if <msg> is longer than allowed by config.
+func (h *Handler) checkLength(msg string) error {
+	if limit := h.cfg.MessageLengthLimit(); utf8.RuneCountInString(msg) > limit {
+		return errors.Newf("Message is longer than %v symbols", limit)
 	}
+	return nil
+}
+
+// sendPending sends post message request for message <msg> with <id>, adding it to pending messages. If message is
+// not confirmed within timeout, it's sent again until postAttempts are exhausted. If request can't be sent, message is
+// retried after reconnect. If server doesn't confirm message IDs, message is sent once. If <action> is true, message is
+// sent as action message.
+func (h *Handler) sendPending(id int64, msg string, action bool) {
+	room, _ := h.rooms.current()
+	err := h.conn.WriteJSON(protocol.PostMsgReq{
+		Type: protocol.TypePostMessageReq, Token: h.token.get(), Msg: msg, ID: id, Room: room, Action: action,
+	})
+	if !h.pending.tracked() {
+		if err != nil {
+			h.log.WithError(err).Error("Send post message request")
+		}
+		h.setDelivery(id, lo.Ternary(err == nil, ui.DeliveryNone, ui.DeliveryFailed))
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("Send post message request, will retry after reconnect")
+		h.showPending(h.pending.push(id, msg, action, 0, nil))
+		return
+	}
+	timeout := ackTimeout(h.diagnostics().RTT())
+	h.showPending(h.pending.push(id, msg, action, timeout, func() {
+		if h.pending.attempts(id) >= h.postAttempts {
+			h.showPending(h.pending.pop(id))
+			h.setDelivery(id, ui.DeliveryFailed)
+			h.log.Warnf("Message was not confirmed by server after %v attempts, it may be lost", h.postAttempts)
+			return
+		}
+		h.log.Debugf("Message was not confirmed by server within %v, retrying", timeout.Round(time.Millisecond))
+		h.sendPending(id, msg, action)
+	}))
+}
+
+// untrackPending stops waiting for confirmation and retrying of own messages, once server responds without message ID.
+// Delivery status of messages is not known then, so it's not shown.
+func (h *Handler) untrackPending() {
+	ids := h.pending.untrack()
+	if ids == nil {
+		return
+	}
+	h.log.Warn("Server doesn't confirm message IDs, messages will not be retried if they are lost")
+	h.showPending(0)
+	for _, id := range ids {
+		h.setDelivery(id, ui.DeliveryNone)
+	}
+}
+
+// retryPending sends again messages which were not confirmed by server before connection was lost.
+func (h *Handler) retryPending() {
+	retries, dropped := h.pending.retries(h.postAttempts)
+	if len(dropped) > 0 {
+		h.log.Warnf("%v messages were not confirmed by server after %v attempts, they may be lost", len(dropped),
+			h.postAttempts)
+	}
+	for _, id := range dropped {
+		h.setDelivery(id, ui.DeliveryFailed)
+	}
+	for _, pending := range retries {
+		h.sendPending(pending.id, pending.msg, pending.action)
+	}
+	h.showPending(len(retries))
 }
 
 // PostMessage sends online useres list request to server.
 func (h *Handler) RequestOnlineUsers() {
-	if err := h.conn.WriteJSON(onlineUsersReq{Type: typeOnlineUsersReq, Token: h.token}); err != nil {
-		h.log.Error(errors.Wrap(err, "Send online users request"))
+	err := h.conn.WriteJSON(protocol.OnlineUsersReq{Type: protocol.TypeOnlineUsersReq, Token: h.token.get()})
+	if err != nil {
+		h.log.WithError(err).Error("Send online users request")
 	}
 }
 
 // HandleChatMsgToClient performs actions to do when server sends chat message to client.
 func (h *Handler) HandleChatMsgToClient() {
-	h.conn.AddOnRespListener(func(resp map[string]any) {
-		if resp["type"] != typeChatMessageToClient {
+	h.conn.AddOnTypeListener(protocol.TypeChatMessageToClient, func(resp map[string]any) {
+		var r protocol.ChatMsgToClient
+		err := mapstructure.Decode(resp, &r)
+		if err != nil {
+			h.log.WithError(err).Error("Decode chat message to client")
+			return
+		}
+		for _, listener := range h.onMessage {
+			listener(r)
+		}
+		if h.ChatUI() == nil {
+			return
+		}
+		if !h.cursor.add(r) {
+			return
+		}
+		h.logToTranscript(transcriptEntry{
+			Time: h.msgTime(r.Timestamp), Nickname: r.Nickname, IsSystem: r.IsSystem, Room: r.Room, Msg: r.Msg,
+			Action: r.Action,
+		})
+		if !h.rooms.add(r) {
+			h.showRoom()
 			return
 		}
-		var r chatMsgToClient
+		if !r.IsSystem && h.mutes.has(r.Nickname) {
+			return
+		}
+		if !r.IsSystem && r.Nickname == h.cfg.Nickname {
+			if localID, ok := h.echoes.take(r.Msg, r.Action); ok {
+				h.ChatUI().SetMessageID(localID, r.ID) // Already printed when sent, show ID to reference it in commands
+				return
+			}
+		}
+		h.printMessage(r)
+		if shouldNotify(r.Priority) {
+			h.ChatUI().Notify()
+		} else if !r.IsSystem && r.Nickname != h.cfg.Nickname {
+			h.ChatUI().NotifyMessage(r.Nickname, r.Msg, isMention(r.Msg, h.cfg.Nickname))
+		}
+		if !r.IsSystem && r.Nickname != h.cfg.Nickname {
+			if reply, ok := h.autoreplier.reply(r.Msg); ok {
+				h.PostMessage(reply)
+			}
+		}
+	})
+}
+
+// AddOnMessageListener registers function <l> to be run by HandleChatMsgToClient when chat message is received, whether
+// chat UI is set or not. Listeners should be registered before listening starts, they are run in the order of
+// registration.
+func (h *Handler) AddOnMessageListener(l func(protocol.ChatMsgToClient)) {
+	h.onMessage = append(h.onMessage, l)
+}
+
+// HandlePrivateMessage performs actions to do when server sends private message to client or responds with status if
+// private message was delivered.
+func (h *Handler) HandlePrivateMessage() {
+	handle := func(resp map[string]any) {
+		if h.ChatUI() == nil {
+			return
+		}
+		switch resp["type"] {
+		case protocol.TypePrivateMessageToClient:
+			var r privateMsgToClient
+			if err := mapstructure.Decode(resp, &r); err != nil {
+				h.log.WithError(err).Error("Decode private message to client")
+				return
+			}
+			h.logToTranscript(transcriptEntry{Time: h.msgTime(r.Timestamp), Nickname: r.Nickname, Private: true, Msg: r.Msg})
+			if h.mutes.has(r.Nickname) {
+				return
+			}
+			err := h.ChatUI().AppendMessage(ui.Message{
+				Nickname: r.Nickname, Text: r.Msg, Time: h.msgTime(r.Timestamp), IsPrivate: true,
+			})
+			if err != nil {
+				h.log.WithError(err).Error()
+			}
+			h.ChatUI().Notify()
+		case protocol.TypePrivateMessageResp:
+			var r privateMsgResp
+			if err := mapstructure.Decode(resp, &r); err != nil {
+				h.log.WithError(err).Error("Decode private message status response")
+				return
+			}
+			switch r.Status {
+			case protocol.StatusOk:
+			case protocol.StatusUserNotFound:
+				h.log.Errorf("Private message failed, user %v not found", r.Recipient)
+			default:
+				h.log.Error("Private message failed, status: ", r.Status)
+			}
+		}
+	}
+	h.conn.AddOnTypeListener(protocol.TypePrivateMessageToClient, handle)
+	h.conn.AddOnTypeListener(protocol.TypePrivateMessageResp, handle)
+}
+
+// React posts reaction with <emoji> to chat box <line>. Server has no dedicated reaction request, so reaction is
+// posted as a regular message quoting the line.
+func (h *Handler) React(line string, emoji string) {
+	h.PostMessage(fmt.Sprintf("%v to \"%v\"", emoji, line))
+}
+
+// HandleReportResponse performs actions to do when server responds with status of message report.
+func (h *Handler) HandleReportResponse() {
+	h.conn.AddOnTypeListener(protocol.TypeReportResp, func(resp map[string]any) {
+		var r reportResp
+		if err := mapstructure.Decode(resp, &r); err != nil {
+			h.log.WithError(err).Error("Decode report status response")
+			return
+		}
+		switch r.Status {
+		case protocol.StatusOk:
+			h.log.Infof("Message #%v is reported to moderators", r.MsgID)
+		case protocol.StatusMessageNotFound:
+			h.log.Errorf("Report failed, message #%v not found", r.MsgID)
+		default:
+			h.log.Error("Report failed, status: ", r.Status)
+		}
+	})
+}
+
+// SendTyping sends signal to server that user is typing.
+func (h *Handler) SendTyping() {
+	if err := h.conn.WriteJSON(typingReq{Type: protocol.TypeTypingReq, Token: h.token.get()}); err != nil {
+		h.log.WithError(err).Error("Send typing request")
+	}
+}
+
+// HandleTyping performs actions to do when server signals that another user is typing.
+func (h *Handler) HandleTyping() {
+	h.conn.AddOnTypeListener(protocol.TypeTypingToClient, func(resp map[string]any) {
+		if h.ChatUI() == nil {
+			return
+		}
+		var r typingToClient
 		err := mapstructure.Decode(resp, &r)
 		if err != nil {
-			h.log.Error(errors.Wrap(err, "Decode chat message to client"))
+			h.log.WithError(err).Error("Decode typing signal")
 			return
 		}
-		if err := h.ChatUI.PrintToChatBox(r.Nickname, r.Msg, r.IsSystem); err != nil {
-			h.log.Error(err)
+		if r.Nickname != h.cfg.Nickname {
+			h.ChatUI().SetTyping(r.Nickname)
 		}
 	})
 }
 
-// HandlePostMessageResponse performs actions to do when server responds with status if message was posted.
-func (h *Handler) HandlePostMessageResponse() {
-	h.conn.AddOnRespListener(func(resp map[string]any) {
-		if resp["type"] != typePostMessageResp {
+// HandleHistory performs actions to do when server sends recent chat messages to client. If history arrives before
+// chat UI is set, it's kept until PrintBacklog is called.
+func (h *Handler) HandleHistory() {
+	h.conn.AddOnTypeListener(protocol.TypeHistory, func(resp map[string]any) {
+		var r history
+		err := mapstructure.Decode(resp, &r)
+		if err != nil {
+			h.log.WithError(err).Error("Decode history response")
 			return
 		}
-		var r postMsgResp
+		if r.Status != protocol.StatusOk {
+			h.log.Error("Get history failed, status: ", r.Status)
+			return
+		}
+		msgs := lo.Filter(r.Messages, func(msg protocol.ChatMsgToClient, _ int) bool {
+			return h.cursor.add(msg) && h.rooms.add(msg)
+		})
+		h.backlogMu.Lock()
+		defer h.backlogMu.Unlock()
+		if h.ChatUI() == nil {
+			h.backlog = append(h.backlog, msgs...)
+			return
+		}
+		h.printMessages(msgs)
+	})
+}
+
+// PrintBacklog prints chat messages received before chat UI was set. It should be called as soon as chat UI is set.
+func (h *Handler) PrintBacklog() {
+	h.backlogMu.Lock()
+	defer h.backlogMu.Unlock()
+	h.printMessages(h.backlog)
+	h.backlog = nil
+	h.showRoom()
+}
+
+// HandlePostMessageResponse performs actions to do when server responds with status if message was posted.
+func (h *Handler) HandlePostMessageResponse() {
+	h.conn.AddOnTypeListener(protocol.TypePostMessageResp, func(resp map[string]any) {
+		var r protocol.PostMsgResp
 		err := mapstructure.Decode(resp, &r)
 		if err != nil {
-			h.log.Error(errors.Wrap(err, "Decode post message status response"))
+			h.log.WithError(err).Error("Decode post message status response")
+			return
+		}
+		if r.ID == 0 {
+			h.untrackPending()
+			if r.Status != protocol.StatusOk {
+				h.log.Error("Post message failed, status: ", r.Status)
+			}
 			return
 		}
-		if r.Status != statusOk {
+		id := r.ID
+		h.showPending(h.pending.pop(id))
+		if r.Status != protocol.StatusOk {
+			h.deliveries.resolve(id, errors.Newf("Post message failed, status: %v", r.Status))
+			h.setDelivery(id, ui.DeliveryFailed)
 			h.log.Error("Post message failed, status: ", r.Status)
+			return
 		}
+		h.setDelivery(id, ui.DeliveryOk)
 	})
 }
 
 // HandleOnlineUsers performs actions to do when server sends online users list to client.
 func (h *Handler) HandleOnlineUsers() {
-	h.conn.AddOnRespListener(func(resp map[string]any) {
-		if resp["type"] != typeOnlineUsers {
+	h.conn.AddOnTypeListener(protocol.TypeOnlineUsers, func(resp map[string]any) {
+		if h.ChatUI() == nil {
 			return
 		}
-		var r onlineUsers
+		var r protocol.OnlineUsers
 		err := mapstructure.Decode(resp, &r)
 		if err != nil {
-			h.log.Error(errors.Wrap(err, "Decode online users response"))
+			h.log.WithError(err).Error("Decode online users response")
 			return
 		}
-		if r.Status == statusOk {
-			h.ChatUI.OnlineUsersCh <- r.Users
+		if r.Status == protocol.StatusOk {
+			users, err := decodeOnlineUsers(r.Users)
+			if err != nil {
+				h.log.WithError(err).Error()
+				return
+			}
+			h.printPresenceChanges(users)
+			for i := range users {
+				users[i].Muted = h.mutes.has(users[i].Nickname)
+			}
+			h.ChatUI().SetOnlineUsers(users)
 		} else {
 			h.log.Error("Get online users failed, status: ", r.Status)
 		}
 	})
 }
 
-// login sends login request to server.
+// HandleOnlineCount performs actions to do when server pushes amount of online users without the list of them.
+func (h *Handler) HandleOnlineCount() {
+	h.conn.AddOnTypeListener(protocol.TypeOnlineCount, func(resp map[string]any) {
+		if h.ChatUI() == nil {
+			return
+		}
+		var r onlineCount
+		if err := mapstructure.Decode(resp, &r); err != nil {
+			h.log.WithError(err).Error("Decode online users count")
+			return
+		}
+		h.ChatUI().SetOnlineCount(r.Count)
+	})
+}
+
+// decodeOnlineUsers returns online <users> received from server, each being either nickname string or
+// protocol.OnlineUser object.
+func decodeOnlineUsers(users []any) ([]ui.OnlineUser, error) {
+	decoded, err := protocol.DecodeOnlineUsers(users)
+	if err != nil {
+		return nil, err
+	}
+	return lo.Map(decoded, func(u protocol.OnlineUser, _ int) ui.OnlineUser {
+		return ui.OnlineUser{
+			Nickname: u.Nickname,
+			Status:   u.Status,
+			Idle:     time.Duration(u.Idle * float64(time.Second)),
+			Role:     u.Role,
+			Away:     u.Away,
+		}
+	}), nil
+}
+
+// sendJoinMessage posts configured join message if it's set and wasn't sent recently.
+func (h *Handler) sendJoinMessage() {
+	if h.cfg.JoinMessage == "" {
+		return
+	}
+	if h.now().Sub(h.joinAt) < joinMessageInterval {
+		h.log.Debug("Skip join message, it was sent recently")
+		return
+	}
+	msg := expandJoinMessage(h.cfg.JoinMessage, h.cfg.Nickname, h.cfg.ServerAddress)
+	if err := h.checkLength(msg); err != nil {
+		h.log.WithError(err).Warn("Skip join message")
+		return
+	}
+	h.PostMessage(msg)
+	h.joinAt = h.now()
+}
+
+// expandJoinMessage returns <tmpl> with {nickname} and {server} placeholders replaced by <nickname> and <server>.
+func expandJoinMessage(tmpl string, nickname string, server string) string {
+	return strings.NewReplacer("{nickname}", nickname, "{server}", server).Replace(tmpl)
+}
+
+// sendPrivateMessage sends private message request to server. <args> should be in form of '<nickname> <text>'.
+func (h *Handler) sendPrivateMessage(args string) error {
+	recipient, msg, _ := strings.Cut(args, " ")
+	msg = strings.TrimSpace(msg)
+	if recipient == "" || msg == "" {
+		return errUsage
+	}
+	err := h.conn.WriteJSON(privateMsgReq{
+		Type: protocol.TypePrivateMessageReq, Token: h.token.get(), Recipient: recipient, Msg: msg,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Send private message request")
+	}
+	h.logToTranscript(transcriptEntry{Time: h.now(), Nickname: h.cfg.Nickname, Private: true, Msg: msg})
+	return h.ChatUI().AppendMessage(ui.Message{
+		Nickname: recipient, Text: msg, Time: h.now(), IsPrivate: true, IsOutgoing: true,
+	})
+}
+
+// reportMessage sends request to report message to moderators. <args> should contain message ID optionally prefixed
+// with "#" and reason, e.g. "#42 spam".
+func (h *Handler) reportMessage(args string) error {
+	id, reason, _ := strings.Cut(args, " ")
+	reason = strings.TrimSpace(reason)
+	msgID, err := parseMsgID(id)
+	if err != nil || reason == "" {
+		return errUsage
+	}
+	err = h.conn.WriteJSON(reportReq{Type: protocol.TypeReportReq, Token: h.token.get(), MsgID: msgID, Reason: reason})
+	return errors.Wrap(err, "Send report request")
+}
+
+// requestHistory sends recent chat messages request to server. If <sinceID> or <since> unix milliseconds timestamp is
+// not 0, only messages newer than that are requested, e.g. missed while connection was lost.
+func (h *Handler) requestHistory(sinceID int64, since int64) {
+	req := historyReq{
+		Type: protocol.TypeHistoryReq, Token: h.token.get(), Count: historyCount, SinceID: sinceID, Since: since,
+	}
+	if err := h.conn.WriteJSON(req); err != nil {
+		h.log.WithError(err).Error("Send history request")
+	}
+}
+
+// printMessages prints <msgs> to chat box in order, except messages of muted users.
+func (h *Handler) printMessages(msgs []protocol.ChatMsgToClient) {
+	for _, msg := range msgs {
+		if msg.IsSystem || !h.mutes.has(msg.Nickname) {
+			h.printMessage(msg)
+		}
+	}
+}
+
+// printMessage prints <msg> to chat box. If <msg> has ID, it's printed before the text, so message can be referenced
+// in commands. Action messages are printed as "* nickname text".
+func (h *Handler) printMessage(msg protocol.ChatMsgToClient) {
+	err := h.ChatUI().AppendMessage(ui.Message{
+		ID:          msg.ID,
+		Nickname:    msg.Nickname,
+		Source:      msg.Source,
+		Text:        msg.Msg,
+		Time:        h.msgTime(msg.Timestamp),
+		IsSystem:    msg.IsSystem,
+		IsImportant: msg.Priority >= priorityHigh,
+		IsAction:    msg.Action,
+		IsEdited:    msg.Edited,
+		Level:       msg.Level,
+	})
+	if err != nil {
+		h.log.WithError(err).Error()
+	}
+}
+
+// shouldNotify returns true if user should be notified about message with <priority>. High priority messages always
+// notify.
+func shouldNotify(priority float64) bool {
+	return priority >= priorityHigh
+}
+
+// isMention returns true if <msg> mentions user with <nickname>, ignoring case.
+func isMention(msg string, nickname string) bool {
+	return nickname != "" && strings.Contains(strings.ToLower(msg), strings.ToLower(nickname))
+}
+
+// msgTime returns time of message sent at <timestamp> unix milliseconds, or current time of Handler.Clock if
+// <timestamp> is not set.
+func (h *Handler) msgTime(timestamp int64) time.Time {
+	if timestamp == 0 {
+		return h.now()
+	}
+	return time.UnixMilli(timestamp)
+}
+
+// now returns current time of Handler.Clock.
+func (h *Handler) now() time.Time {
+	return h.Clock.Now()
+}
+
+// afterFunc runs <f> once duration <d> of Handler.Clock passes.
+func (h *Handler) afterFunc(d time.Duration, f func()) clock.Timer {
+	return h.Clock.AfterFunc(d, f)
+}
+
+// setStatus shows connection <state> in status bar of chat UI, if it's initialized.
+func (h *Handler) setStatus(state string) {
+	if h.ChatUI() != nil {
+		h.ChatUI().SetStatus(state)
+	}
+}
+
+// showPending shows <count> of messages waiting for confirmation from server in status bar of chat UI, if it's
+// initialized.
+func (h *Handler) showPending(count int) {
+	if h.ChatUI() != nil {
+		h.ChatUI().SetPending(count)
+	}
+}
+
+// login sends login request to server using Handler.Authenticator.
 func (h *Handler) login() error {
-	err := h.conn.WriteJSON(loginReq{Type: typeLoginReq, Nickname: h.cfg.Nickname})
-	return errors.Wrap(err, "Send login request")
+	h.token.retry()
+	return h.Authenticator.Login(h.conn)
 }
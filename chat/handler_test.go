@@ -0,0 +1,290 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go_chat_client/config"
+	"go_chat_client/protocol"
+	"go_chat_client/util/clock"
+	stdinUtil "go_chat_client/util/stdin"
+
+	"github.com/sirupsen/logrus"
+)
+
+// testTransport represents minimal connection.Transport recording requests instead of answering them, so tests
+// decide what server responds with. Responses are passed to listeners with deliver. It implements none of optional
+// transport interfaces.
+type testTransport struct {
+	mu     sync.Mutex
+	onType map[float64][]func(map[string]any)
+	reqs   chan map[string]any
+}
+
+// newTestTransport returns new test transport.
+func newTestTransport() *testTransport {
+	return &testTransport{onType: make(map[float64][]func(map[string]any)), reqs: make(chan map[string]any, 256)}
+}
+
+// Connect does nothing. Used to implement connection.Transport interface.
+func (t *testTransport) Connect(ctx context.Context) error {
+	return nil
+}
+
+// Listen blocks until <ctx> is cancelled, since responses are passed with deliver. Used to implement
+// connection.Transport interface.
+func (t *testTransport) Listen(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// CloseConn does nothing. Used to implement connection.Transport interface.
+func (t *testTransport) CloseConn() {}
+
+// WriteJSON records <req> in form it would be received by server. Used to implement connection.Transport interface.
+func (t *testTransport) WriteJSON(req any) error {
+	var fields map[string]any
+	if err := roundTrip(req, &fields); err != nil {
+		return err
+	}
+	t.reqs <- fields
+	return nil
+}
+
+// AddOnTypeListener registers function <l> to be run when response of type <msgType> is delivered. Used to implement
+// connection.Transport interface.
+func (t *testTransport) AddOnTypeListener(msgType float64, l func(map[string]any)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onType[msgType] = append(t.onType[msgType], l)
+}
+
+// AddOnDisconnectListener does nothing, since test transport is never disconnected. Used to implement
+// connection.Transport interface.
+func (t *testTransport) AddOnDisconnectListener(l func(error)) {}
+
+// deliver runs listeners of <resp> type right away, as if it's received from server.
+func (t *testTransport) deliver(resp any) {
+	var fields map[string]any
+	if err := roundTrip(resp, &fields); err != nil {
+		panic(err)
+	}
+	t.mu.Lock()
+	listeners := slices.Clone(t.onType[fields["type"].(float64)])
+	t.mu.Unlock()
+	for _, listener := range listeners {
+		listener(fields)
+	}
+}
+
+// next returns the next request recorded by <t>, failing the test if there is none.
+func (t *testTransport) next(tb testing.TB) map[string]any {
+	tb.Helper()
+	select {
+	case req := <-t.reqs:
+		return req
+	case <-time.After(time.Second):
+		tb.Fatal("No request is sent")
+		return nil
+	}
+}
+
+// nextOfType returns the next recorded request of <msgType>, skipping requests of other types.
+func (t *testTransport) nextOfType(tb testing.TB, msgType float64) map[string]any {
+	tb.Helper()
+	for {
+		if req := t.next(tb); req["type"] == msgType {
+			return req
+		}
+	}
+}
+
+// assertNoRequest fails the test if <t> recorded any request of <msgType> which is not taken with next yet.
+func (t *testTransport) assertNoRequest(tb testing.TB, msgType float64) {
+	tb.Helper()
+	for {
+		select {
+		case req := <-t.reqs:
+			if req["type"] == msgType {
+				tb.Fatalf("Unexpected request %v", req)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// testLogger returns logger discarding entries.
+func testLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+// newTestHandler returns handler with <cfg> communicating through test transport, logged in with nickname from <cfg>,
+// and fake clock it uses. Fake clock starts at current time, since handler is created with real one.
+func newTestHandler(t *testing.T, cfg *config.Config) (*Handler, *testTransport, *clock.Fake) {
+	t.Helper()
+	transport := newTestTransport()
+	h := NewHandler(testLogger(), cfg, transport)
+	fake := clock.NewFake(time.Now())
+	h.Clock = fake
+	h.token.set("token")
+	return h, transport, fake
+}
+
+// postResp returns post message response confirming message with <id>.
+func postResp(id any) map[string]any {
+	return map[string]any{"type": protocol.TypePostMessageResp, "status": protocol.StatusOk, "id": id}
+}
+
+// waitForWaiters waits until <n> timers of <fake> are waiting, failing the test if it doesn't happen within a second.
+func waitForWaiters(tb testing.TB, fake *clock.Fake, n int) {
+	tb.Helper()
+	deadline := time.Now().Add(time.Second)
+	for fake.Waiters() != n {
+		if time.Now().After(deadline) {
+			tb.Fatalf("%v timers are waiting, want %v", fake.Waiters(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMsgTime(t *testing.T) {
+	h, _, fake := newTestHandler(t, &config.Config{Nickname: "alice"})
+	fake.Advance(time.Hour)
+
+	if got := h.msgTime(0); !got.Equal(fake.Now()) {
+		t.Errorf("Time of message without timestamp is %v, want %v", got, fake.Now())
+	}
+	sent := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	if got := h.msgTime(sent.UnixMilli()); !got.Equal(sent) {
+		t.Errorf("Time of message with timestamp is %v, want %v", got, sent)
+	}
+}
+
+func TestLoginAskedAgain(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       float64
+		input        string
+		wantNickname string
+		wantPassword any
+	}{
+		{"nickname taken", protocol.StatusNameAlreadyTaken, "bob\n", "bob", nil},
+		{"nickname empty", protocol.StatusNameIsEmpty, "bob\n", "bob", nil},
+		{"nickname too long", protocol.StatusNameIsTooLong, "bob\n", "bob", nil},
+		{"auth failed", protocol.StatusAuthFailed, "secret\n", "alice", "secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := newTestTransport()
+			h := NewHandler(testLogger(), &config.Config{Nickname: "alice"}, transport)
+			h.Prompter = stdinUtil.NewPrompter(testLogger(), strings.NewReader(tt.input))
+			h.HandleLoginResponse(func(err error) { t.Errorf("Login is cancelled: %v", err) })
+			done := make(chan error, 1)
+			go func() { done <- h.LoginAndWaitForToken(context.Background()) }()
+
+			transport.nextOfType(t, protocol.TypeLoginReq)
+			transport.deliver(protocol.LoginResp{Type: protocol.TypeLoginResp, Status: tt.status})
+			req := transport.nextOfType(t, protocol.TypeLoginReq)
+			if req["nickname"] != tt.wantNickname || req["password"] != tt.wantPassword {
+				t.Errorf("Login is requested again with nickname %v and password %v, want %v and %v", req["nickname"],
+					req["password"], tt.wantNickname, tt.wantPassword)
+			}
+			transport.deliver(protocol.LoginResp{Type: protocol.TypeLoginResp, Status: protocol.StatusOk, Token: "token"})
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("Login failed: %v", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Login didn't finish")
+			}
+			if h.cfg.Nickname != tt.wantNickname {
+				t.Errorf("Nickname is %v, want %v", h.cfg.Nickname, tt.wantNickname)
+			}
+		})
+	}
+}
+
+func TestLoginPromptFailed(t *testing.T) {
+	transport := newTestTransport()
+	h := NewHandler(testLogger(), &config.Config{Nickname: "alice"}, transport)
+	h.Prompter = stdinUtil.NewPrompter(testLogger(), strings.NewReader(""))
+	ctx, cancel := context.WithCancelCause(context.Background())
+	h.HandleLoginResponse(cancel)
+	done := make(chan error, 1)
+	go func() { done <- h.LoginAndWaitForToken(ctx) }()
+
+	transport.nextOfType(t, protocol.TypeLoginReq)
+	transport.deliver(protocol.LoginResp{Type: protocol.TypeLoginResp, Status: protocol.StatusNameAlreadyTaken})
+	select {
+	case err := <-done:
+		if err == nil || errors.Is(err, context.Canceled) {
+			t.Errorf("Login error is %v, want error of prompt", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Login didn't finish")
+	}
+	transport.assertNoRequest(t, protocol.TypeLoginReq)
+}
+
+func TestSendJoinMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		want string
+	}{
+		{"literal", "Hi all", "Hi all"},
+		{"nickname", "{nickname} is here", "alice is here"},
+		{"server", "Back on {server}", "Back on example.com:8080"},
+		{"both repeated", "{nickname}@{server}, {nickname}", "alice@example.com:8080, alice"},
+		{"unknown placeholder", "Hi {room}", "Hi {room}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, transport, _ := newTestHandler(t, &config.Config{
+				Nickname: "alice", ServerAddress: "example.com:8080", JoinMessage: tt.tmpl,
+			})
+
+			h.sendJoinMessage()
+			if req := transport.nextOfType(t, protocol.TypePostMessageReq); req["msg"] != tt.want {
+				t.Errorf("Join message is %q, want %q", req["msg"], tt.want)
+			}
+			h.sendJoinMessage()
+			transport.assertNoRequest(t, protocol.TypePostMessageReq)
+		})
+	}
+}
+
+func TestShouldNotify(t *testing.T) {
+	tests := []struct {
+		priority float64
+		want     bool
+	}{
+		{priorityNormal, false},
+		{priorityHigh, true},
+		{priorityHigh + 1, true},
+	}
+	for _, tt := range tests {
+		if got := shouldNotify(tt.priority); got != tt.want {
+			t.Errorf("shouldNotify(%v) = %v, want %v", tt.priority, got, tt.want)
+		}
+	}
+}
+
+func TestMuteNotificationsInvalidDuration(t *testing.T) {
+	h, _, _ := newTestHandler(t, &config.Config{Nickname: "alice"})
+	for _, args := range []string{"", "soon", "30", "-5m"} {
+		if err := h.muteNotifications(args); !errors.Is(err, errUsage) {
+			t.Errorf("muteNotifications(%q) returned %v, want %v", args, err, errUsage)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package chat
+
+import (
+	"time"
+
+	"go_chat_client/protocol"
+
+	"github.com/cockroachdb/errors"
+	"github.com/mitchellh/mapstructure"
+)
+
+// handshakeTimeout is the maximum time to wait for handshake response from server.
+const handshakeTimeout = time.Second * 5
+
+// ErrIncompatibleProtocol is returned by Handshake if server doesn't support protocol version of client.
+var ErrIncompatibleProtocol = errors.New("Server uses incompatible protocol version")
+
+// handshakeReq represents request announcing protocol version of client, sent right after connecting.
+type handshakeReq struct {
+	Type    float64 `json:"type"`
+	Version int     `json:"version"`
+}
+
+// handshakeResp represents handshake response from server with protocol version of server.
+type handshakeResp struct {
+	Type    float64 `json:"type"`
+	Status  float64 `json:"status"`
+	Version int     `json:"version"`
+}
+
+// HandleHandshake performs actions to do when server responds to handshake request: passes the response to
+// Handshake waiting for it.
+func (h *Handler) HandleHandshake() {
+	h.conn.AddOnTypeListener(protocol.TypeHandshakeResp, func(resp map[string]any) {
+		var r handshakeResp
+		if err := mapstructure.Decode(resp, &r); err != nil {
+			h.log.WithError(err).Error("Decode handshake response")
+			return
+		}
+		select {
+		case h.handshakeCh <- r:
+		default:
+		}
+	})
+}
+
+// CheckProtocol runs Handshake if it's enabled in config, so chat UI and client package check protocol version the
+// same way. It returns nil otherwise.
+func (h *Handler) CheckProtocol() error {
+	if !h.cfg.Handshake {
+		return nil
+	}
+	return h.Handshake()
+}
+
+// Handshake announces protocol version of client to server and blocks until server responds. It returns
+// ErrIncompatibleProtocol if server doesn't support it. Servers not responding within handshakeTimeout are assumed to
+// predate handshake and are used as is, with a warning, so it should be done only if user enabled it. Protocol version
+// of server can't change on reconnect, so it's done once, before the first login.
+func (h *Handler) Handshake() error {
+	err := h.conn.WriteJSON(handshakeReq{Type: protocol.TypeHandshakeReq, Version: protocol.Version})
+	if err != nil {
+		return errors.Wrap(err, "Send handshake request")
+	}
+	select {
+	case r := <-h.handshakeCh:
+		if r.Status == protocol.StatusIncompatibleVersion || r.Version != protocol.Version {
+			return errors.Wrapf(ErrIncompatibleProtocol, "Client version is %v, server version is %v",
+				protocol.Version, r.Version)
+		}
+		h.log.Debug("Protocol version ", r.Version, " is confirmed by server")
+	case <-h.Clock.After(handshakeTimeout):
+		h.log.Warnf("Server didn't respond to handshake in %v, it may be incompatible with this client", handshakeTimeout)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package chat
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go_chat_client/config"
+	"go_chat_client/protocol"
+)
+
+// handshake runs Handshake of <h> in new goroutine and returns channel receiving its result, once the request is
+// recorded by <transport>.
+func handshake(t *testing.T, h *Handler, transport *testTransport) <-chan error {
+	t.Helper()
+	result := make(chan error, 1)
+	go func() {
+		result <- h.Handshake()
+	}()
+	req := transport.nextOfType(t, protocol.TypeHandshakeReq)
+	if req["version"] != float64(protocol.Version) {
+		t.Errorf("Handshake request version is %v, want %v", req["version"], protocol.Version)
+	}
+	return result
+}
+
+// handshakeResult returns error received by <result>, failing the test if Handshake doesn't return within a second.
+func handshakeResult(t *testing.T, result <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("Handshake didn't return")
+		return nil
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	tests := []struct {
+		name string
+		resp handshakeResp
+		want error
+	}{
+		{
+			name: "confirmed",
+			resp: handshakeResp{Type: protocol.TypeHandshakeResp, Status: protocol.StatusOk, Version: protocol.Version},
+		},
+		{
+			name: "incompatible status",
+			resp: handshakeResp{Type: protocol.TypeHandshakeResp, Status: protocol.StatusIncompatibleVersion,
+				Version: protocol.Version},
+			want: ErrIncompatibleProtocol,
+		},
+		{
+			name: "other version",
+			resp: handshakeResp{Type: protocol.TypeHandshakeResp, Status: protocol.StatusOk, Version: protocol.Version + 1},
+			want: ErrIncompatibleProtocol,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, transport, _ := newTestHandler(t, &config.Config{Nickname: "alice"})
+			h.HandleHandshake()
+			result := handshake(t, h, transport)
+
+			transport.deliver(tt.resp)
+			if err := handshakeResult(t, result); !errors.Is(err, tt.want) {
+				t.Errorf("Handshake returned %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandshakeIgnoredByServer(t *testing.T) {
+	h, transport, fake := newTestHandler(t, &config.Config{Nickname: "alice"})
+	h.HandleHandshake()
+	result := handshake(t, h, transport)
+
+	waitForWaiters(t, fake, 1)
+	fake.Advance(handshakeTimeout - time.Millisecond)
+	select {
+	case err := <-result:
+		t.Fatalf("Handshake returned %v before timeout", err)
+	default:
+	}
+	fake.Advance(time.Millisecond)
+	if err := handshakeResult(t, result); err != nil {
+		t.Errorf("Handshake returned %v once server didn't respond, want nil", err)
+	}
+}
@@ -0,0 +1,73 @@
+package chat
+
+import (
+	"sync"
+
+	"go_chat_client/protocol"
+	"go_chat_client/ui"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// kickedMsg represents notice from server that user is disconnected for a policy reason and should not reconnect.
+type kickedMsg struct {
+	Type   float64 `json:"type"`
+	Reason string  `json:"reason"`
+	Ban    bool    `json:"ban"`
+}
+
+// kick represents the last kick or ban notice received from server.
+type kick struct {
+	mu     sync.Mutex
+	notice *kickedMsg // Nil if user was not kicked
+}
+
+// set stores kick <notice>.
+func (k *kick) set(notice kickedMsg) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.notice = &notice
+}
+
+// get returns kick notice and true if user was kicked.
+func (k *kick) get() (kickedMsg, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.notice == nil {
+		return kickedMsg{}, false
+	}
+	return *k.notice, true
+}
+
+// HandleKicked performs actions to do when server notifies that user is kicked or banned: prints the reason to chat
+// box and marks connection as not to be restored, so HandleOnDisconnect doesn't reconnect once server closes it.
+func (h *Handler) HandleKicked() {
+	h.conn.AddOnTypeListener(protocol.TypeKicked, func(resp map[string]any) {
+		var r kickedMsg
+		if err := mapstructure.Decode(resp, &r); err != nil {
+			h.log.WithError(err).Error("Decode kick notice")
+			return
+		}
+		h.kick.set(r)
+		h.setStatus(ui.StatusKicked)
+		if h.ChatUI() == nil {
+			h.log.Error(kickText(r))
+			return
+		}
+		if err := h.ChatUI().PrintToChatBox("", kickText(r), true, true); err != nil {
+			h.log.WithError(err).Error()
+		}
+	})
+}
+
+// kickText returns message explaining kick <notice> to user.
+func kickText(notice kickedMsg) string {
+	text := "You are kicked from server"
+	if notice.Ban {
+		text = "You are banned from server"
+	}
+	if notice.Reason != "" {
+		text += ", reason: " + notice.Reason
+	}
+	return text + ". Connection will not be restored."
+}
@@ -0,0 +1,37 @@
+package chat
+
+import (
+	"slices"
+	"sync"
+)
+
+// loginListeners represents functions run after each successful login. It's safe for concurrent use.
+type loginListeners struct {
+	mu        sync.Mutex
+	listeners []func()
+}
+
+// add registers function <listener> to be run after login.
+func (l *loginListeners) add(listener func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.listeners = append(l.listeners, listener)
+}
+
+// run runs registered functions in the order of registration. Functions can register other ones while running.
+func (l *loginListeners) run() {
+	l.mu.Lock()
+	listeners := slices.Clone(l.listeners)
+	l.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener()
+	}
+}
+
+// AddOnLoginListener registers function <l> to be run after each successful login, both the first one and after
+// connection is restored, e.g. to request data which is not sent by server until user is logged in. Listeners are run
+// in the order of registration, after sending join message and joining rooms.
+func (h *Handler) AddOnLoginListener(l func()) {
+	h.onLogin.add(l)
+}
@@ -0,0 +1,56 @@
+package chat
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// defaultMacros are the macros available without config, name to expansion.
+var defaultMacros = map[string]string{
+	"shrug":     `¯\_(ツ)_/¯`,
+	"tableflip": "(╯°□°)╯︵ ┻━┻",
+}
+
+// newMacros returns <custom> macros from config merged with defaultMacros, with names lowered and leading "/"
+// removed. Custom macros replace default ones with the same name. Macros with empty name or expansion are skipped.
+func newMacros(custom map[string]string) map[string]string {
+	macros := maps.Clone(defaultMacros)
+	for name, expansion := range custom {
+		name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "/"))
+		if name != "" && expansion != "" {
+			macros[name] = expansion
+		}
+	}
+	return macros
+}
+
+// warnMacroCollisions logs warning for each macro named as a command, since commands take precedence over macros.
+func (h *Handler) warnMacroCollisions() {
+	for _, cmd := range h.commands() {
+		if _, ok := h.macros[cmd.name]; ok {
+			h.log.Warnf("Macro /%v is ignored, since there is command with the same name", cmd.name)
+		}
+	}
+}
+
+// expandMacro returns expansion of macro <name> followed by <args>, if any, and true if macro exists.
+func (h *Handler) expandMacro(name string, args string) (string, bool) {
+	expansion, ok := h.macros[name]
+	if !ok {
+		return "", false
+	}
+	if args != "" {
+		expansion += " " + args
+	}
+	return expansion, true
+}
+
+// macroNames returns sorted names of all macros.
+func (h *Handler) macroNames() []string {
+	names := lo.Keys(h.macros)
+	slices.Sort(names)
+	return names
+}
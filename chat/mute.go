@@ -0,0 +1,112 @@
+package chat
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// mutes represents nicknames of users which messages are hidden. It's safe for concurrent use.
+type mutes struct {
+	mu        sync.Mutex
+	nicknames []string
+}
+
+// set replaces muted nicknames with <nicknames>, e.g. taken from config.
+func (m *mutes) set(nicknames []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nicknames = slices.Clone(nicknames)
+}
+
+// add mutes <nickname> and returns true if it wasn't muted yet.
+func (m *mutes) add(nickname string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if slices.Contains(m.nicknames, nickname) {
+		return false
+	}
+	m.nicknames = append(m.nicknames, nickname)
+	return true
+}
+
+// remove unmutes <nickname> and returns true if it was muted.
+func (m *mutes) remove(nickname string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx := slices.Index(m.nicknames, nickname)
+	if idx == -1 {
+		return false
+	}
+	m.nicknames = slices.Delete(m.nicknames, idx, idx+1)
+	return true
+}
+
+// has returns true if <nickname> is muted.
+func (m *mutes) has(nickname string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Contains(m.nicknames, nickname)
+}
+
+// list returns muted nicknames, sorted.
+func (m *mutes) list() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	nicknames := slices.Clone(m.nicknames)
+	slices.Sort(nicknames)
+	return nicknames
+}
+
+// muteUser hides messages of user with nickname <args>, including private ones and notifications about them. Muted
+// nicknames are saved to config.
+func (h *Handler) muteUser(args string) error {
+	nickname := strings.TrimSpace(args)
+	if nickname == "" {
+		return errUsage
+	}
+	if nickname == h.cfg.Nickname {
+		h.log.Warn("You can't mute yourself")
+		return nil
+	}
+	if !h.mutes.add(nickname) {
+		h.log.Infof("%v is already muted", nickname)
+		return nil
+	}
+	h.log.Infof("%v is muted, type /unmute %v to show their messages again", nickname, nickname)
+	h.saveMutes()
+	return nil
+}
+
+// unmuteUser shows messages of user with nickname <args> muted by /mute command again.
+func (h *Handler) unmuteUser(args string) error {
+	nickname := strings.TrimSpace(args)
+	if nickname == "" {
+		return errUsage
+	}
+	if !h.mutes.remove(nickname) {
+		h.log.Infof("%v is not muted", nickname)
+		return nil
+	}
+	h.log.Infof("%v is unmuted", nickname)
+	h.saveMutes()
+	return nil
+}
+
+// saveMutes writes muted nicknames to config and refreshes online users box, so it marks muted users.
+func (h *Handler) saveMutes() {
+	h.cfg.Muted = h.mutes.list()
+	h.runConfigChangeListeners()
+	if h.ChatUI() != nil {
+		h.ChatUI().RefreshOnlineBox()
+	}
+}
+
+// showMuted prints nicknames muted by /mute command to chat box.
+func (h *Handler) showMuted(args string) error {
+	nicknames := h.mutes.list()
+	if len(nicknames) == 0 {
+		return h.printSystemLines([]string{"No users are muted, use /mute to mute them"})
+	}
+	return h.printSystemLines([]string{"Muted users: " + strings.Join(nicknames, ", ")})
+}
@@ -0,0 +1,36 @@
+package chat
+
+import (
+	"fmt"
+	"time"
+
+	"go_chat_client/connection"
+)
+
+// netDiag represents snapshot of connection diagnostics shown by /netdiag command.
+type netDiag struct {
+	state          connection.State
+	rtt            time.Duration
+	reconnects     int
+	lastDisconnect error
+	pending        int
+}
+
+// lines returns diagnostics formatted as lines to print to chat box.
+func (d netDiag) lines() []string {
+	rtt := "not measured yet"
+	if d.rtt > 0 {
+		rtt = d.rtt.Round(time.Millisecond).String()
+	}
+	lastDisconnect := "none"
+	if d.lastDisconnect != nil {
+		lastDisconnect = d.lastDisconnect.Error()
+	}
+	return []string{
+		fmt.Sprintf("Connection state: %v", d.state),
+		fmt.Sprintf("Round-trip time: %v", rtt),
+		fmt.Sprintf("Reconnects: %v", d.reconnects),
+		fmt.Sprintf("Last disconnect reason: %v", lastDisconnect),
+		fmt.Sprintf("Messages waiting for confirmation: %v", d.pending),
+	}
+}
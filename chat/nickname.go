@@ -0,0 +1,35 @@
+package chat
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/errors"
+)
+
+// MaxNicknameLength is the maximum length of nickname accepted by server. Server counts length in symbols (Unicode code
+// points), not bytes, so nickname of 20 emoji or accented letters is allowed.
+const MaxNicknameLength = 20
+
+// Errors returned by ValidateNickname.
+var (
+	ErrNicknameEmpty      = errors.New("Nickname can't be empty")
+	ErrNicknameTooLong    = errors.Newf("Nicknames with length > %v symbols are not allowed", MaxNicknameLength)
+	ErrNicknameCharacters = errors.New("Nickname can't contain spaces or control characters")
+)
+
+// ValidateNickname returns error if <name> can't be used to log in. Nicknames with spaces are not allowed as commands
+// like /msg take nickname as the first word.
+func ValidateNickname(name string) error {
+	if name == "" {
+		return ErrNicknameEmpty
+	}
+	if utf8.RuneCountInString(name) > MaxNicknameLength {
+		return ErrNicknameTooLong
+	}
+	if strings.IndexFunc(name, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) != -1 {
+		return ErrNicknameCharacters
+	}
+	return nil
+}
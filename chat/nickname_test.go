@@ -0,0 +1,56 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+)
+
+func TestValidateNickname(t *testing.T) {
+	tests := []struct {
+		name     string
+		nickname string
+		want     error
+	}{
+		{name: "valid", nickname: "alice", want: nil},
+		{name: "valid with symbols", nickname: "alice_2-b.c", want: nil},
+		{name: "empty", nickname: "", want: ErrNicknameEmpty},
+		{name: "space", nickname: "alice bob", want: ErrNicknameCharacters},
+		{name: "leading space", nickname: " alice", want: ErrNicknameCharacters},
+		{name: "tab", nickname: "alice\t", want: ErrNicknameCharacters},
+		{name: "non-breaking space", nickname: "alice\u00a0bob", want: ErrNicknameCharacters},
+		{name: "control character", nickname: "alice\x1b[31m", want: ErrNicknameCharacters},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := ValidateNickname(test.nickname); !errors.Is(err, test.want) {
+				t.Errorf("ValidateNickname(%q) is %v, want %v", test.nickname, err, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateNicknameLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		nickname string
+		want     error
+	}{
+		{name: "ASCII at limit", nickname: strings.Repeat("a", MaxNicknameLength), want: nil},
+		{name: "ASCII over limit", nickname: strings.Repeat("a", MaxNicknameLength+1), want: ErrNicknameTooLong},
+		{name: "accented letters at limit", nickname: strings.Repeat("é", MaxNicknameLength), want: nil},
+		{name: "Cyrillic at limit", nickname: strings.Repeat("я", MaxNicknameLength), want: nil},
+		{name: "Cyrillic over limit", nickname: strings.Repeat("я", MaxNicknameLength+1), want: ErrNicknameTooLong},
+		{name: "emoji at limit", nickname: strings.Repeat("🙂", MaxNicknameLength), want: nil},
+		{name: "emoji over limit", nickname: strings.Repeat("🙂", MaxNicknameLength+1), want: ErrNicknameTooLong},
+		{name: "single symbol", nickname: "🙂", want: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := ValidateNickname(test.nickname); !errors.Is(err, test.want) {
+				t.Errorf("ValidateNickname(%q) is %v, want %v", test.nickname, err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"go_chat_client/protocol"
+
+	"github.com/cockroachdb/errors"
+	"github.com/mitchellh/mapstructure"
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+)
+
+// offlineToken is the token offline transport responds to login with.
+const offlineToken = "offline"
+
+// offlineUsers are the users offline transport lists as online besides the user himself.
+var offlineUsers = []string{"alice", "bob"}
+
+// OfflineTransport represents connection.Transport which doesn't connect anywhere. It answers requests as server
+// would do, echoing posted messages back, which allows to use UI without server.
+type OfflineTransport struct {
+	log       *logrus.Logger
+	mu        sync.Mutex
+	nickname  string
+	lastID    int64
+	responses chan map[string]any
+	closeOnce sync.Once
+	closed    chan struct{}
+	onResp    []func(map[string]any)
+	onType    map[float64][]func(map[string]any)
+}
+
+// NewOfflineTransport returns new offline transport.
+func NewOfflineTransport(log *logrus.Logger) *OfflineTransport {
+	return &OfflineTransport{
+		log:       log,
+		responses: make(chan map[string]any, 64),
+		closed:    make(chan struct{}),
+		onType:    map[float64][]func(map[string]any){},
+	}
+}
+
+// Connect does nothing, since there is nothing to connect to.
+func (t *OfflineTransport) Connect(ctx context.Context) error {
+	return nil
+}
+
+// Listen runs response and type listeners for responses to requests, blocking current goroutine until <ctx> is
+// cancelled or transport is closed.
+func (t *OfflineTransport) Listen(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.closed:
+			return nil
+		case resp := <-t.responses:
+			t.mu.Lock()
+			listeners := append(slices.Clone(t.onResp), t.onType[resp["type"].(float64)]...)
+			t.mu.Unlock()
+			for _, listener := range listeners {
+				listener(resp)
+			}
+		}
+	}
+}
+
+// CloseConn stops Listen. Subsequent calls do nothing.
+func (t *OfflineTransport) CloseConn() {
+	t.closeOnce.Do(func() {
+		close(t.closed)
+	})
+}
+
+// WriteJSON answers <req> as server would do. Requests not affecting client are ignored.
+func (t *OfflineTransport) WriteJSON(req any) error {
+	var fields map[string]any
+	if err := roundTrip(req, &fields); err != nil {
+		return errors.Wrap(err, "Encode request")
+	}
+	var msgType float64
+	if err := mapstructure.Decode(fields["type"], &msgType); err != nil {
+		return errors.Wrap(err, "Decode request type")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch msgType {
+	case protocol.TypeHandshakeReq:
+		t.respond(handshakeResp{Type: protocol.TypeHandshakeResp, Status: protocol.StatusOk, Version: protocol.Version})
+	case protocol.TypeLoginReq:
+		var r protocol.LoginReq
+		if err := mapstructure.Decode(fields, &r); err != nil {
+			return errors.Wrap(err, "Decode login request")
+		}
+		t.nickname = r.Nickname
+		t.respond(protocol.LoginResp{Type: protocol.TypeLoginResp, Token: offlineToken, Status: protocol.StatusOk})
+	case protocol.TypePostMessageReq:
+		var r protocol.PostMsgReq
+		if err := mapstructure.Decode(fields, &r); err != nil {
+			return errors.Wrap(err, "Decode post message request")
+		}
+		t.lastID++
+		t.respond(protocol.PostMsgResp{Type: protocol.TypePostMessageResp, Status: protocol.StatusOk, ID: r.ID})
+		t.respond(protocol.ChatMsgToClient{
+			Type:      protocol.TypeChatMessageToClient,
+			Nickname:  t.nickname,
+			Msg:       r.Msg,
+			Timestamp: time.Now().UnixMilli(),
+			ID:        t.lastID,
+			Room:      r.Room,
+			Action:    r.Action,
+		})
+	case protocol.TypeOnlineUsersReq:
+		users := []any{t.nickname}
+		for _, user := range offlineUsers {
+			users = append(users, user)
+		}
+		t.respond(protocol.OnlineUsers{Type: protocol.TypeOnlineUsers, Status: protocol.StatusOk, Users: users})
+	case protocol.TypeHistoryReq:
+		t.respond(history{Type: protocol.TypeHistory, Status: protocol.StatusOk})
+	case protocol.TypePrivateMessageReq:
+		var r privateMsgReq
+		if err := mapstructure.Decode(fields, &r); err != nil {
+			return errors.Wrap(err, "Decode private message request")
+		}
+		t.respond(privateMsgResp{Type: protocol.TypePrivateMessageResp, Status: protocol.StatusOk, Recipient: r.Recipient})
+	case protocol.TypeReportReq:
+		var r reportReq
+		if err := mapstructure.Decode(fields, &r); err != nil {
+			return errors.Wrap(err, "Decode report request")
+		}
+		t.respond(reportResp{Type: protocol.TypeReportResp, Status: protocol.StatusOk, MsgID: r.MsgID})
+	case protocol.TypeEditReq:
+		var r editReq
+		if err := mapstructure.Decode(fields, &r); err != nil {
+			return errors.Wrap(err, "Decode edit request")
+		}
+		t.respond(msgChangeResp{Type: protocol.TypeEditResp, Status: t.changeStatus(r.ID), ID: r.ID})
+	case protocol.TypeDeleteReq:
+		var r deleteReq
+		if err := mapstructure.Decode(fields, &r); err != nil {
+			return errors.Wrap(err, "Decode delete request")
+		}
+		t.respond(msgChangeResp{Type: protocol.TypeDeleteResp, Status: t.changeStatus(r.ID), ID: r.ID})
+	}
+	return nil
+}
+
+// changeStatus returns status of request to edit or delete message with <id>: only echoed messages exist. Should be
+// called with t.mu locked.
+func (t *OfflineTransport) changeStatus(id int64) float64 {
+	return lo.Ternary(id > 0 && id <= t.lastID, protocol.StatusOk, protocol.StatusMessageNotFound)
+}
+
+// respond queues <resp> to be passed to type listeners by Listen, in form it would be received from server. Should be
+// called with t.mu locked.
+func (t *OfflineTransport) respond(resp any) {
+	var fields map[string]any
+	if err := roundTrip(resp, &fields); err != nil {
+		t.log.WithError(err).Error("Encode offline response")
+		return
+	}
+	select {
+	case t.responses <- fields:
+	default:
+		t.log.Warn("Offline response queue is full, response is dropped")
+	}
+}
+
+// AddOnRespListener registers function <l> to be run when transport responds with any message.
+func (t *OfflineTransport) AddOnRespListener(l func(map[string]any)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onResp = append(t.onResp, l)
+}
+
+// AddOnTypeListener registers function <l> to be run when transport responds with message of type <msgType>.
+func (t *OfflineTransport) AddOnTypeListener(msgType float64, l func(map[string]any)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onType[msgType] = append(t.onType[msgType], l)
+}
+
+// Reconnect does nothing, since offline transport is never disconnected. It returns true.
+func (t *OfflineTransport) Reconnect() bool {
+	t.log.Info("Offline mode, nothing to reconnect")
+	return true
+}
+
+// AddOnDisconnectListener does nothing, since offline transport is never disconnected.
+func (t *OfflineTransport) AddOnDisconnectListener(l func(error)) {}
+
+// roundTrip encodes <src> to JSON and decodes it to <dst>, the same way request travels to server and back.
+func roundTrip(src any, dst any) error {
+	bytes, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, dst)
+}
@@ -0,0 +1,71 @@
+package chat
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"go_chat_client/ui"
+
+	"github.com/samber/lo"
+)
+
+// presence represents nicknames of online users from the last list received from server.
+type presence struct {
+	mu    sync.Mutex
+	known map[string]bool // Nil until the first list is received
+}
+
+// update stores <nicknames> as online users and returns sorted nicknames of users who joined and left since the
+// previous update. The first update, e.g. after login, is a baseline and returns nothing.
+func (p *presence) update(nicknames []string) ([]string, []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	current := lo.SliceToMap(nicknames, func(nickname string) (string, bool) {
+		return nickname, true
+	})
+	isBaseline := p.known == nil
+	previous := p.known
+	p.known = current
+	if isBaseline {
+		return nil, nil
+	}
+	joined := lo.Filter(lo.Keys(current), func(nickname string, _ int) bool {
+		return !previous[nickname]
+	})
+	left := lo.Filter(lo.Keys(previous), func(nickname string, _ int) bool {
+		return !current[nickname]
+	})
+	slices.Sort(joined)
+	slices.Sort(left)
+	return joined, left
+}
+
+// reset forgets online users, so the next update is a baseline again. Used on disconnect, since users who joined or
+// left while client was disconnected can't be told apart.
+func (p *presence) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.known = nil
+}
+
+// printPresenceChanges prints system messages about users from <users> who joined or left since the previous list of
+// online users, if it's enabled in config.
+func (h *Handler) printPresenceChanges(users []ui.OnlineUser) {
+	joined, left := h.presence.update(lo.Map(users, func(user ui.OnlineUser, _ int) string {
+		return user.Nickname
+	}))
+	if !h.cfg.JoinLeaveNotices {
+		return
+	}
+	for _, nickname := range lo.Without(joined, h.cfg.Nickname) {
+		if err := h.ChatUI().PrintToChatBox("", fmt.Sprintf("%v joined", nickname), true, false); err != nil {
+			h.log.WithError(err).Error()
+		}
+	}
+	for _, nickname := range lo.Without(left, h.cfg.Nickname) {
+		if err := h.ChatUI().PrintToChatBox("", fmt.Sprintf("%v left", nickname), true, false); err != nil {
+			h.log.WithError(err).Error()
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package chat
+
+import (
+	"sync"
+	"time"
+)
+
+// represents default rate limit of outgoing messages, used if it's not set in config.
+const (
+	defaultRateLimitMessages = 5
+	defaultRateLimitInterval = time.Second * 10
+)
+
+// rateLimiter represents token bucket limiting rate of outgoing messages. Bucket holds up to <capacity> tokens and is
+// refilled at rate of <capacity> tokens per <interval>.
+type rateLimiter struct {
+	mu       sync.Mutex
+	capacity float64
+	interval time.Duration
+	tokens   float64
+	last     time.Time
+	now      func() time.Time
+}
+
+// newRateLimiter returns new rate limiter allowing <messages> per <interval>, using <now> to get current time.
+func newRateLimiter(messages int, interval time.Duration, now func() time.Time) *rateLimiter {
+	return &rateLimiter{
+		capacity: float64(messages),
+		interval: interval,
+		tokens:   float64(messages),
+		last:     now(),
+		now:      now,
+	}
+}
+
+// allow returns true and takes a token if message can be sent now. It returns false if rate limit is exceeded.
+func (l *rateLimiter) allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	elapsed := now.Sub(l.last)
+	l.last = now
+	l.tokens = min(l.tokens+l.capacity*float64(elapsed)/float64(l.interval), l.capacity)
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
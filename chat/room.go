@@ -0,0 +1,283 @@
+package chat
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"go_chat_client/protocol"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+)
+
+// defaultRoom is the room messages without room name belong to. It's always joined.
+const defaultRoom = ""
+
+// roomMessagesLimit is the maximum amount of messages kept for each room to show when switching to it.
+const roomMessagesLimit = 500
+
+// roomReq represents request to join or leave room to send to server.
+type roomReq struct {
+	Type  float64 `json:"type"`
+	Token string  `json:"token"`
+	Room  string  `json:"room"`
+}
+
+// rooms represents joined chat rooms, the active one shown in chat box and messages received in each of them.
+type rooms struct {
+	mu       sync.Mutex
+	joined   []string
+	active   string
+	messages map[string][]protocol.ChatMsgToClient
+	unread   map[string]int
+}
+
+// add stores <msg> in it's room and returns true if the room is active. Otherwise message is counted as unread.
+// Messages of rooms which are not joined are ignored.
+func (r *rooms) add(msg protocol.ChatMsgToClient) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if msg.Room != defaultRoom && !slices.Contains(r.joined, msg.Room) {
+		return false
+	}
+	if r.messages == nil {
+		r.messages = map[string][]protocol.ChatMsgToClient{}
+		r.unread = map[string]int{}
+	}
+	msgs := append(r.messages[msg.Room], msg)
+	r.messages[msg.Room] = msgs[max(len(msgs)-roomMessagesLimit, 0):]
+	if msg.Room == r.active {
+		return true
+	}
+	r.unread[msg.Room]++
+	return false
+}
+
+// join adds <room> to joined rooms. It returns false if <room> is already joined.
+func (r *rooms) join(room string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if room == defaultRoom || slices.Contains(r.joined, room) {
+		return false
+	}
+	r.joined = append(r.joined, room)
+	return true
+}
+
+// leave removes <room> from joined rooms with it's messages, making default room active if <room> was active. It
+// returns false if <room> is not joined.
+func (r *rooms) leave(room string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !slices.Contains(r.joined, room) {
+		return false
+	}
+	r.joined = lo.Without(r.joined, room)
+	delete(r.messages, room)
+	delete(r.unread, room)
+	if r.active == room {
+		r.active = defaultRoom
+	}
+	return true
+}
+
+// switchTo makes <room> active, resets it's unread count and returns it's messages. It returns false if <room> is not
+// joined.
+func (r *rooms) switchTo(room string) ([]protocol.ChatMsgToClient, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if room != defaultRoom && !slices.Contains(r.joined, room) {
+		return nil, false
+	}
+	r.active = room
+	delete(r.unread, room)
+	return slices.Clone(r.messages[room]), true
+}
+
+// edit replaces text of stored message with <id> with <text>, marking it as edited. It returns false if message is
+// not stored.
+func (r *rooms) edit(id int64, text string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, msgs := range r.messages {
+		if i := slices.IndexFunc(msgs, func(msg protocol.ChatMsgToClient) bool { return msg.ID == id }); i >= 0 {
+			msgs[i].Msg = text
+			msgs[i].Edited = true
+			return true
+		}
+	}
+	return false
+}
+
+// remove removes stored message with <id>. It returns false if message is not stored.
+func (r *rooms) remove(id int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for room, msgs := range r.messages {
+		if i := slices.IndexFunc(msgs, func(msg protocol.ChatMsgToClient) bool { return msg.ID == id }); i >= 0 {
+			r.messages[room] = slices.Delete(msgs, i, i+1)
+			return true
+		}
+	}
+	return false
+}
+
+// current returns active room and total amount of unread messages in other rooms.
+func (r *rooms) current() (string, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.active, lo.Sum(lo.Values(r.unread))
+}
+
+// list returns joined rooms including default one, each followed by amount of unread messages, if any.
+func (r *rooms) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return lo.Map(append([]string{defaultRoom}, r.joined...), func(room string, _ int) string {
+		name := roomName(room)
+		if room == r.active {
+			name += " (active)"
+		}
+		if unread := r.unread[room]; unread > 0 {
+			name += fmt.Sprintf(" - %v unread", unread)
+		}
+		return name
+	})
+}
+
+// joinedRooms returns joined rooms except default one.
+func (r *rooms) joinedRooms() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.joined)
+}
+
+// roomName returns human-readable name of <room>.
+func roomName(room string) string {
+	return "#" + lo.Ternary(room == defaultRoom, "main", room)
+}
+
+// parseRoom returns room name from command <args>, with optional "#" prefix removed. Name of default room is "main".
+func parseRoom(args string) string {
+	room := strings.TrimPrefix(strings.TrimSpace(args), "#")
+	return lo.Ternary(room == "main", defaultRoom, room)
+}
+
+// validateRoom returns error if <room> parsed with parseRoom can't be joined. Room names with spaces are not allowed as
+// commands like /join take room name as the first word.
+func validateRoom(room string) error {
+	if strings.IndexFunc(room, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) != -1 {
+		return errors.Newf("Room %v can't contain spaces or control characters", roomName(room))
+	}
+	return nil
+}
+
+// enterDefaultRoom makes configured default room active, adding it to joined rooms if needed, so it's joined with
+// joinRooms. If the room is invalid, a warning is logged and default room stays active. Used on the first login before
+// history is requested, so messages of the room received with history are shown instead of counted as unread.
+func (h *Handler) enterDefaultRoom() {
+	room := parseRoom(h.cfg.DefaultRoom)
+	if room == defaultRoom {
+		return
+	}
+	if err := validateRoom(room); err != nil {
+		h.log.Warnf("%v, staying in %v", errors.Wrap(err, "Invalid default_room in config"), roomName(defaultRoom))
+		return
+	}
+	h.rooms.join(room)
+	h.rooms.switchTo(room)
+}
+
+// joinRooms sends join requests for configured and joined rooms. Used after login.
+func (h *Handler) joinRooms() {
+	for _, room := range h.cfg.Rooms {
+		h.rooms.join(parseRoom(room))
+	}
+	for _, room := range h.rooms.joinedRooms() {
+		if err := h.conn.WriteJSON(roomReq{Type: protocol.TypeJoinRoomReq, Token: h.token.get(), Room: room}); err != nil {
+			h.log.WithError(err).Error("Send join room request")
+		}
+	}
+	h.showRoom()
+}
+
+// joinRoom joins room in <args> and makes it active.
+func (h *Handler) joinRoom(args string) error {
+	room := parseRoom(args)
+	if room == defaultRoom {
+		return errUsage
+	}
+	if h.rooms.join(room) {
+		if err := h.conn.WriteJSON(roomReq{Type: protocol.TypeJoinRoomReq, Token: h.token.get(), Room: room}); err != nil {
+			h.rooms.leave(room)
+			return errors.Wrap(err, "Send join room request")
+		}
+	}
+	return h.switchRoom(room)
+}
+
+// leaveRoom leaves room in <args>. If it was active, default room becomes active.
+func (h *Handler) leaveRoom(args string) error {
+	room := parseRoom(args)
+	if room == defaultRoom {
+		return errUsage
+	}
+	active, _ := h.rooms.current()
+	if !h.rooms.leave(room) {
+		h.log.Warnf("Room %v is not joined", roomName(room))
+		return nil
+	}
+	if err := h.conn.WriteJSON(roomReq{Type: protocol.TypeLeaveRoomReq, Token: h.token.get(), Room: room}); err != nil {
+		h.log.WithError(err).Error("Send leave room request")
+	}
+	if active == room {
+		return h.switchRoom(defaultRoom)
+	}
+	h.showRoom()
+	return nil
+}
+
+// selectRoom makes room in <args> active or prints list of joined rooms if <args> are empty.
+func (h *Handler) selectRoom(args string) error {
+	if strings.TrimSpace(args) != "" {
+		return h.switchRoom(parseRoom(args))
+	}
+	for _, line := range h.rooms.list() {
+		if err := h.ChatUI().PrintToChatBox("", line, true, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// switchRoom makes <room> active, replacing chat box contents with it's messages. Should be called from UI goroutine.
+func (h *Handler) switchRoom(room string) error {
+	msgs, ok := h.rooms.switchTo(room)
+	if !ok {
+		h.log.Warnf("Room %v is not joined, type /join %v to join it", roomName(room), roomName(room))
+		return nil
+	}
+	if err := h.ChatUI().ResetChatBox(); err != nil {
+		return err
+	}
+	h.printMessages(msgs)
+	h.showRoom()
+	return nil
+}
+
+// showRoom shows active room and amount of unread messages in other rooms in status bar of chat UI, if it's
+// initialized. Nothing is shown if only default room is joined.
+func (h *Handler) showRoom() {
+	if h.ChatUI() == nil {
+		return
+	}
+	if len(h.rooms.joinedRooms()) == 0 {
+		h.ChatUI().SetRoom("", 0)
+		return
+	}
+	active, unread := h.rooms.current()
+	h.ChatUI().SetRoom(roomName(active), unread)
+}
@@ -0,0 +1,78 @@
+package chat
+
+import (
+	"slices"
+	"testing"
+
+	"go_chat_client/config"
+	"go_chat_client/protocol"
+)
+
+// joinedRoomsOf returns rooms of join requests recorded by <transport>.
+func joinedRoomsOf(transport *testTransport) []string {
+	var rooms []string
+	for {
+		select {
+		case req := <-transport.reqs:
+			if req["type"] == protocol.TypeJoinRoomReq {
+				rooms = append(rooms, req["room"].(string))
+			}
+		default:
+			return rooms
+		}
+	}
+}
+
+func TestDefaultRoom(t *testing.T) {
+	tests := []struct {
+		name       string
+		rooms      []string
+		room       string
+		wantActive string
+		wantJoined []string
+	}{
+		{name: "not set", rooms: []string{"random"}, room: "", wantActive: defaultRoom, wantJoined: []string{"random"}},
+		{name: "main", room: "#main", wantActive: defaultRoom},
+		{name: "joined on login", rooms: []string{"random"}, room: "#dev", wantActive: "dev",
+			wantJoined: []string{"dev", "random"}},
+		{name: "configured room", rooms: []string{"dev", "random"}, room: "dev", wantActive: "dev",
+			wantJoined: []string{"dev", "random"}},
+		{name: "invalid", rooms: []string{"random"}, room: "my room", wantActive: defaultRoom,
+			wantJoined: []string{"random"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, transport, _ := newTestHandler(t, &config.Config{Rooms: test.rooms, DefaultRoom: test.room})
+
+			h.PostLogin()
+			if active, _ := h.rooms.current(); active != test.wantActive {
+				t.Errorf("Active room is %q, want %q", active, test.wantActive)
+			}
+			joined := joinedRoomsOf(transport)
+			slices.Sort(joined)
+			if !slices.Equal(joined, test.wantJoined) {
+				t.Errorf("Join requests are sent for %v, want %v", joined, test.wantJoined)
+			}
+		})
+	}
+}
+
+func TestDefaultRoomHistory(t *testing.T) {
+	h, transport, _ := newTestHandler(t, &config.Config{DefaultRoom: "dev"})
+	h.HandleHistory()
+
+	h.PostLogin()
+	transport.deliver(map[string]any{"type": protocol.TypeHistory, "status": protocol.StatusOk, "messages": []any{
+		protocol.ChatMsgToClient{ID: 1, Nickname: "bob", Msg: "main", Timestamp: 1},
+		protocol.ChatMsgToClient{ID: 2, Nickname: "bob", Msg: "dev", Room: "dev", Timestamp: 2},
+	}})
+
+	if active, unread := h.rooms.current(); active != "dev" || unread != 1 {
+		t.Errorf("Active room is %q with %v unread in others, want %q with 1", active, unread, "dev")
+	}
+	h.backlogMu.Lock()
+	defer h.backlogMu.Unlock()
+	if len(h.backlog) != 1 || h.backlog[0].Msg != "dev" {
+		t.Errorf("Backlog to print is %v, want message of default room", h.backlog)
+	}
+}
@@ -0,0 +1,115 @@
+package chat
+
+import (
+	"context"
+	"sync"
+)
+
+// token represents access token received from server on login. It's safe for concurrent use.
+type token struct {
+	mu       sync.Mutex
+	value    string
+	received bool
+	err      error         // Cause of failed login, nil unless login failed since the last reset or retry
+	ready    chan struct{} // Closed once token is received, login failed or token is reset
+}
+
+// get returns access token, or empty string if it's not received since the last reset.
+func (t *token) get() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.value
+}
+
+// set stores access token <value> and wakes up callers of wait. It never blocks, so login responses without anyone
+// waiting for them are fine.
+func (t *token) set(value string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.value = value
+	if !t.received {
+		t.received = true
+		t.wake()
+	}
+	t.err = nil
+}
+
+// fail makes callers of wait return false until retry is called, since login failed with <err>, e.g. server rejected
+// credentials. It does nothing if token is already received.
+func (t *token) fail(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.received {
+		return
+	}
+	if t.err == nil {
+		t.wake()
+	}
+	t.err = err
+}
+
+// failure returns cause of failed login, or nil if login didn't fail since the last reset or retry.
+func (t *token) failure() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// retry clears cause of failed login before logging in again, so token is waited for again.
+func (t *token) retry() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		t.err = nil
+		t.ready = make(chan struct{})
+	}
+}
+
+// reset clears access token, e.g. once connection is lost, so it's waited for again after the next login. Callers of
+// wait still waiting for the previous token are woken up.
+func (t *token) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.received && t.err == nil {
+		t.wake()
+	}
+	t.value, t.received, t.err = "", false, nil
+	t.ready = make(chan struct{})
+}
+
+// wait blocks until access token is received and returns true, or returns false if login fails, reset is called or
+// <ctx> is cancelled first.
+func (t *token) wait(ctx context.Context) bool {
+	t.mu.Lock()
+	ready := t.readyCh()
+	t.mu.Unlock()
+
+	select {
+	case <-ready:
+	case <-ctx.Done():
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.ready == ready && t.received
+}
+
+// wake closes channel callers of wait are waiting on, unless it's closed already, e.g. login response is received
+// after login failed by timeout. Caller must hold the lock.
+func (t *token) wake() {
+	ready := t.readyCh()
+	select {
+	case <-ready:
+	default:
+		close(ready)
+	}
+}
+
+// readyCh returns channel closed once token is received, creating it if needed. Caller must hold the lock.
+func (t *token) readyCh() chan struct{} {
+	if t.ready == nil {
+		t.ready = make(chan struct{})
+	}
+	return t.ready
+}
@@ -0,0 +1,63 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitResult returns result of wait of <tok>, failing the test if it blocks for more than a second.
+func waitResult(t *testing.T, tok *token) bool {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	received := tok.wait(ctx)
+	if ctx.Err() != nil {
+		t.Fatal("wait blocked")
+	}
+	return received
+}
+
+func TestTokenSetAfterFail(t *testing.T) {
+	var tok token
+	failed := errors.New("timeout")
+	tok.fail(failed)
+	if waitResult(t, &tok) {
+		t.Error("wait returned true after login failed")
+	}
+	if err := tok.failure(); !errors.Is(err, failed) {
+		t.Errorf("failure is %v, want %v", err, failed)
+	}
+
+	tok.set("late")
+	if !waitResult(t, &tok) {
+		t.Error("wait returned false once late token is received")
+	}
+	if value := tok.get(); value != "late" {
+		t.Errorf("Token is %v, want late", value)
+	}
+	if err := tok.failure(); err != nil {
+		t.Errorf("failure is %v once token is received, want nil", err)
+	}
+}
+
+func TestTokenRounds(t *testing.T) {
+	var tok token
+	tok.fail(errors.New("first"))
+	tok.fail(errors.New("second"))
+	tok.retry()
+	tok.set("token")
+	tok.set("again")
+	if !waitResult(t, &tok) || tok.get() != "again" {
+		t.Errorf("Token is %q after retry, want again", tok.get())
+	}
+
+	tok.reset()
+	tok.fail(errors.New("rejected"))
+	tok.reset()
+	tok.set("new")
+	if !waitResult(t, &tok) || tok.get() != "new" {
+		t.Errorf("Token is %q after reset, want new", tok.get())
+	}
+}
@@ -0,0 +1,151 @@
+package chat
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// transcriptFlushInterval is the interval between flushes of buffered transcript entries to file.
+const transcriptFlushInterval = time.Second * 5
+
+// transcriptEntry represents message written to transcript.
+type transcriptEntry struct {
+	Time     time.Time `json:"time"`
+	Nickname string    `json:"nickname"`
+	IsSystem bool      `json:"isSystem"`
+	Private  bool      `json:"private,omitempty"`
+	Room     string    `json:"room,omitempty"`
+	Action   bool      `json:"action,omitempty"`
+	Msg      string    `json:"msg"`
+}
+
+// transcript represents file messages are appended to, in JSON Lines format if file extension is ".jsonl" and as plain
+// text otherwise. Entries are buffered and flushed every transcriptFlushInterval and on close.
+type transcript struct {
+	log    *logrus.Logger
+	mu     sync.Mutex
+	file   *os.File
+	w      *bufio.Writer
+	isJSON bool
+	failed bool
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// newTranscript returns transcript appending to file at <path>, creating it if it doesn't exist.
+func newTranscript(log *logrus.Logger, path string) (*transcript, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "Open chat log file")
+	}
+	t := &transcript{
+		log:    log,
+		file:   file,
+		w:      bufio.NewWriter(file),
+		isJSON: filepath.Ext(path) == ".jsonl",
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go t.flushPeriodically()
+	return t, nil
+}
+
+// write appends <entry> to transcript. Write errors are logged once and don't stop the chat.
+func (t *transcript) write(entry transcriptEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var err error
+	if t.isJSON {
+		err = json.NewEncoder(t.w).Encode(entry)
+	} else {
+		_, err = fmt.Fprintln(t.w, formatTranscriptEntry(entry))
+	}
+	t.check(errors.Wrap(err, "Write chat log"))
+}
+
+// formatTranscriptEntry returns <entry> as plain text line, e.g. "2024-01-02 15:04:05 #dev <nick> hello".
+func formatTranscriptEntry(entry transcriptEntry) string {
+	line := entry.Time.Format(time.DateTime)
+	if entry.Room != "" {
+		line += " " + roomName(entry.Room)
+	}
+	switch {
+	case entry.IsSystem:
+		line += " [SYSTEM]"
+	case entry.Action:
+		line += fmt.Sprintf(" * %v", entry.Nickname)
+	case entry.Private:
+		line += fmt.Sprintf(" [PM] <%v>", entry.Nickname)
+	default:
+		line += fmt.Sprintf(" <%v>", entry.Nickname)
+	}
+	return line + " " + entry.Msg
+}
+
+// flushPeriodically flushes transcript every transcriptFlushInterval until transcript is closed.
+func (t *transcript) flushPeriodically() {
+	defer close(t.done)
+	ticker := time.NewTicker(transcriptFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+			t.mu.Lock()
+			t.check(errors.Wrap(t.w.Flush(), "Flush chat log"))
+			t.mu.Unlock()
+		}
+	}
+}
+
+// close flushes buffered entries and closes transcript file.
+func (t *transcript) close() {
+	close(t.stop)
+	<-t.done
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.check(errors.Wrap(t.w.Flush(), "Flush chat log"))
+	t.check(errors.Wrap(t.file.Close(), "Close chat log"))
+}
+
+// check logs <err> if it's not nil, unless error was already logged. Should be called with t.mu locked.
+func (t *transcript) check(err error) {
+	if err == nil || t.failed {
+		return
+	}
+	t.failed = true
+	t.log.WithError(err).Error("Further chat log errors are not shown")
+}
+
+// OpenChatLog starts appending received and sent messages to file at <path>. It returns error if file can't be opened.
+func (h *Handler) OpenChatLog(path string) error {
+	t, err := newTranscript(h.log, path)
+	if err != nil {
+		return err
+	}
+	h.transcript = t
+	return nil
+}
+
+// CloseChatLog writes buffered messages to chat log file and closes it, if it's open.
+func (h *Handler) CloseChatLog() {
+	if h.transcript != nil {
+		h.transcript.close()
+	}
+}
+
+// logToTranscript appends <entry> to chat log, if it's open.
+func (h *Handler) logToTranscript(entry transcriptEntry) {
+	if h.transcript != nil {
+		h.transcript.write(entry)
+	}
+}
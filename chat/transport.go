@@ -0,0 +1,39 @@
+package chat
+
+import (
+	"time"
+
+	"go_chat_client/connection"
+)
+
+// noDiagnostics represents diagnostics of transport which doesn't measure connection quality.
+type noDiagnostics struct{}
+
+func (noDiagnostics) RTT() time.Duration       { return 0 }
+func (noDiagnostics) Reconnects() int          { return 0 }
+func (noDiagnostics) LastDisconnectErr() error { return nil }
+
+// connState returns state of transport, or connection.StateConnected if transport doesn't report it.
+func (h *Handler) connState() connection.State {
+	if r, ok := h.conn.(connection.StateReporter); ok {
+		return r.State()
+	}
+	return connection.StateConnected
+}
+
+// retryDelay returns time to wait before reconnecting, or connection.DefaultRetryDelay if transport doesn't have
+// its own.
+func (h *Handler) retryDelay() time.Duration {
+	if d, ok := h.conn.(connection.RetryDelayer); ok {
+		return d.RetryDelay()
+	}
+	return connection.DefaultRetryDelay
+}
+
+// diagnostics returns connection diagnostics of transport, or zero ones if transport doesn't measure them.
+func (h *Handler) diagnostics() connection.Diagnoser {
+	if d, ok := h.conn.(connection.Diagnoser); ok {
+		return d
+	}
+	return noDiagnostics{}
+}
@@ -0,0 +1,250 @@
+package chat
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"go_chat_client/config"
+	"go_chat_client/connection"
+	"go_chat_client/protocol"
+	"go_chat_client/ui"
+	stdinUtil "go_chat_client/util/stdin"
+
+	"github.com/cockroachdb/errors"
+	"github.com/mitchellh/mapstructure"
+)
+
+// diagTransport represents test transport which also reports state and measures connection quality.
+type diagTransport struct {
+	*testTransport
+}
+
+func (t diagTransport) State() connection.State   { return connection.StateReconnecting }
+func (t diagTransport) RTT() time.Duration        { return time.Millisecond * 42 }
+func (t diagTransport) RetryDelay() time.Duration { return time.Second }
+func (t diagTransport) Reconnects() int           { return 3 }
+func (t diagTransport) LastDisconnectErr() error  { return errors.New("EOF") }
+
+// login runs LoginAndWaitForToken of <h> in background, answers login request sent through <transport> with <resp>
+// and returns the result of login.
+func login(t *testing.T, h *Handler, transport *testTransport, resp protocol.LoginResp) error {
+	t.Helper()
+	h.Prompter = nil
+	h.HandleLoginResponse(func(error) {})
+	done := make(chan error, 1)
+	go func() { done <- h.LoginAndWaitForToken(context.Background()) }()
+
+	req := transport.nextOfType(t, protocol.TypeLoginReq)
+	if req["nickname"] != "alice" {
+		t.Errorf("Login nickname is %v, want alice", req["nickname"])
+	}
+	resp.Type = protocol.TypeLoginResp
+	transport.deliver(resp)
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("Login didn't finish")
+		return nil
+	}
+}
+
+func TestLogin(t *testing.T) {
+	transport := newTestTransport()
+	h := NewHandler(testLogger(), &config.Config{Nickname: "alice"}, transport)
+
+	if err := login(t, h, transport, protocol.LoginResp{Status: protocol.StatusOk, Token: "secret"}); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if token := h.token.get(); token != "secret" {
+		t.Errorf("Token is %v, want secret", token)
+	}
+}
+
+func TestLoginRejected(t *testing.T) {
+	transport := newTestTransport()
+	h := NewHandler(testLogger(), &config.Config{Nickname: "alice"}, transport)
+
+	err := login(t, h, transport, protocol.LoginResp{Status: protocol.StatusNameAlreadyTaken})
+	if !errors.Is(err, ErrNicknameRejected) {
+		t.Errorf("Login error is %v, want %v", err, ErrNicknameRejected)
+	}
+	if token := h.token.get(); token != "" {
+		t.Errorf("Token is %v after rejected login, want none", token)
+	}
+}
+
+func TestLoginCancelled(t *testing.T) {
+	transport := newTestTransport()
+	h := NewHandler(testLogger(), &config.Config{Nickname: "alice"}, transport)
+	h.Prompter = nil
+	ctx, cancel := context.WithCancelCause(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- h.LoginAndWaitForToken(ctx) }()
+
+	transport.nextOfType(t, protocol.TypeLoginReq)
+	// Login prompt is cancelled by user before server responds
+	cancel(stdinUtil.ErrCancelled)
+	select {
+	case err := <-done:
+		if !errors.Is(err, stdinUtil.ErrCancelled) {
+			t.Errorf("Login error is %v, want %v", err, stdinUtil.ErrCancelled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Login didn't finish once cancelled")
+	}
+	if token := h.token.get(); token != "" {
+		t.Errorf("Token is %v after cancelled login, want none", token)
+	}
+}
+
+// tokenAuthenticator represents stub authenticator logging in with API token instead of nickname.
+type tokenAuthenticator struct {
+	logins int
+}
+
+// Login sends login request with API token. Used to implement Authenticator interface.
+func (a *tokenAuthenticator) Login(conn connection.Transport) error {
+	a.logins++
+	return conn.WriteJSON(map[string]any{"type": protocol.TypeLoginReq, "apiToken": "api-token"})
+}
+
+func TestLoginWithAuthenticator(t *testing.T) {
+	transport := newTestTransport()
+	h := NewHandler(testLogger(), &config.Config{Nickname: "alice"}, transport)
+	auth := &tokenAuthenticator{}
+	h.Authenticator = auth
+	h.Prompter = nil
+	h.HandleLoginResponse(func(error) {})
+	done := make(chan error, 1)
+	go func() { done <- h.LoginAndWaitForToken(context.Background()) }()
+
+	req := transport.nextOfType(t, protocol.TypeLoginReq)
+	if req["apiToken"] != "api-token" || req["nickname"] != nil {
+		t.Errorf("Login request is %v, want one sent by authenticator", req)
+	}
+	transport.deliver(protocol.LoginResp{Type: protocol.TypeLoginResp, Status: protocol.StatusOk, Token: "secret"})
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Login didn't finish")
+	}
+	if token := h.token.get(); token != "secret" || auth.logins != 1 {
+		t.Errorf("Token is %v after %v logins, want secret after 1", token, auth.logins)
+	}
+}
+
+func TestOnlineUsers(t *testing.T) {
+	h, transport, _ := newTestHandler(t, &config.Config{Nickname: "alice"})
+	received := make(chan []ui.OnlineUser, 1)
+	transport.AddOnTypeListener(protocol.TypeOnlineUsers, func(resp map[string]any) {
+		var r protocol.OnlineUsers
+		if err := mapstructure.Decode(resp, &r); err != nil {
+			t.Errorf("Decode online users: %v", err)
+		}
+		users, err := decodeOnlineUsers(r.Users)
+		if err != nil {
+			t.Errorf("Decode online users: %v", err)
+		}
+		received <- users
+	})
+
+	h.RequestOnlineUsers()
+	req := transport.nextOfType(t, protocol.TypeOnlineUsersReq)
+	if req["token"] != "token" {
+		t.Errorf("Online users request token is %v, want token", req["token"])
+	}
+	transport.deliver(protocol.OnlineUsers{Type: protocol.TypeOnlineUsers, Status: protocol.StatusOk, Users: []any{
+		"alice",
+		protocol.OnlineUser{Nickname: "bob", Idle: 90, Role: "admin", Away: true},
+	}})
+
+	want := []ui.OnlineUser{{Nickname: "alice"}, {Nickname: "bob", Idle: time.Second * 90, Role: "admin", Away: true}}
+	if users := <-received; !slices.Equal(users, want) {
+		t.Errorf("Online users are %v, want %v", users, want)
+	}
+}
+
+func TestNetDiagWithoutDiagnostics(t *testing.T) {
+	h, _, _ := newTestHandler(t, &config.Config{})
+
+	want := []string{
+		"Connection state: Connected",
+		"Round-trip time: not measured yet",
+		"Reconnects: 0",
+		"Last disconnect reason: none",
+		"Messages waiting for confirmation: 0",
+	}
+	if lines := h.netDiag().lines(); !slices.Equal(lines, want) {
+		t.Errorf("Diagnostics are %q, want %q", lines, want)
+	}
+	if delay := h.retryDelay(); delay != connection.DefaultRetryDelay {
+		t.Errorf("Retry delay is %v, want %v", delay, connection.DefaultRetryDelay)
+	}
+}
+
+func TestNetDiagFromTransport(t *testing.T) {
+	h := NewHandler(testLogger(), &config.Config{}, diagTransport{newTestTransport()})
+
+	want := []string{
+		"Connection state: Reconnecting",
+		"Round-trip time: 42ms",
+		"Reconnects: 3",
+		"Last disconnect reason: EOF",
+		"Messages waiting for confirmation: 0",
+	}
+	if lines := h.netDiag().lines(); !slices.Equal(lines, want) {
+		t.Errorf("Diagnostics are %q, want %q", lines, want)
+	}
+	if delay := h.retryDelay(); delay != time.Second {
+		t.Errorf("Retry delay is %v, want %v", delay, time.Second)
+	}
+}
+
+func TestStart(t *testing.T) {
+	tests := []struct {
+		name      string
+		handshake bool
+	}{
+		{name: "without handshake"},
+		{name: "with handshake", handshake: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, transport, _ := newTestHandler(t, &config.Config{Nickname: "alice", Handshake: tt.handshake})
+			h.token.reset()
+			h.Prompter = nil
+			h.HandleAll(context.Background(), func(error) {})
+			done := make(chan error, 1)
+			go func() { done <- h.Start(context.Background()) }()
+
+			if tt.handshake {
+				if req := transport.next(t); req["type"] != protocol.TypeHandshakeReq {
+					t.Fatalf("The first request is %v, want handshake request", req)
+				}
+				transport.deliver(handshakeResp{Type: protocol.TypeHandshakeResp, Status: protocol.StatusOk,
+					Version: protocol.Version})
+			}
+			if req := transport.next(t); req["type"] != protocol.TypeLoginReq {
+				t.Fatalf("Request is %v, want login request", req)
+			}
+			transport.deliver(protocol.LoginResp{Type: protocol.TypeLoginResp, Status: protocol.StatusOk, Token: "secret"})
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatalf("Start returned %v", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Start didn't finish")
+			}
+			if req := transport.next(t); req["type"] != protocol.TypeHistoryReq {
+				t.Errorf("Request after login is %v, want history request", req)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package chat
+
+import (
+	"fmt"
+
+	"go_chat_client/connection"
+
+	"github.com/samber/lo"
+)
+
+// localState represents snapshot of local client state shown by /whoami and /status commands.
+type localState struct {
+	nickname   string
+	server     string
+	tls        *bool // Nil if TLS mode is not set, e.g. in offline mode
+	state      connection.State
+	hasToken   bool
+	pending    int
+	unread     int
+	reconnects int
+}
+
+// identityLines returns nickname, server and connection state formatted as lines to print to chat box.
+func (s localState) identityLines() []string {
+	tls := "not set"
+	if s.tls != nil {
+		tls = lo.Ternary(*s.tls, "on", "off")
+	}
+	return []string{
+		fmt.Sprintf("Nickname: %v", s.nickname),
+		fmt.Sprintf("Server: %v", s.server),
+		fmt.Sprintf("TLS: %v", tls),
+		fmt.Sprintf("Connection state: %v", s.state),
+		fmt.Sprintf("Logged in: %v", lo.Ternary(s.hasToken, "yes", "no")),
+	}
+}
+
+// statusLines returns identity lines followed by amounts of queued and unread messages and reconnects.
+func (s localState) statusLines() []string {
+	return append(s.identityLines(),
+		fmt.Sprintf("Messages waiting for confirmation: %v", s.pending),
+		fmt.Sprintf("Unread messages: %v", s.unread),
+		fmt.Sprintf("Reconnects: %v", s.reconnects),
+	)
+}
+
+// localState returns snapshot of local client state.
+func (h *Handler) localState() localState {
+	return localState{
+		nickname:   h.cfg.Nickname,
+		server:     h.cfg.ServerAddress,
+		tls:        h.cfg.TLSMode,
+		state:      h.connState(),
+		hasToken:   h.token.get() != "",
+		pending:    h.pending.len(),
+		unread:     h.ChatUI().Unread(),
+		reconnects: h.diagnostics().Reconnects(),
+	}
+}
+
+// showWhoami prints own nickname, server and connection state to chat box.
+func (h *Handler) showWhoami(args string) error {
+	return h.printSystemLines(h.localState().identityLines())
+}
+
+// showStatus prints local client state, including amounts of queued and unread messages, to chat box.
+func (h *Handler) showStatus(args string) error {
+	return h.printSystemLines(h.localState().statusLines())
+}
+
+// printSystemLines prints each of <lines> to chat box as system message.
+func (h *Handler) printSystemLines(lines []string) error {
+	for _, line := range lines {
+		if err := h.ChatUI().PrintToChatBox("", line, true, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
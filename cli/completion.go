@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/samber/lo"
+)
+
+// programName is the name of executable to complete flags for.
+const programName = "go_chat_client"
+
+// flag represents command line flag to complete.
+type flag struct {
+	short       string
+	long        string
+	description string
+	hasValue    bool
+	choices     []string
+}
+
+// CompletionScript returns script completing command line flags in <shell>, which can be "bash", "zsh" or "fish".
+func CompletionScript(shell string) (string, error) {
+	flags := completionFlags()
+	switch shell {
+	case "bash":
+		return bashCompletion(flags), nil
+	case "zsh":
+		return zshCompletion(flags), nil
+	case "fish":
+		return fishCompletion(flags), nil
+	default:
+		return "", errors.Newf("Unknown shell '%v', it should be bash, zsh or fish", shell)
+	}
+}
+
+// completionFlags returns command line flags, taken from Flags structure.
+func completionFlags() []flag {
+	var flags []flag
+	parser := newParser(&Flags{})
+	// Help flag is added on parsing
+	if _, err := parser.ParseArgs(nil); err != nil {
+		return nil
+	}
+	for _, group := range parser.Groups() {
+		for _, option := range group.Options() {
+			if option.Hidden {
+				continue
+			}
+			_, isBool := option.Value().(bool)
+			flags = append(flags, flag{
+				short:       lo.Ternary(option.ShortName != 0, string(option.ShortName), ""),
+				long:        option.LongName,
+				description: option.Description,
+				hasValue:    !isBool,
+				choices:     option.Choices,
+			})
+		}
+	}
+	return flags
+}
+
+// bashCompletion returns bash completion script for <flags>. Values of flags without choices are completed as file
+// names.
+func bashCompletion(flags []flag) string {
+	var names, cases []string
+	for _, f := range flags {
+		names = append(names, "--"+f.long)
+		if f.short != "" {
+			names = append(names, "-"+f.short)
+		}
+		if len(f.choices) > 0 {
+			cases = append(cases, fmt.Sprintf("        --%v) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;", f.long,
+				strings.Join(f.choices, " ")))
+		} else if f.hasValue {
+			cases = append(cases, fmt.Sprintf("        --%v) return ;;", f.long))
+		}
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "_%v() {\n", programName)
+	sb.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	sb.WriteString("    case \"$prev\" in\n")
+	sb.WriteString(strings.Join(cases, "\n") + "\n")
+	sb.WriteString("    esac\n")
+	fmt.Fprintf(&sb, "    COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+	sb.WriteString("}\n")
+	fmt.Fprintf(&sb, "complete -o default -F _%v %v\n", programName, programName)
+	return sb.String()
+}
+
+// zshCompletion returns zsh completion script for <flags>.
+func zshCompletion(flags []flag) string {
+	escape := strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`)
+	var specs []string
+	for _, f := range flags {
+		action := ""
+		if len(f.choices) > 0 {
+			action = fmt.Sprintf(":value:(%v)", strings.Join(f.choices, " "))
+		} else if f.hasValue {
+			action = ":value:_files"
+		}
+		description := escape.Replace(f.description)
+		specs = append(specs, fmt.Sprintf("'--%v%v[%v]%v'", f.long, lo.Ternary(f.hasValue, "=", ""), description,
+			action))
+		if f.short != "" {
+			specs = append(specs, fmt.Sprintf("'-%v%v[%v]%v'", f.short, lo.Ternary(f.hasValue, "+", ""), description,
+				action))
+		}
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#compdef %v\n", programName)
+	fmt.Fprintf(&sb, "_%v() {\n", programName)
+	sb.WriteString("    _arguments \\\n        " + strings.Join(specs, " \\\n        ") + "\n")
+	sb.WriteString("}\n")
+	fmt.Fprintf(&sb, "compdef _%v %v\n", programName, programName)
+	return sb.String()
+}
+
+// fishCompletion returns fish completion script for <flags>.
+func fishCompletion(flags []flag) string {
+	escape := strings.NewReplacer(`\`, `\\`, "'", `\'`)
+	var sb strings.Builder
+	for _, f := range flags {
+		fmt.Fprintf(&sb, "complete -c %v", programName)
+		if f.short != "" {
+			fmt.Fprintf(&sb, " -s %v", f.short)
+		}
+		fmt.Fprintf(&sb, " -l %v -d '%v'", f.long, escape.Replace(f.description))
+		if len(f.choices) > 0 {
+			fmt.Fprintf(&sb, " -x -a '%v'", strings.Join(f.choices, " "))
+		} else if f.hasValue {
+			sb.WriteString(" -r")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -3,23 +3,62 @@ package cli
 import (
 	"github.com/cockroachdb/errors"
 	goFlags "github.com/jessevdk/go-flags"
-	"github.com/sirupsen/logrus"
 )
 
 // Flags represents command line flags.
 type Flags struct {
-	Version  bool         `short:"v" long:"version"  description:"Print the program version"`
-	LogLevel logrus.Level `short:"l" long:"logLevel" description:"Logging level. Can be from 0 (least verbose) to 6 (most verbose)"`
+	Version    bool   `short:"v" long:"version"   description:"Print the program version"`
+	LogLevel   string `short:"l" long:"log-level" description:"Logging level: trace, debug, info, warn, error or fatal, overriding config"`
+	OldLevel   string `long:"logLevel"            description:"Deprecated alias of --log-level" hidden:"true"`
+	Quiet      bool   `short:"q" long:"quiet"     description:"Show only warnings and errors, log file still gets more verbose levels"`
+	Insecure   bool   `long:"insecure"            description:"Skip TLS certificate verification. Use only for self-signed certificates"`
+	Invite     string `long:"invite"              description:"One-time invite token for invite-only servers"`
+	Proxy      string `long:"proxy"               description:"Proxy to connect through, e.g. 'socks5://host:port'"`
+	ClientCert string `long:"client-cert"         description:"PEM client certificate for servers requiring mutual TLS, overriding config"`
+	ClientKey  string `long:"client-key"          description:"PEM private key of client certificate, overriding config"`
+	CACert     string `long:"ca-cert"             description:"PEM bundle of CA certificates to verify server, overriding config"`
+	Message    string `long:"message"             description:"Post message, wait for server confirmation and exit without starting the UI"`
+	Once       bool   `long:"once"                description:"Try to connect only once instead of retrying until success"`
+	RetryDelay string `long:"reconnect-delay"     description:"Seconds to wait between attempts to connect, e.g. 0.1 on LAN, overriding config"`
+	LogFile    string `long:"log-file"            description:"Log file, rotated by size. Empty to disable"`
+	LogFormat  string `long:"log-format"          description:"Format of log outside of chat box" choice:"text" choice:"json"`
+	Password   bool   `long:"password"            description:"Ask for password to log in with. It's not saved to config"`
+	Server     string `long:"server"              description:"Server address in format of 'host:port', overriding config"`
+	Nickname   string `long:"nickname"            description:"User name to login with, overriding config"`
+	TLS        bool   `long:"tls"                 description:"Connect to server using TLS protocol, overriding config"`
+	NoTLS      bool   `long:"no-tls"              description:"Connect to server without TLS protocol, overriding config"`
+	ChatLog    string `long:"chat-log"            description:"File to append chat messages to, in JSON Lines format if it ends with .jsonl"`
+	Offline    bool   `long:"offline"             description:"Do not connect to server, echo posted messages back. Useful to try the UI"`
+	NoColor    bool   `long:"no-color"            description:"Do not use colors, same as setting NO_COLOR environment variable"`
+	Completion string `long:"completion"          description:"Print completion script for shell and exit" choice:"bash" choice:"zsh" choice:"fish"`
+}
+
+// TLSMode returns TLS mode set by --tls or --no-tls flag, or nil if none of them is set.
+func (f Flags) TLSMode() *bool {
+	if !f.TLS && !f.NoTLS {
+		return nil
+	}
+	return &f.TLS
 }
 
 // Parse returns a structure initialized with command line arguments and error if parsing failed.
 func Parse() (Flags, error) {
-	flags := Flags{LogLevel: logrus.InfoLevel} // Set defaults
-	parser := goFlags.NewParser(&flags, goFlags.Options(goFlags.Default))
-	_, err := parser.Parse()
+	flags := Flags{LogFile: "go_chat_client.log", LogFormat: "text"} // Set defaults
+	_, err := newParser(&flags).Parse()
+	if flags.LogLevel == "" {
+		flags.LogLevel = flags.OldLevel
+	}
+	if err == nil && flags.TLS && flags.NoTLS {
+		err = errors.New("Flags --tls and --no-tls can't be used together")
+	}
 	return flags, errors.Wrap(err, "Parse CLI arguments")
 }
 
+// newParser returns parser of command line arguments into <flags>.
+func newParser(flags *Flags) *goFlags.Parser {
+	return goFlags.NewParser(flags, goFlags.Options(goFlags.Default))
+}
+
 // IsErrOfType returns true if <err> is of type <t>.
 func IsErrOfType(err error, t goFlags.ErrorType) bool {
 	goFlagsErr := &goFlags.Error{}
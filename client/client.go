@@ -0,0 +1,267 @@
+// Package client provides chat client without UI, which connects to server, logs in, posts and receives messages. It
+// can be embedded into other programs. It's built on chat.Handler, so messages are confirmed and retried, and
+// connection is restored the same way as in chat UI.
+package client
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go_chat_client/chat"
+	"go_chat_client/config"
+	"go_chat_client/connection"
+	"go_chat_client/protocol"
+
+	"github.com/cockroachdb/errors"
+	"github.com/mitchellh/mapstructure"
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultTimeout is the maximum time to wait for server response, used if it's not set in Options.
+const defaultTimeout = time.Second * 10
+
+// ErrNicknameRejected is returned by Client.Login if server rejects nickname, e.g. because it's taken.
+var ErrNicknameRejected = chat.ErrNicknameRejected
+
+// ErrAuthFailed is returned by Client.Login if server requires password and it's missing or wrong.
+var ErrAuthFailed = chat.ErrAuthFailed
+
+// ErrIncompatibleProtocol is returned by Client.Connect if Options.Handshake is set and server doesn't support
+// protocol version of client.
+var ErrIncompatibleProtocol = chat.ErrIncompatibleProtocol
+
+// Options represents client settings.
+type Options struct {
+	Connection       connection.Options // Connection settings, used only if client is created with New
+	Nickname         string             // User name to login with on Client.Connect
+	Password         string             // Password to login with on Client.Connect, if server requires it
+	MaxMessageLength int                // Maximum amount of symbols in message. If 0, config.DefaultMaxMessageLength is used
+	Timeout          time.Duration      // Maximum time to wait for server response. If 0, defaultTimeout is used
+	Handshake        bool               // Check protocol version with server on Client.Connect, see chat.Handler.Handshake
+}
+
+// Message represents chat message received from server.
+type Message struct {
+	ID       int64 // Assigned by server, 0 if server doesn't support message IDs
+	Nickname string
+	Text     string
+	Time     time.Time
+	Room     string // Empty for the main room
+	IsSystem bool
+	IsAction bool
+}
+
+// Client represents chat client without UI. Its methods are safe for concurrent use, except Login, which should not
+// be called while connection is being restored.
+type Client struct {
+	log       *logrus.Logger
+	conn      connection.Transport
+	opts      Options
+	cfg       *config.Config
+	handler   *chat.Handler
+	keepAlive func(ctx context.Context) // Sends keepalive pings, nil if transport is not owned by client
+	cancel    context.CancelCauseFunc
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	onMessage []func(Message)
+	onlineMu  sync.Mutex // Serializes online users requests, since responses don't reference them
+	online    chan protocol.OnlineUsers
+}
+
+// New returns new client connecting to server at <addr> in form of 'host:port' with settings <opts>. It returns error
+// if connection settings are invalid.
+func New(log *logrus.Logger, addr string, opts Options) (*Client, error) {
+	conn, err := connection.NewHandler(log, addr, opts.Connection)
+	if err != nil {
+		return nil, err
+	}
+	c := NewWithTransport(log, conn, opts)
+	c.keepAlive = conn.KeepAlive
+	return c, nil
+}
+
+// NewWithTransport returns new client communicating through <conn> with settings <opts>. Options.Connection is
+// ignored. <conn> can be already connected, but should not be listened to yet.
+func NewWithTransport(log *logrus.Logger, conn connection.Transport, opts Options) *Client {
+	cfg := &config.Config{Nickname: opts.Nickname, MaxMessageLength: opts.MaxMessageLength, Handshake: opts.Handshake}
+	c := &Client{
+		log:     log,
+		conn:    conn,
+		opts:    opts,
+		cfg:     cfg,
+		handler: chat.NewHandler(log, cfg, conn),
+		online:  make(chan protocol.OnlineUsers, 1),
+	}
+	// Rejected credentials fail Login instead of being asked in terminal
+	c.handler.Prompter = nil
+	c.handler.Password = opts.Password
+	c.handler.AddOnMessageListener(c.handleMessage)
+	c.handler.HandleHandshake()
+	c.handler.HandleChatMsgToClient()
+	c.handler.HandlePostMessageResponse()
+	conn.AddOnTypeListener(protocol.TypeOnlineUsers, c.handleOnlineUsers)
+	return c
+}
+
+// Connect connects to server unless transport is connected already, starts listening for messages, checks protocol
+// version if Options.Handshake is set and logs in with Options.Nickname and Options.Password. If connection is lost
+// later, it's restored and client logs in again, messages which were not confirmed are sent again. It returns error if
+// connection can't be established, server uses incompatible protocol (ErrIncompatibleProtocol) or login failed,
+// e.g. ErrNicknameRejected. In the latter case, login can be retried with Login.
+func (c *Client) Connect(ctx context.Context) error {
+	if r, ok := c.conn.(connection.StateReporter); !ok || r.State() != connection.StateConnected {
+		if err := c.conn.Connect(ctx); err != nil {
+			return err
+		}
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	c.mu.Lock()
+	c.cancel = cancel
+	c.mu.Unlock()
+	c.handler.HandleOnDisconnect(ctx)
+	c.handler.HandleLoginResponse(cancel)
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		if err := c.conn.Listen(ctx); err != nil {
+			c.log.WithError(err).Error()
+		}
+	}()
+	if c.keepAlive != nil {
+		go c.keepAlive(ctx)
+	}
+
+	if err := c.handler.CheckProtocol(); err != nil {
+		return err
+	}
+	return c.Login(c.opts.Nickname, c.opts.Password)
+}
+
+// Login logs in with <nickname> and <password> and blocks until server responds. It returns ErrNicknameRejected or
+// ErrAuthFailed if server rejects credentials, or other error if server didn't respond within Options.Timeout.
+// Successful credentials are used to log in again after reconnect.
+func (c *Client) Login(nickname string, password string) error {
+	previousNickname, previousPassword := c.cfg.Nickname, c.handler.Password
+	c.cfg.Nickname, c.handler.Password = nickname, password
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout())
+	defer cancel()
+	err := c.handler.LoginAndWaitForToken(ctx)
+	if err == nil {
+		return nil
+	}
+	c.cfg.Nickname, c.handler.Password = previousNickname, previousPassword
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errors.Newf("Login response was not received within %v", c.timeout())
+	}
+	return err
+}
+
+// Send posts <msg> to the main room and blocks until server confirms it. Message is sent again if it's not confirmed
+// in time or connection is lost. It returns error if message is too long, too many messages are sent in a short time,
+// server rejects message or didn't confirm it within Options.Timeout.
+func (c *Client) Send(msg string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout())
+	defer cancel()
+	err := c.handler.PostMessageAndWait(ctx, msg)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errors.Newf("Post message response was not received within %v", c.timeout())
+	}
+	return err
+}
+
+// OnMessage registers function <l> to be run when chat message is received. Listeners are run in the order of
+// registration, one message at a time.
+func (c *Client) OnMessage(l func(Message)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onMessage = append(c.onMessage, l)
+}
+
+// OnlineUsers requests list of online users and returns their nicknames. It returns error if server rejects request
+// or didn't respond within Options.Timeout.
+func (c *Client) OnlineUsers() ([]string, error) {
+	c.onlineMu.Lock()
+	defer c.onlineMu.Unlock()
+	select {
+	case <-c.online: // Drop list pushed by server nobody waited for
+	default:
+	}
+	c.handler.RequestOnlineUsers()
+
+	var r protocol.OnlineUsers
+	select {
+	case r = <-c.online:
+	case <-time.After(c.timeout()):
+		return nil, errors.Newf("Online users response was not received within %v", c.timeout())
+	}
+	if r.Status != protocol.StatusOk {
+		return nil, errors.Newf("Get online users failed, status: %v", r.Status)
+	}
+	users, err := protocol.DecodeOnlineUsers(r.Users)
+	if err != nil {
+		return nil, err
+	}
+	return lo.Map(users, func(u protocol.OnlineUser, _ int) string {
+		return u.Nickname
+	}), nil
+}
+
+// Flush blocks until server confirms all messages being sent with Send and returns true, or returns false if some of
+// them are still not confirmed after <timeout>.
+func (c *Client) Flush(timeout time.Duration) bool {
+	return c.handler.Flush(timeout)
+}
+
+// Close stops listening for messages and closes connection to server. Client can't be used after that.
+func (c *Client) Close() {
+	c.mu.Lock()
+	cancel := c.cancel
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel(nil)
+	}
+	c.conn.CloseConn()
+	c.wg.Wait()
+}
+
+// handleMessage runs message listeners for chat message <r>.
+func (c *Client) handleMessage(r protocol.ChatMsgToClient) {
+	msg := Message{
+		ID:       r.ID,
+		Nickname: r.Nickname,
+		Text:     r.Msg,
+		Time:     lo.Ternary(r.Timestamp != 0, time.UnixMilli(r.Timestamp), time.Now()),
+		Room:     r.Room,
+		IsSystem: r.IsSystem,
+		IsAction: r.Action,
+	}
+	c.mu.Lock()
+	listeners := slices.Clone(c.onMessage)
+	c.mu.Unlock()
+	for _, listener := range listeners {
+		listener(msg)
+	}
+}
+
+// handleOnlineUsers passes online users response <resp> to OnlineUsers, if it waits for it.
+func (c *Client) handleOnlineUsers(resp map[string]any) {
+	var r protocol.OnlineUsers
+	if err := mapstructure.Decode(resp, &r); err != nil {
+		c.log.WithError(err).Error("Decode online users response")
+		return
+	}
+	select {
+	case c.online <- r:
+	default:
+	}
+}
+
+// timeout returns maximum time to wait for server response.
+func (c *Client) timeout() time.Duration {
+	return lo.Ternary(c.opts.Timeout > 0, c.opts.Timeout, defaultTimeout)
+}
@@ -0,0 +1,311 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"go_chat_client/protocol"
+	"go_chat_client/util/wstest"
+
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+)
+
+// newTestClient returns client of <srv> logging in as alice, not connected yet. Client is closed once test finishes.
+func newTestClient(t *testing.T, srv *wstest.Server) *Client {
+	t.Helper()
+	return newTestClientWithOptions(t, srv, Options{})
+}
+
+// newTestClientWithOptions returns client like newTestClient with other settings taken from <opts>.
+func newTestClientWithOptions(t *testing.T, srv *wstest.Server, opts Options) *Client {
+	t.Helper()
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	opts.Connection.RetryDelay = lo.ToPtr(time.Millisecond * 10)
+	opts.Nickname = "alice"
+	opts.Timeout = wstest.Timeout
+	c, err := New(log, srv.Addr(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+// async runs <f> in new goroutine and returns channel receiving its result.
+func async(f func() error) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		result <- f()
+	}()
+	return result
+}
+
+// wait returns result received by <result>, failing the test if it's not received within wstest.Timeout.
+func wait(t *testing.T, result <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(wstest.Timeout):
+		t.Fatalf("Nothing received within %v", wstest.Timeout)
+		return nil
+	}
+}
+
+// login answers login request of client on <conn> with <status>, and returns the request.
+func login(conn *wstest.Conn, status float64) map[string]any {
+	req := conn.ReadType(protocol.TypeLoginReq)
+	conn.Write(map[string]any{"type": protocol.TypeLoginResp, "status": status, "token": "secret"})
+	return req
+}
+
+// connect returns client of <srv> logged in as alice, and connection of it accepted by <srv>.
+func connect(t *testing.T, srv *wstest.Server) (*Client, *wstest.Conn) {
+	t.Helper()
+	c := newTestClient(t, srv)
+	connected := async(func() error {
+		return c.Connect(context.Background())
+	})
+	conn := srv.Accept()
+	login(conn, protocol.StatusOk)
+	if err := wait(t, connected); err != nil {
+		t.Fatal(err)
+	}
+	return c, conn
+}
+
+func TestConnect(t *testing.T) {
+	srv := wstest.NewServer(t)
+	c := newTestClient(t, srv)
+	connected := async(func() error {
+		return c.Connect(context.Background())
+	})
+
+	req := login(srv.Accept(), protocol.StatusOk)
+	if err := wait(t, connected); err != nil {
+		t.Fatal(err)
+	}
+	if req["nickname"] != "alice" {
+		t.Errorf("Login request nickname is %v, want alice", req["nickname"])
+	}
+}
+
+func TestConnectWithoutHandshake(t *testing.T) {
+	srv := wstest.NewServer(t)
+	c := newTestClient(t, srv)
+	connected := async(func() error {
+		return c.Connect(context.Background())
+	})
+
+	conn := srv.Accept()
+	if req := conn.Read(); req["type"] != protocol.TypeLoginReq {
+		t.Errorf("The first request is %v, want login request without handshake", req)
+	}
+	conn.Write(map[string]any{"type": protocol.TypeLoginResp, "status": protocol.StatusOk, "token": "secret"})
+	if err := wait(t, connected); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConnectHandshake(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		want    error
+	}{
+		{name: "compatible", version: protocol.Version},
+		{name: "incompatible", version: protocol.Version + 1, want: ErrIncompatibleProtocol},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := wstest.NewServer(t)
+			c := newTestClientWithOptions(t, srv, Options{Handshake: true})
+			connected := async(func() error {
+				return c.Connect(context.Background())
+			})
+
+			conn := srv.Accept()
+			if req := conn.Read(); req["type"] != protocol.TypeHandshakeReq {
+				t.Fatalf("The first request is %v, want handshake request", req)
+			}
+			conn.Write(map[string]any{"type": protocol.TypeHandshakeResp, "status": protocol.StatusOk,
+				"version": tt.version})
+			if tt.want == nil {
+				login(conn, protocol.StatusOk)
+			}
+			if err := wait(t, connected); !errors.Is(err, tt.want) {
+				t.Errorf("Connect returned %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		status float64
+		want   error
+	}{
+		{name: "nickname taken", status: protocol.StatusNameAlreadyTaken, want: ErrNicknameRejected},
+		{name: "wrong password", status: protocol.StatusAuthFailed, want: ErrAuthFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := wstest.NewServer(t)
+			c := newTestClient(t, srv)
+			connected := async(func() error {
+				return c.Connect(context.Background())
+			})
+			conn := srv.Accept()
+			login(conn, tt.status)
+			if err := wait(t, connected); !errors.Is(err, tt.want) {
+				t.Fatalf("Connect returned %v, want %v", err, tt.want)
+			}
+
+			loggedIn := async(func() error {
+				return c.Login("bob", "password")
+			})
+			req := login(conn, protocol.StatusOk)
+			if err := wait(t, loggedIn); err != nil {
+				t.Fatalf("Login with other credentials: %v", err)
+			}
+			if req["nickname"] != "bob" || req["password"] != "password" {
+				t.Errorf("Login request is %v, want nickname bob and password", req)
+			}
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	srv := wstest.NewServer(t)
+	c, conn := connect(t, srv)
+
+	sent := async(func() error {
+		return c.Send("hi")
+	})
+	req := conn.ReadType(protocol.TypePostMessageReq)
+	if req["msg"] != "hi" || req["token"] != "secret" {
+		t.Errorf("Post message request is %v, want message hi with token", req)
+	}
+	select {
+	case err := <-sent:
+		t.Fatalf("Send returned %v before message is confirmed", err)
+	case <-time.After(time.Millisecond * 50):
+	}
+	conn.Write(map[string]any{"type": protocol.TypePostMessageResp, "status": protocol.StatusOk, "id": req["id"]})
+
+	if err := wait(t, sent); err != nil {
+		t.Error(err)
+	}
+	if !c.Flush(0) {
+		t.Error("Flush returned false once message is confirmed")
+	}
+}
+
+func TestSendRejected(t *testing.T) {
+	srv := wstest.NewServer(t)
+	c, conn := connect(t, srv)
+
+	sent := async(func() error {
+		return c.Send("hi")
+	})
+	req := conn.ReadType(protocol.TypePostMessageReq)
+	conn.Write(map[string]any{
+		"type": protocol.TypePostMessageResp, "status": protocol.StatusMessageIsTooLong, "id": req["id"],
+	})
+
+	if err := wait(t, sent); err == nil {
+		t.Error("Send of rejected message returned no error")
+	}
+}
+
+func TestSendTooLong(t *testing.T) {
+	srv := wstest.NewServer(t)
+	c, _ := connect(t, srv)
+	c.cfg.MaxMessageLength = 3
+
+	if err := c.Send("hello"); err == nil {
+		t.Error("Send of too long message returned no error")
+	}
+}
+
+func TestOnMessage(t *testing.T) {
+	srv := wstest.NewServer(t)
+	c, conn := connect(t, srv)
+	msgs := make(chan Message, 1)
+	c.OnMessage(func(msg Message) {
+		msgs <- msg
+	})
+
+	conn.Write(map[string]any{
+		"type": protocol.TypeChatMessageToClient, "nickname": "bob", "msg": "hello", "id": 7, "timestamp": 1000,
+		"room": "dev",
+	})
+
+	select {
+	case msg := <-msgs:
+		want := Message{ID: 7, Nickname: "bob", Text: "hello", Time: time.UnixMilli(1000), Room: "dev"}
+		if msg != want {
+			t.Errorf("Received message is %+v, want %+v", msg, want)
+		}
+	case <-time.After(wstest.Timeout):
+		t.Fatal("Message is not received")
+	}
+}
+
+func TestOnlineUsers(t *testing.T) {
+	srv := wstest.NewServer(t)
+	c, conn := connect(t, srv)
+
+	var users []string
+	received := async(func() error {
+		var err error
+		users, err = c.OnlineUsers()
+		return err
+	})
+	conn.ReadType(protocol.TypeOnlineUsersReq)
+	conn.Write(map[string]any{
+		"type": protocol.TypeOnlineUsers, "status": protocol.StatusOk,
+		"users": []any{"alice", map[string]any{"nickname": "bob", "away": true}},
+	})
+
+	if err := wait(t, received); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"alice", "bob"}; !slices.Equal(users, want) {
+		t.Errorf("Online users are %v, want %v", users, want)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	srv := wstest.NewServer(t)
+	c, conn := connect(t, srv)
+	loggedIn := async(func() error {
+		return c.Login("bob", "")
+	})
+	login(conn, protocol.StatusOk)
+	if err := wait(t, loggedIn); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Drop()
+	conn = srv.Accept()
+	if req := login(conn, protocol.StatusOk); req["nickname"] != "bob" {
+		t.Errorf("Login request nickname after reconnect is %v, want bob", req["nickname"])
+	}
+
+	sent := async(func() error {
+		return c.Send("back")
+	})
+	req := conn.ReadType(protocol.TypePostMessageReq)
+	conn.Write(map[string]any{"type": protocol.TypePostMessageResp, "status": protocol.StatusOk, "id": req["id"]})
+	if err := wait(t, sent); err != nil {
+		t.Error(err)
+	}
+}
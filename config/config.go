@@ -1,7 +1,13 @@
 package config
 
 import (
+	"io/fs"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/pelletier/go-toml/v2"
@@ -9,16 +15,172 @@ import (
 
 const configFileName = "go_chat_client_config.toml"
 
+// DefaultMaxMessageLength is the maximum amount of symbols (Unicode code points, as counted by server) in message
+// allowed to be sent, used if it's not set in config.
+const DefaultMaxMessageLength = 2000
+
+// fallbackDirName is the name of directory in user config directory to write config file to if configFileName is not
+// writable. History file is kept there as well.
+const fallbackDirName = "go_chat_client"
+
+// historyFileName is the name of input history file in fallbackDirName.
+const historyFileName = "history.jsonl"
+
+// ErrReadOnly is returned by Write if config file is not writable and config was written to fallback location instead.
+var ErrReadOnly = errors.New("Config file is read-only")
+
+// ErrConfigNotFound is returned by Read if config file doesn't exist, e.g. on first run. It wraps fs.ErrNotExist.
+var ErrConfigNotFound = errors.Wrap(fs.ErrNotExist, "Config file not found")
+
+// writePath is the path config is written to. It's changed to fallback path once configFileName turns out read-only.
+var writePath = configFileName
+
 // Config represents config file contents.
 type Config struct {
-	ServerAddress string `toml:"server_address" comment:"Server address in format of 'host:port'"`
-	TLSMode       *bool  `toml:"tls_mode" comment:"Connect to server using TLS protocol?"`
-	Nickname      string `toml:"nickname" comment:"User name to login with"`
+	ServerAddress      string              `toml:"server_address" comment:"Server address in format of 'host:port'"`
+	ServerPath         string              `toml:"server_path" comment:"Path of chat endpoint on server, starting with '/'. Empty for default (/chat)"`
+	Subprotocols       []string            `toml:"subprotocols" comment:"WebSocket subprotocols to offer to server, in order of preference"`
+	TLSMode            *bool               `toml:"tls_mode" comment:"Connect to server using TLS protocol?"`
+	Insecure           bool                `toml:"insecure" comment:"Skip TLS certificate verification? Use only for servers with self-signed certificates"`
+	ProxyURL           string              `toml:"proxy_url" comment:"Proxy to connect through, e.g. 'socks5://host:port'. Empty to take from environment"`
+	ClientCert         string              `toml:"client_cert" comment:"Path to PEM client certificate for servers requiring mutual TLS, empty to disable"`
+	ClientKey          string              `toml:"client_key" comment:"Path to PEM private key of client certificate"`
+	CACert             string              `toml:"ca_cert" comment:"Path to PEM bundle of CA certificates to verify server, empty to use system ones"`
+	Nickname           string              `toml:"nickname" comment:"User name to login with"`
+	Invite             string              `toml:"invite" comment:"One-time invite token for invite-only servers, cleared once used"`
+	Rooms              []string            `toml:"rooms" comment:"Rooms to join on login, besides the main one"`
+	DefaultRoom        string              `toml:"default_room" comment:"Room to switch to on start, joining it if needed. Empty for the main one"`
+	JoinMessage        string              `toml:"join_message" comment:"Message to send on login, empty to disable. Placeholders: {nickname}, {server}"`
+	LoginMessage       string              `toml:"login_message" comment:"Message to send once logged in, empty to disable. Placeholders: {nickname}, {server}"`
+	RepeatLoginMessage bool                `toml:"repeat_login_message" comment:"Send login_message again each time connection is restored?"`
+	NicknameColors     []string            `toml:"nickname_colors" comment:"Colors to pick nickname colors from, empty to use default set"`
+	ColorOverrides     map[string]string   `toml:"color_overrides" comment:"Colors of particular nicknames, e.g. alice = 'hi_blue'. Updated by /color command"`
+	Muted              []string            `toml:"muted" comment:"Nicknames of users which messages are hidden. Updated by /mute and /unmute commands"`
+	SystemLabel        string              `toml:"system_label" comment:"Label shown instead of nickname in system messages, empty for default (SYSTEM)"`
+	SystemColor        string              `toml:"system_color" comment:"Color of system messages label, empty for default (cyan)"`
+	LogLevel           string              `toml:"log_level" comment:"Logging level: trace, debug, info, warn, error or fatal. Empty for default (info)"`
+	Quiet              bool                `toml:"quiet" comment:"Show only warnings and errors in chat window? Log file still gets more verbose levels"`
+	TimestampFormat    string              `toml:"timestamp_format" comment:"Format of message time as Go time layout, e.g. '2006-01-02 03:04 PM', 'off' to hide it. Empty for default (15:04:05)"`
+	CompactTimestamps  bool                `toml:"compact_timestamps" comment:"Show message time only if it differs from time of the previous message?"`
+	RelativeTimestamps bool                `toml:"relative_timestamps" comment:"Show message time relative to now, e.g. '2m ago'? F4 switches it"`
+	KeyBindings        map[string][]string `toml:"key_bindings" comment:"Keys to bind UI actions to, e.g. toggle_online_box = ['F6']. Omitted actions use default keys"`
+	OnlineBoxOpen      bool                `toml:"online_box_open" comment:"Open online users window on start? Updated when it's opened or closed"`
+	OnlineBoxWidth     int                 `toml:"online_box_width" comment:"Width of online users window in columns, 0 to fit the longest nickname"`
+	OnlineBoxLeft      bool                `toml:"online_box_left" comment:"Show online users window at the left side instead of the right one?"`
+	SkipQuitConfirm    bool                `toml:"skip_quit_confirm" comment:"Quit without confirmation even if input window is not empty?"`
+	DisableMouse       bool                `toml:"disable_mouse" comment:"Do not capture mouse? Mouse is used to scroll and focus windows"`
+	DisableFormatting  bool                `toml:"disable_formatting" comment:"Show *bold*, _italic_ and 'code' markers in messages as is instead of styling text?"`
+	DisableOpenURL     bool                `toml:"disable_open_url" comment:"Do not open URLs from chat window in browser? Set on headless systems"`
+	DisableHistoryFile bool                `toml:"disable_history_file" comment:"Do not save sent messages to history file? Input history is kept only until exit then"`
+	NewMessagesBanner  bool                `toml:"new_messages_banner" comment:"Show amount of new messages over chat window while it's scrolled up?"`
+	JoinLeaveNotices   bool                `toml:"join_leave_notices" comment:"Show system messages when users join or leave, according to the list of online users?"`
+	AutoAway           int                 `toml:"auto_away" comment:"Time in seconds without key presses after which you are marked away, 0 to disable"`
+	RateLimitMessages  int                 `toml:"rate_limit_messages" comment:"Maximum amount of messages to send per rate limit interval, 0 for default (5)"`
+	RateLimitInterval  int                 `toml:"rate_limit_interval" comment:"Rate limit interval in seconds, 0 for default (10)"`
+	PostAttempts       int                 `toml:"post_attempts" comment:"Maximum attempts to send message not confirmed by server, 0 for default (3)"`
+	ReconnectAttempts  int                 `toml:"reconnect_attempts" comment:"Maximum consecutive attempts to connect before giving up, 0 to retry forever"`
+	ReconnectDelay     *float64            `toml:"reconnect_delay" comment:"Time in seconds to wait between attempts to connect, e.g. 0.1 on LAN or 0 for no delay. Unset for default (5)"`
+	DialTimeout        int                 `toml:"dial_timeout" comment:"Maximum time in seconds to wait for connection to server on each attempt, 0 for default (10)"`
+	ReadTimeout        int                 `toml:"read_timeout" comment:"Time in seconds without any data from server after which connection is considered lost, 0 for default (60)"`
+	Compression        bool                `toml:"compression" comment:"Compress messages, if server supports it? Useful on slow connections"`
+	Handshake          bool                `toml:"handshake" comment:"Check protocol version with server right after connecting? Servers not supporting it delay start by 5 seconds"`
+	MaxMessageLength   int                 `toml:"max_message_length" comment:"Maximum amount of symbols in message allowed to be sent, 0 for default (2000)"`
+	MessageTTL         int                 `toml:"message_ttl" comment:"Time in seconds after which messages disappear from chat window, 0 to keep them"`
+	Scrollback         int                 `toml:"scrollback" comment:"Maximum amount of messages kept in chat window, older ones are removed. 0 for default (5000)"`
+	Notifications      string              `toml:"notifications" comment:"Notify about new messages while away or scrolled up: '' (off), 'bell' or 'desktop'"`
+	ReconnectIndicator bool                `toml:"reconnect_indicator" comment:"Ring the bell twice and highlight status bar when connection is restored?"`
+	AutoreplyCooldown  int                 `toml:"autoreply_cooldown" comment:"Minimum interval in seconds between autoreplies to the same trigger, 0 for default (60)"`
+	Autoreplies        map[string]string   `toml:"autoreplies" comment:"Responses to send when incoming message contains trigger, e.g. ping = 'pong'"`
+	Macros             map[string]string   `toml:"macros" comment:"Commands expanded to text sent as message, e.g. brb = 'be right back' for /brb"`
+	Connections        []Connection        `toml:"connections" comment:"Servers to connect to besides the main one, each shown in it's own chat window. F5 switches between them"`
+
+	envOverrides []func(persisted *Config) // Restore values replaced by ApplyEnv before config is written
+}
+
+// Validate returns error if any of config values is out of range.
+func (c *Config) Validate() error {
+	if c.ServerPath != "" && !strings.HasPrefix(c.ServerPath, "/") {
+		return errors.Newf("Invalid config value server_path = '%v', it should start with '/'", c.ServerPath)
+	}
+	if c.MaxMessageLength < 0 {
+		return errors.Newf("Invalid config value max_message_length = %v, it should be positive", c.MaxMessageLength)
+	}
+	if !slices.Contains([]string{"", "bell", "desktop"}, c.Notifications) {
+		return errors.Newf("Invalid config value notifications = '%v', it should be '', 'bell' or 'desktop'",
+			c.Notifications)
+	}
+	if c.ReconnectDelay != nil && *c.ReconnectDelay < 0 {
+		return errors.Newf("Invalid config value reconnect_delay = %v, it should not be negative", *c.ReconnectDelay)
+	}
+	if c.DialTimeout < 0 {
+		return errors.Newf("Invalid config value dial_timeout = %v, it should not be negative", c.DialTimeout)
+	}
+	if c.ReadTimeout < 0 {
+		return errors.Newf("Invalid config value read_timeout = %v, it should not be negative", c.ReadTimeout)
+	}
+	if err := validateTimestampFormat(c.TimestampFormat); err != nil {
+		return err
+	}
+	if c.AutoAway < 0 {
+		return errors.Newf("Invalid config value auto_away = %v, it should not be negative", c.AutoAway)
+	}
+	if c.OnlineBoxWidth < 0 {
+		return errors.Newf("Invalid config value online_box_width = %v, it should not be negative", c.OnlineBoxWidth)
+	}
+	if c.MessageTTL < 0 {
+		return errors.Newf("Invalid config value message_ttl = %v, it should not be negative", c.MessageTTL)
+	}
+	if c.Scrollback < 0 {
+		return errors.Newf("Invalid config value scrollback = %v, it should not be negative", c.Scrollback)
+	}
+	return validateConnections(c.Connections)
+}
+
+// validateTimestampFormat returns error if <layout> is not empty, "off" or valid Go time layout. Layout is considered
+// valid if formatting a known time with it gives something other than the layout itself.
+func validateTimestampFormat(layout string) error {
+	if layout == "" || layout == "off" {
+		return nil
+	}
+	known := time.Date(2001, time.February, 3, 4, 5, 6, 0, time.UTC)
+	if known.Format(layout) == layout {
+		return errors.Newf("Invalid config value timestamp_format = '%v', it should be Go time layout, e.g. '15:04:05' "+
+			"(https://pkg.go.dev/time#pkg-constants)", layout)
+	}
+	return nil
+}
+
+// MessageLengthLimit returns maximum amount of symbols in message allowed to be sent.
+func (c *Config) MessageLengthLimit() int {
+	if c.MaxMessageLength > 0 {
+		return c.MaxMessageLength
+	}
+	return DefaultMaxMessageLength
+}
+
+// RetryDelay returns time to wait between attempts to connect, or nil if reconnect_delay is not set.
+func (c *Config) RetryDelay() *time.Duration {
+	if c.ReconnectDelay == nil {
+		return nil
+	}
+	delay := time.Duration(*c.ReconnectDelay * float64(time.Second))
+	return &delay
 }
 
-// Read reads and returns config file.
+// Read reads and returns config file. It returns ErrConfigNotFound if config file doesn't exist, or other error if
+// it can't be read or decoded. Config in fallback location is preferred, since it's written only if
+// configFileName is read-only and so contains the latest settings.
 func Read() (*Config, error) {
-	bytes, err := os.ReadFile(configFileName)
+	path := configFileName
+	if fallback, err := fallbackPath(); err == nil {
+		if _, err = os.Stat(fallback); err == nil {
+			path, writePath = fallback, fallback
+		}
+	}
+	bytes, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Config{}, ErrConfigNotFound
+	}
 	if err != nil {
 		return &Config{}, errors.Wrap(err, "Read config file")
 	}
@@ -32,13 +194,88 @@ func Read() (*Config, error) {
 	return &cfg, nil
 }
 
-// Write writes <cfg> to file.
+// Write writes <cfg> to file. Values set by ApplyEnv are written as they were before it, unless they are changed since
+// then. If config file is read-only, it writes <cfg> to fallback location in user config
+// directory, returning ErrReadOnly with the fallback path once. Subsequent calls write to fallback location silently.
 func Write(cfg *Config) error {
-	bytes, err := toml.Marshal(cfg)
+	persisted := *cfg
+	for _, restore := range cfg.envOverrides {
+		restore(&persisted)
+	}
+	bytes, err := toml.Marshal(persisted)
 	if err != nil {
 		return errors.Wrap(err, "Encode config file")
 	}
 
-	err = os.WriteFile(configFileName, bytes, 0644)
-	return errors.Wrap(err, "Write config file")
+	err = writeFile(writePath, bytes)
+	if !isPermissionErr(err) || writePath != configFileName {
+		return errors.Wrap(err, "Write config file")
+	}
+
+	fallback, err := fallbackPath()
+	if err != nil {
+		return errors.Wrap(err, "Config file is read-only, settings will not be saved")
+	}
+	if err = writeFile(fallback, bytes); err != nil {
+		return errors.Wrap(err, "Config file is read-only, settings will not be saved")
+	}
+	writePath = fallback
+	return errors.Wrapf(ErrReadOnly, "Settings are saved to %v instead", fallback)
+}
+
+// writeFile writes <data> to file at <path> readable only by owner, since config can contain credentials, creating
+// parent directories if needed. Data is written to temporary file first and then renamed to <path>, so file is never
+// left partially written. It returns permission error if existing file is read-only.
+func writeFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errors.Wrap(err, "Create config directory")
+	}
+	// Renaming replaces read-only file as well, so check it's writable explicitly
+	if file, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+		file.Close()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp") // Created with 0600 permissions
+	if err != nil {
+		return errors.Wrap(err, "Create temporary config file")
+	}
+	defer os.Remove(tmp.Name()) // Does nothing after successfull rename
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "Write temporary config file")
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "Flush temporary config file")
+	}
+	if err = tmp.Close(); err != nil {
+		return errors.Wrap(err, "Close temporary config file")
+	}
+	return errors.Wrap(os.Rename(tmp.Name(), path), "Replace config file")
+}
+
+// isPermissionErr returns true if <err> is caused by lack of permission to write file or it's directory.
+func isPermissionErr(err error) bool {
+	return errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EROFS)
+}
+
+// HistoryPath returns path to file in user config directory to save input history to between sessions.
+func HistoryPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", errors.Wrap(err, "Get user config directory")
+	}
+	return filepath.Join(dir, fallbackDirName, historyFileName), nil
+}
+
+// fallbackPath returns path to write config file to if configFileName is read-only.
+func fallbackPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", errors.Wrap(err, "Get user config directory")
+	}
+	return filepath.Join(dir, fallbackDirName, configFileName), nil
 }
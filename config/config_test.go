@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// assertFile fails the test if file at <path> doesn't have <content> or permissions other than owner-only.
+func assertFile(t *testing.T, path string, content string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Errorf("File content is %q, want %q", data, content)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("File mode is %v, want %v", mode, fs.FileMode(0600))
+	}
+}
+
+// assertNoTempFiles fails the test if temporary files are left in <dir>.
+func assertNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	tmp, err := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tmp) != 0 {
+		t.Errorf("Temporary files %v are left", tmp)
+	}
+}
+
+func TestWriteFileCreatesDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a", "b")
+	path := filepath.Join(dir, configFileName)
+
+	if err := writeFile(path, []byte("nickname = 'alice'")); err != nil {
+		t.Fatalf("Write file: %v", err)
+	}
+	assertFile(t, path, "nickname = 'alice'")
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0700 {
+		t.Errorf("Directory mode is %v, want %v", mode, fs.FileMode(0700))
+	}
+	assertNoTempFiles(t, dir)
+}
+
+func TestWriteFileReplacesAtomically(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, configFileName)
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer old.Close()
+
+	if err := writeFile(path, []byte("new")); err != nil {
+		t.Fatalf("Write file: %v", err)
+	}
+	assertFile(t, path, "new")
+	// File is replaced by rename instead of being truncated, so readers of the old file never see partial content
+	data, err := io.ReadAll(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "old" {
+		t.Errorf("Content of replaced file is %q, want %q", data, "old")
+	}
+	assertNoTempFiles(t, dir)
+}
+
+func TestWriteFileReadOnly(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("Root can write read-only files")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, configFileName)
+	if err := os.WriteFile(path, []byte("old"), 0400); err != nil {
+		t.Fatal(err)
+	}
+
+	err := writeFile(path, []byte("new"))
+	if !isPermissionErr(err) {
+		t.Errorf("Write error is %v, want permission error", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "old" {
+		t.Errorf("File content is %q, want %q", data, "old")
+	}
+	assertNoTempFiles(t, dir)
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		wantNotFound bool
+		wantErr      string
+		wantAddr     string
+	}{
+		{name: "missing", wantNotFound: true},
+		{name: "invalid", content: "server_address = ", wantErr: "Decode config file"},
+		{name: "valid", content: "server_address = 'example.com:8080'\nnickname = 'bob'\n", wantAddr: "example.com:8080"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inTempDir(t)
+			if !tt.wantNotFound {
+				if err := os.WriteFile(configFileName, []byte(tt.content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cfg, err := Read()
+			if notFound := errors.Is(err, ErrConfigNotFound); notFound != tt.wantNotFound {
+				t.Errorf("Read returned %v, want ErrConfigNotFound: %v", err, tt.wantNotFound)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Read returned %v, want error about %v", err, tt.wantErr)
+			}
+			if tt.wantErr == "" && !tt.wantNotFound && err != nil {
+				t.Errorf("Read returned %v, want nil", err)
+			}
+			if cfg == nil || cfg.ServerAddress != tt.wantAddr {
+				t.Errorf("Read returned config %+v, want server address %q", cfg, tt.wantAddr)
+			}
+		})
+	}
+}
+
+func TestValidateMessageTTL(t *testing.T) {
+	tests := []struct {
+		ttl     int
+		wantErr bool
+	}{
+		{0, false},
+		{3600, false},
+		{-1, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.ttl), func(t *testing.T) {
+			err := (&Config{MessageTTL: tt.ttl}).Validate()
+			if tt.wantErr && (err == nil || !strings.Contains(err.Error(), "message_ttl")) {
+				t.Errorf("Validate returned %v, want error about message_ttl", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Validate returned %v, want nil", err)
+			}
+		})
+	}
+}
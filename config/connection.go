@@ -0,0 +1,56 @@
+package config
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Connection represents server to connect to besides the one set by server_address, shown in it's own chat window.
+type Connection struct {
+	ServerAddress string `toml:"server_address" comment:"Server address in format of 'host:port'"`
+	ServerPath    string `toml:"server_path" comment:"Path of chat endpoint on server, starting with '/'. Empty for default (/chat)"`
+	TLSMode       bool   `toml:"tls_mode" comment:"Connect to server using TLS protocol?"`
+	Nickname      string `toml:"nickname" comment:"User name to login with"`
+}
+
+// ForConnection returns copy of <c> for connection to server <conn>: server and nickname are taken from <conn>, other
+// settings are shared with the main connection, except for rooms, default room and invite, which are specific to the
+// main server. Slices and maps are copied, so changes made by commands, e.g. /mute, don't affect <c>.
+func (c *Config) ForConnection(conn Connection) *Config {
+	cfg := *c
+	cfg.ServerAddress = conn.ServerAddress
+	cfg.ServerPath = conn.ServerPath
+	cfg.TLSMode = &conn.TLSMode
+	cfg.Nickname = conn.Nickname
+	cfg.Invite = ""
+	cfg.Rooms = nil
+	cfg.DefaultRoom = ""
+	cfg.Muted = slices.Clone(c.Muted)
+	cfg.ColorOverrides = maps.Clone(c.ColorOverrides)
+	cfg.Autoreplies = maps.Clone(c.Autoreplies)
+	cfg.Macros = maps.Clone(c.Macros)
+	cfg.Connections = nil
+	cfg.envOverrides = nil
+	return &cfg
+}
+
+// validateConnections returns error if server address or nickname of any of <conns> is missing, or if server path
+// doesn't start with '/'.
+func validateConnections(conns []Connection) error {
+	for i, conn := range conns {
+		if conn.ServerAddress == "" {
+			return errors.Newf("Invalid config value connections[%v].server_address = '', it should be 'host:port'", i)
+		}
+		if conn.ServerPath != "" && !strings.HasPrefix(conn.ServerPath, "/") {
+			return errors.Newf("Invalid config value connections[%v].server_path = '%v', it should start with '/'", i,
+				conn.ServerPath)
+		}
+		if conn.Nickname == "" {
+			return errors.Newf("Invalid config value connections[%v].nickname = '', it should not be empty", i)
+		}
+	}
+	return nil
+}
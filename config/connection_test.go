@@ -0,0 +1,71 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestForConnection(t *testing.T) {
+	tls := true
+	cfg := &Config{
+		ServerAddress:  "main:1",
+		TLSMode:        &tls,
+		Nickname:       "bob",
+		Invite:         "token",
+		Rooms:          []string{"dev"},
+		DefaultRoom:    "dev",
+		Muted:          []string{"eve"},
+		ColorOverrides: map[string]string{"alice": "red"},
+		AutoAway:       60,
+		Connections:    []Connection{{ServerAddress: "other:2", Nickname: "carol"}},
+	}
+
+	got := cfg.ForConnection(Connection{ServerAddress: "other:2", ServerPath: "/ws", Nickname: "carol"})
+	if got.ServerAddress != "other:2" || got.ServerPath != "/ws" || got.Nickname != "carol" || *got.TLSMode {
+		t.Errorf("Server is %v%v with TLS %v and nickname %v, want other:2/ws without TLS and carol",
+			got.ServerAddress, got.ServerPath, *got.TLSMode, got.Nickname)
+	}
+	if got.Invite != "" || got.Rooms != nil || got.DefaultRoom != "" || got.Connections != nil {
+		t.Errorf("Settings of main server are kept: invite %q, rooms %v, default room %q, connections %v", got.Invite,
+			got.Rooms, got.DefaultRoom, got.Connections)
+	}
+	if got.AutoAway != 60 {
+		t.Errorf("auto_away is %v, want shared value 60", got.AutoAway)
+	}
+
+	got.Muted = append(got.Muted[:0], "mallory")
+	got.ColorOverrides["alice"] = "blue"
+	if !slices.Equal(cfg.Muted, []string{"eve"}) || cfg.ColorOverrides["alice"] != "red" {
+		t.Errorf("Changes of connection config affect main one: muted %v, colors %v", cfg.Muted, cfg.ColorOverrides)
+	}
+	if !*cfg.TLSMode || cfg.ServerAddress != "main:1" {
+		t.Errorf("Main config is changed to %v with TLS %v", cfg.ServerAddress, *cfg.TLSMode)
+	}
+}
+
+func TestValidateConnections(t *testing.T) {
+	tests := []struct {
+		name    string
+		conn    Connection
+		wantErr string
+	}{
+		{"valid", Connection{ServerAddress: "other:2", ServerPath: "/ws", Nickname: "carol"}, ""},
+		{"no server address", Connection{Nickname: "carol"}, "connections[1].server_address"},
+		{"relative server path", Connection{ServerAddress: "other:2", ServerPath: "ws", Nickname: "carol"},
+			"connections[1].server_path"},
+		{"no nickname", Connection{ServerAddress: "other:2"}, "connections[1].nickname"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Connections: []Connection{{ServerAddress: "first:1", Nickname: "bob"}, tt.conn}}
+			err := cfg.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Validate returned %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Validate returned %v, want error about %v", err, tt.wantErr)
+			}
+		})
+	}
+}
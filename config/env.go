@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// envPrefix is the prefix of environment variables overriding config values.
+const envPrefix = "GOCHAT_"
+
+// ApplyEnv overrides values of <cfg> with environment variables which are set, e.g. GOCHAT_SERVER_ADDRESS overrides
+// server_address. Environment takes precedence over config file, but not over command line flags. It returns error if
+// value of boolean variable is invalid. Overridden values are not written to config file by Write.
+func ApplyEnv(cfg *Config) error {
+	if value, ok := lookupEnv("SERVER_ADDRESS"); ok {
+		override(cfg, func(c *Config) *string { return &c.ServerAddress }, value)
+	}
+	if value, ok := lookupEnv("TLS_MODE"); ok {
+		tls, err := parseBool(value)
+		if err != nil {
+			return errors.Wrapf(err, "Parse environment variable %vTLS_MODE", envPrefix)
+		}
+		override(cfg, func(c *Config) **bool { return &c.TLSMode }, &tls)
+	}
+	if value, ok := lookupEnv("NICKNAME"); ok {
+		override(cfg, func(c *Config) *string { return &c.Nickname }, value)
+	}
+	if value, ok := lookupEnv("PROXY_URL"); ok {
+		override(cfg, func(c *Config) *string { return &c.ProxyURL }, value)
+	}
+	return nil
+}
+
+// override sets config value returned by <field> to <value>, remembering the previous one to be written to config
+// file instead, as long as value is not changed again, e.g. by command line flag or /nick command.
+func override[T comparable](cfg *Config, field func(c *Config) *T, value T) {
+	previous := *field(cfg)
+	*field(cfg) = value
+	cfg.envOverrides = append(cfg.envOverrides, func(persisted *Config) {
+		if *field(persisted) == value {
+			*field(persisted) = previous
+		}
+	})
+}
+
+// lookupEnv returns value of environment variable with <name> prefixed by envPrefix and true, or false if it's not set
+// or empty.
+func lookupEnv(name string) (string, bool) {
+	value := strings.TrimSpace(os.Getenv(envPrefix + name))
+	return value, value != ""
+}
+
+// parseBool returns boolean <value>, which can be "true", "false", "1", "0", "y", "n", "yes" or "no", ignoring case.
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "1", "y", "yes":
+		return true, nil
+	case "false", "0", "n", "no":
+		return false, nil
+	}
+	return false, errors.Newf("Invalid boolean value '%v', it should be true/false, 1/0, y/n or yes/no", value)
+}
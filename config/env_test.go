@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/samber/lo"
+)
+
+// inTempDir runs test in new temporary directory, which is also used as user config directory, so config is read from
+// and written to it.
+func inTempDir(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	writePath = configFileName
+	t.Cleanup(func() {
+		writePath = configFileName
+		if err := os.Chdir(wd); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// clearEnv unsets environment variables read by ApplyEnv for the duration of the test.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{"SERVER_ADDRESS", "TLS_MODE", "NICKNAME", "PROXY_URL"} {
+		t.Setenv(envPrefix+name, "")
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("GOCHAT_SERVER_ADDRESS", " env:8080 ")
+	t.Setenv("GOCHAT_TLS_MODE", "Yes")
+	cfg := &Config{ServerAddress: "file:8080", Nickname: "alice", TLSMode: lo.ToPtr(false)}
+
+	if err := ApplyEnv(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ServerAddress != "env:8080" {
+		t.Errorf("Server address is %v, want env:8080 from environment", cfg.ServerAddress)
+	}
+	if cfg.TLSMode == nil || !*cfg.TLSMode {
+		t.Errorf("TLS mode is %v, want true from environment", lo.FromPtr(cfg.TLSMode))
+	}
+	if cfg.Nickname != "alice" {
+		t.Errorf("Nickname is %v, want alice from config since variable is empty", cfg.Nickname)
+	}
+}
+
+func TestApplyEnvInvalidBool(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("GOCHAT_TLS_MODE", "maybe")
+	if err := ApplyEnv(&Config{}); err == nil {
+		t.Error("ApplyEnv returned no error for invalid boolean")
+	}
+}
+
+func TestWriteSkipsEnvOverrides(t *testing.T) {
+	inTempDir(t)
+	clearEnv(t)
+	t.Setenv("GOCHAT_SERVER_ADDRESS", "env:8080")
+	t.Setenv("GOCHAT_NICKNAME", "bob")
+	t.Setenv("GOCHAT_TLS_MODE", "true")
+	t.Setenv("GOCHAT_PROXY_URL", "socks5://env:1080")
+	cfg := &Config{ServerAddress: "file:8080", Nickname: "alice", Scrollback: 100}
+
+	if err := ApplyEnv(cfg); err != nil {
+		t.Fatal(err)
+	}
+	// Changed after environment is applied, e.g. by /nick command
+	cfg.Nickname = "carol"
+	if err := Write(cfg); err != nil {
+		t.Fatal(err)
+	}
+	written, err := Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if written.ServerAddress != "file:8080" || written.TLSMode != nil || written.ProxyURL != "" {
+		t.Errorf("Written server address, TLS mode and proxy are %v, %v and %v, want values from config file",
+			written.ServerAddress, lo.FromPtr(written.TLSMode), written.ProxyURL)
+	}
+	if written.Nickname != "carol" {
+		t.Errorf("Written nickname is %v, want carol set after environment is applied", written.Nickname)
+	}
+	if written.Scrollback != 100 {
+		t.Errorf("Written scrollback is %v, want 100", written.Scrollback)
+	}
+	if cfg.ServerAddress != "env:8080" {
+		t.Errorf("Server address is %v after Write, want env:8080 from environment", cfg.ServerAddress)
+	}
+}
@@ -0,0 +1,68 @@
+package connection
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+)
+
+// typeField is the name of message field containing message type.
+const typeField = "type"
+
+// AddOnTypeListener registers function <l> to be run when client receives a message of type <msgType> from server.
+func (h *Handler) AddOnTypeListener(msgType float64, l func(map[string]any)) {
+	h.listenersMu.Lock()
+	defer h.listenersMu.Unlock()
+	if h.onType == nil {
+		h.onType = map[float64][]func(map[string]any){}
+	}
+	h.onType[msgType] = append(h.onType[msgType], l)
+}
+
+// dispatch runs on response listeners and listeners of <resp> type. Type field of <resp> is normalized to float64, so
+// listeners can decode it regardless of whether server sent a number or a numeric string. Messages with missing or
+// unknown type are logged. Listeners registered while it runs are run starting from the next message.
+func (h *Handler) dispatch(resp map[string]any) {
+	h.listenersMu.Lock()
+	respListeners := slices.Clone(h.onResponse)
+	h.listenersMu.Unlock()
+	for _, listener := range respListeners {
+		listener(resp)
+	}
+	msgType, err := messageType(resp)
+	if err != nil {
+		h.log.WithError(err).Warnf("Skip message from server %v", resp)
+		return
+	}
+	resp[typeField] = msgType
+	h.listenersMu.Lock()
+	listeners, ok := h.onType[msgType]
+	listeners = slices.Clone(listeners)
+	h.listenersMu.Unlock()
+	if !ok {
+		h.log.Warnf("Skip message from server of unknown type %v", msgType)
+		return
+	}
+	for _, listener := range listeners {
+		listener(resp)
+	}
+}
+
+// messageType returns type of <resp>, or error if type is missing or not a number.
+func messageType(resp map[string]any) (float64, error) {
+	switch t := resp[typeField].(type) {
+	case float64:
+		return t, nil
+	case json.Number:
+		return t.Float64()
+	case string:
+		msgType, err := strconv.ParseFloat(t, 64)
+		return msgType, errors.Wrapf(err, "Parse message type '%v'", t)
+	case nil:
+		return 0, errors.New("Message type is missing")
+	default:
+		return 0, errors.Newf("Message type %v is not a number", t)
+	}
+}
@@ -1,91 +1,440 @@
 package connection
 
 import (
+	"context"
+	"encoding/json"
+	"io"
 	"net"
+	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"go_chat_client/util/clock"
+
 	"github.com/cockroachdb/errors"
 	"github.com/gorilla/websocket"
 	"github.com/samber/lo"
 	"github.com/sirupsen/logrus"
 )
 
+// inviteHeader is the name of HTTP header to send invite token in.
+const inviteHeader = "X-Invite-Token"
+
+// closeTimeout is the maximum time to wait for close message to be sent to server on shutdown.
+const closeTimeout = time.Second * 3
+
+// pingInterval is the interval between keepalive pings used to measure round-trip time.
+const pingInterval = time.Second * 15
+
+// defaultReadTimeout is the maximum time to wait for any data from server used if it's not set in Options. It's a few
+// times longer than pingInterval, so connection is considered lost only if server misses several pings in a row.
+const defaultReadTimeout = pingInterval * 4
+
+// defaultDialTimeout is the maximum time to wait for connection to server, including websocket handshake, used if
+// it's not set in Options.
+const defaultDialTimeout = time.Second * 10
+
+// DefaultRetryDelay is the time to wait between attempts to connect and before reconnecting after connection is lost,
+// used if it's not set in Options.
+const DefaultRetryDelay = time.Second * 5
+
+// DefaultPath is the path of chat endpoint on server used if it's not set in Options.
+const DefaultPath = "/chat"
+
+// defaultStateBufferSize is the capacity of connection state channel used if it's not set in Options.
+const defaultStateBufferSize = 16
+
+// Options represents connection settings.
+type Options struct {
+	TLS                bool           // Establish secure connection to server
+	Path               string         // Path of chat endpoint on server, starting with "/". If empty, DefaultPath is used
+	Subprotocols       []string       // WebSocket subprotocols to offer to server, in order of preference
+	InsecureSkipVerify bool           // Do not verify server certificate chain and host name
+	ClientCert         string         // Path to PEM client certificate to authenticate with, if server requires it
+	ClientKey          string         // Path to PEM private key of ClientCert
+	CACert             string         // Path to PEM bundle of CA certificates to verify server. If empty, system ones are used
+	Invite             string         // One-time invite token to send on the first successful connection, if not empty
+	ProxyURL           string         // Proxy to connect through, e.g. 'socks5://host:port'. If empty, taken from environment
+	StateBufferSize    int            // Capacity of connection state channel. If 0, defaultStateBufferSize is used
+	MaxAttempts        int            // Maximum consecutive failed attempts to connect before giving up. If 0, retry forever
+	ReadTimeout        time.Duration  // Maximum time to wait for any data from server. If 0, defaultReadTimeout is used
+	DialTimeout        time.Duration  // Maximum time to wait for connection to server. If 0, defaultDialTimeout is used
+	Compression        bool           // Compress messages with permessage-deflate extension, if server supports it
+	RetryDelay         *time.Duration // Time to wait between attempts to connect, can be 0. If nil, DefaultRetryDelay is used
+	Clock              clock.Clock    // Source of time to wait between attempts and pings. If nil, clock.Real is used
+}
+
+// ErrAttemptsExceeded is returned by Handler.Connect if server is unreachable after Options.MaxAttempts attempts.
+var ErrAttemptsExceeded = errors.New("Connection attempts limit exceeded")
+
+// ErrReconnectRequested is passed to disconnect listeners if connection is closed by Handler.Reconnect.
+var ErrReconnectRequested = errors.New("Reconnect requested")
+
 // Handler represents connection handler. It wraps websocket connection with convenient methods.
 type Handler struct {
-	log          *logrus.Logger
-	conn         *websocket.Conn
-	url          url.URL
-	onResponse   []func(map[string]any)
-	onDisconnect []func(error)
+	log               *logrus.Logger
+	clock             clock.Clock
+	connMu            sync.Mutex // Guards conn, which is replaced by Dial while other goroutines use it
+	conn              *websocket.Conn
+	writeMu           sync.Mutex // Serializes writes, since websocket connection supports only one writer at a time
+	dialer            *websocket.Dialer
+	url               url.URL
+	opts              Options
+	rtt               atomic.Int64
+	closeOnce         sync.Once
+	retryCh           chan struct{}
+	connected         chan struct{} // Signalled once connection is established
+	dropped           atomic.Bool
+	closing           atomic.Bool // Set by CloseConn, so Listen stops instead of treating it as connection loss
+	stateMu           sync.Mutex
+	state             State
+	states            chan State
+	closed            bool
+	reconnects        int
+	lastDisconnectErr error
+	listenersMu       sync.Mutex // Guards listeners, which can be registered while listening
+	onResponse        []func(map[string]any)
+	onType            map[float64][]func(map[string]any)
+	onDisconnect      []func(error)
+	onStateChange     []func(State)
+	onReconnect       []func(ReconnectEvent)
 }
 
-// NewHandler returns new connection handler. <addr> should be specified in form of 'host:port'. If <tls> is true,
-// establish secure connection to server.
-func NewHandler(log *logrus.Logger, tls bool, addr string) *Handler {
-	u := url.URL{Scheme: lo.Ternary(tls, "wss", "ws"), Host: addr, Path: "/chat"}
-	return &Handler{log: log, url: u}
+// NewHandler returns new connection handler with settings <opts>. <addr> should be specified in form of 'host:port'.
+// It returns error if <opts> are invalid, e.g. client certificate can't be loaded or path doesn't start with "/".
+func NewHandler(log *logrus.Logger, addr string, opts Options) (*Handler, error) {
+	path := lo.Ternary(opts.Path != "", opts.Path, DefaultPath)
+	if !strings.HasPrefix(path, "/") {
+		return nil, errors.Newf("Invalid path '%v', it should start with '/'", path)
+	}
+	u := url.URL{Scheme: lo.Ternary(opts.TLS, "wss", "ws"), Host: addr, Path: path}
+	dialer := *websocket.DefaultDialer
+	dialer.Subprotocols = opts.Subprotocols
+	tlsCfg, err := tlsConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	dialer.TLSClientConfig = tlsCfg
+	dialer.HandshakeTimeout = lo.Ternary(opts.DialTimeout > 0, opts.DialTimeout, defaultDialTimeout)
+	dialer.EnableCompression = opts.Compression
+	if err := setProxy(&dialer, opts.ProxyURL); err != nil {
+		return nil, err
+	}
+	states := make(chan State, lo.Ternary(opts.StateBufferSize > 0, opts.StateBufferSize, defaultStateBufferSize))
+	clk := lo.Ternary[clock.Clock](opts.Clock != nil, opts.Clock, clock.Real{})
+	return &Handler{log: log, clock: clk, dialer: &dialer, url: u, opts: opts, states: states,
+		retryCh: make(chan struct{}), connected: make(chan struct{}, 1)}, nil
 }
 
 // Connect connects to server, blocks until connection if successfull and sets Handler.conn field with connection if so.
-func (h *Handler) Connect() {
-	for {
-		conn, _, err := websocket.DefaultDialer.Dial(h.url.String(), nil)
+// It returns error if <ctx> is cancelled before connection is established or ErrAttemptsExceeded if Options.MaxAttempts
+// consecutive attempts failed. Waiting between attempts is interrupted by Reconnect. Each failed attempt is reported to
+// reconnect listeners.
+func (h *Handler) Connect(ctx context.Context) error {
+	h.setState(lo.Ternary(h.currentConn() == nil, StateConnecting, StateReconnecting))
+	for attempt := 1; ; attempt++ {
+		err := h.Dial(ctx)
 		if err == nil {
-			h.conn = conn
-			h.log.Info("Connected to ", h.url.Host)
-			return
+			return nil
+		}
+		if ctx.Err() != nil {
+			return errors.Wrap(ctx.Err(), "Connect to server")
+		}
+		gaveUp := h.opts.MaxAttempts > 0 && attempt >= h.opts.MaxAttempts
+		h.runReconnectListeners(ReconnectEvent{
+			Attempt: attempt, NextDelay: lo.Ternary(gaveUp, 0, h.RetryDelay()), Err: err, GaveUp: gaveUp,
+		})
+		if gaveUp {
+			h.log.WithError(err).Error()
+			h.setState(StateDisconnected)
+			return errors.Wrapf(ErrAttemptsExceeded, "Give up after %v attempts", attempt)
+		}
+		h.log.WithError(err).Errorf("Connection attempt %v failed, retrying in %v", attempt, h.RetryDelay())
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "Connect to server")
+		case <-h.clock.After(h.RetryDelay()):
+		case <-h.retryCh:
+		}
+	}
+}
+
+// Dial makes single attempt to connect to server and sets Handler.conn field with connection if it was successfull.
+func (h *Handler) Dial(ctx context.Context) error {
+	if h.opts.TLS && h.opts.InsecureSkipVerify {
+		h.log.Warn("TLS certificate verification is disabled, connection is vulnerable to interception")
+	}
+	proxyURL := proxyFor(h.url, h.opts)
+	if proxyURL != nil {
+		h.log.Debug("Using proxy ", proxyURL.Redacted())
+	}
+
+	header := http.Header{}
+	if h.opts.Invite != "" {
+		header.Set(inviteHeader, h.opts.Invite)
+	}
+	conn, resp, err := h.dialer.DialContext(ctx, h.url.String(), header)
+	if err != nil {
+		return wrapDialErr(err, proxyURL)
+	}
+	if h.opts.Compression {
+		if isCompressionNegotiated(resp) {
+			conn.EnableWriteCompression(true)
+			h.log.Debug("Compression is enabled")
+		} else {
+			h.log.Info("Server doesn't support compression, messages are sent uncompressed")
+		}
+	}
+	if len(h.opts.Subprotocols) != 0 {
+		if conn.Subprotocol() != "" {
+			h.log.Info("Server accepted subprotocol ", conn.Subprotocol())
 		} else {
-			h.log.Error(errors.Wrap(err, "Connect to server"), " Retrying in 5 seconds.")
-			time.Sleep(time.Second * 5)
+			h.log.Warn("Server didn't accept any subprotocol of ", strings.Join(h.opts.Subprotocols, ", "))
 		}
 	}
+	conn.SetPongHandler(func(payload string) error {
+		return h.onPong(conn, payload)
+	})
+	h.connMu.Lock()
+	if h.conn != nil {
+		h.countReconnect()
+	}
+	h.conn = conn
+	h.connMu.Unlock()
+	h.opts.Invite = ""
+	h.log.Info("Connected to ", h.url.Host)
+	h.setState(StateConnected)
+	select {
+	case h.connected <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// isCompressionNegotiated returns true if server agreed to compress messages in handshake response <resp>.
+func isCompressionNegotiated(resp *http.Response) bool {
+	for _, ext := range resp.Header.Values("Sec-Websocket-Extensions") {
+		if strings.Contains(ext, "permessage-deflate") {
+			return true
+		}
+	}
+	return false
+}
+
+// KeepAlive sends ping to server every pingInterval, measuring round-trip time from the respective pong. Pings are
+// skipped while connection was never established. It blocks current goroutine until <ctx> is cancelled.
+func (h *Handler) KeepAlive(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.clock.After(pingInterval):
+		}
+		conn := h.currentConn()
+		if conn == nil {
+			continue
+		}
+		payload := []byte(strconv.FormatInt(h.clock.Now().UnixNano(), 10))
+		if err := conn.WriteControl(websocket.PingMessage, payload, h.clock.Now().Add(pingInterval)); err != nil {
+			h.log.WithError(err).Debug("Send ping")
+		}
+	}
+}
+
+// RetryDelay returns time to wait between attempts to connect, set by Options.RetryDelay.
+func (h *Handler) RetryDelay() time.Duration {
+	return max(lo.FromPtrOr(h.opts.RetryDelay, DefaultRetryDelay), 0)
+}
+
+// RTT returns round-trip time to server measured by the last keepalive ping, or 0 if it wasn't measured yet.
+func (h *Handler) RTT() time.Duration {
+	return time.Duration(h.rtt.Load())
+}
+
+// onPong stores round-trip time calculated from ping send time contained in pong <payload> received from <conn>. Used
+// as pong handler of websocket connection.
+func (h *Handler) onPong(conn *websocket.Conn, payload string) error {
+	sentAt, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		h.log.WithError(err).Debug("Parse pong payload")
+		return h.extendReadDeadline(conn)
+	}
+	h.rtt.Store(int64(h.clock.Now().Sub(time.Unix(0, sentAt))))
+	return h.extendReadDeadline(conn)
+}
+
+// wrapDialErr wraps connection <err>, telling apart failure to reach proxy at <proxyURL> and failure to reach server
+// through it. <proxyURL> is nil if connection is direct.
+func wrapDialErr(err error, proxyURL *url.URL) error {
+	if proxyURL == nil {
+		return errors.Wrap(err, "Connect to server")
+	}
+	// When proxy is used, the only network dial is the one to proxy itself
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if opErr, ok := e.(*net.OpError); ok && opErr.Op == "dial" {
+			return errors.Wrapf(err, "Connect to proxy %v", proxyURL.Host)
+		}
+	}
+	return errors.Wrapf(err, "Connect to server through proxy %v", proxyURL.Host)
 }
 
 // AddOnDisconnectListener registers function <l> to be run when connection to server is lost.
 func (h *Handler) AddOnDisconnectListener(l func(error)) {
+	h.listenersMu.Lock()
+	defer h.listenersMu.Unlock()
 	h.onDisconnect = append(h.onDisconnect, l)
 }
 
-// CloseConn sends close message to server and closes underlying network connection.
+// CloseConn sends close message to server, closes underlying network connection and connection state channel. If close
+// message can't be sent within closeTimeout, connection is closed anyway. If connection was never established, only
+// the state channel is closed. Listen returns once connection is closed. Subsequent calls do nothing.
 func (h *Handler) CloseConn() {
-	err := h.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
-	if err != nil {
-		h.log.Error(errors.Wrap(err, "Write close connection message"))
+	h.closeOnce.Do(func() {
+		h.closing.Store(true)
+		defer h.closeStates()
+		defer h.setState(StateDisconnected)
+		conn := h.currentConn()
+		if conn == nil {
+			return
+		}
+		msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		err := conn.WriteControl(websocket.CloseMessage, msg, h.clock.Now().Add(closeTimeout))
+		if err != nil {
+			h.log.WithError(err).Error("Write close connection message")
+		}
+		if err = conn.Close(); err != nil {
+			h.log.WithError(err).Error("Close connection")
+		}
+	})
+}
+
+// Reconnect forces reconnection to server. If Connect is waiting before the next attempt, it retries immediately.
+// Otherwise, if connected, connection is closed, so disconnect listeners run with ErrReconnectRequested and can connect
+// again. It returns false if there is nothing to do, e.g. connection attempt is in progress.
+func (h *Handler) Reconnect() bool {
+	select {
+	case h.retryCh <- struct{}{}:
+		return true
+	default:
 	}
-	if err = h.conn.Close(); err != nil {
-		h.log.Error(errors.Wrap(err, "Close connection"))
+	if h.State() != StateConnected {
+		return false
 	}
+	h.dropped.Store(true)
+	conn := h.currentConn()
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if err := conn.WriteControl(websocket.CloseMessage, msg, h.clock.Now().Add(closeTimeout)); err != nil {
+		h.log.WithError(err).Debug("Write close connection message")
+	}
+	if err := conn.Close(); err != nil {
+		h.log.WithError(err).Error("Close connection")
+	}
+	return true
 }
 
-// AddOnRespListener registers function <l> to be run when client receives a message from server.
+// AddOnRespListener registers function <l> to be run when client receives any message from server.
 func (h *Handler) AddOnRespListener(l func(map[string]any)) {
+	h.listenersMu.Lock()
+	defer h.listenersMu.Unlock()
 	h.onResponse = append(h.onResponse, l)
 }
 
-// Listen listens for incoming messages, blocking current goroutine until unknown read error occurs. It runs
-// on disconnect and on response listeners.
-func (h *Handler) Listen() error {
+// Listen listens for incoming messages, blocking current goroutine until unknown read error occurs, <ctx> is cancelled
+// or CloseConn is called. It runs on disconnect, on response and on type listeners. Connection is considered lost if no data,
+// including pongs, is received within Options.ReadTimeout. Messages which are not valid JSON objects are logged and
+// skipped. Disconnect listeners are run once per lost connection, reading is resumed once connection is established
+// again.
+func (h *Handler) Listen(ctx context.Context) error {
 	for {
 		var resp map[string]any
-		err := h.conn.ReadJSON(&resp)
+		conn := h.currentConn()
+		err := h.extendReadDeadline(conn)
+		if err == nil {
+			err = conn.ReadJSON(&resp)
+		}
+		if ctx.Err() != nil || h.closing.Load() {
+			return nil
+		}
 		var closeErr *websocket.CloseError
 		var netErr net.Error
-		if errors.As(err, &closeErr) || errors.As(err, &netErr) {
-			for _, listener := range h.onDisconnect {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		// Message ending in the middle of JSON value is reported as unexpected EOF, unlike connection ending there
+		if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+			h.log.WithError(err).Warn("Skip malformed message from server")
+			continue
+		} else if errors.As(err, &closeErr) || errors.As(err, &netErr) {
+			if h.dropped.Swap(false) {
+				err = ErrReconnectRequested
+			} else if netErr != nil && netErr.Timeout() {
+				err = errors.Wrapf(err, "No data received from server within %v", h.readTimeout())
+			}
+			h.setDisconnectErr(err)
+			h.setState(StateDisconnected)
+			h.listenersMu.Lock()
+			listeners := slices.Clone(h.onDisconnect)
+			h.listenersMu.Unlock()
+			for _, listener := range listeners {
 				listener(err)
 			}
+			if !h.awaitConnected(ctx) {
+				return nil
+			}
 			continue
 		} else if err != nil {
 			return errors.Wrap(err, "Read JSON from connection")
 		}
-		for _, listener := range h.onResponse {
-			listener(resp)
+		h.dispatch(resp)
+	}
+}
+
+// awaitConnected blocks until connection is established and returns true, or returns false if <ctx> is cancelled
+// first. Disconnect listeners normally restore connection before returning, but if they don't, e.g. once user is
+// kicked, reading from the failed connection would run them again and again for the same disconnect.
+func (h *Handler) awaitConnected(ctx context.Context) bool {
+	for h.State() != StateConnected {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-h.connected:
 		}
 	}
+	return true
+}
+
+// extendReadDeadline sets deadline for the next read from <conn> to Options.ReadTimeout from now.
+func (h *Handler) extendReadDeadline(conn *websocket.Conn) error {
+	if err := conn.SetReadDeadline(h.clock.Now().Add(h.readTimeout())); err != nil {
+		return errors.Wrap(err, "Set read deadline")
+	}
+	return nil
 }
 
-// Sends JSON encoding of <req> to server.
+// readTimeout returns maximum time to wait for any data from server.
+func (h *Handler) readTimeout() time.Duration {
+	return lo.Ternary(h.opts.ReadTimeout > 0, h.opts.ReadTimeout, defaultReadTimeout)
+}
+
+// WriteJSON sends JSON encoding of <req> to server. It's safe to call it concurrently. It returns error if connection
+// was never established.
 func (h *Handler) WriteJSON(req any) error {
-	return h.conn.WriteJSON(req)
+	conn := h.currentConn()
+	if conn == nil {
+		return errors.New("Not connected to server")
+	}
+	h.writeMu.Lock()
+	defer h.writeMu.Unlock()
+	return conn.WriteJSON(req)
+}
+
+// currentConn returns the last established connection, or nil if connection was never established.
+func (h *Handler) currentConn() *websocket.Conn {
+	h.connMu.Lock()
+	defer h.connMu.Unlock()
+	return h.conn
 }
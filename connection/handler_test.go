@@ -0,0 +1,509 @@
+package connection
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go_chat_client/protocol"
+	"go_chat_client/util/clock"
+	"go_chat_client/util/wstest"
+
+	"github.com/samber/lo"
+	"github.com/sirupsen/logrus"
+)
+
+// testRetryDelay is the delay between connection attempts used in tests, short enough not to slow them down.
+const testRetryDelay = time.Millisecond * 10
+
+// newTestHandler returns handler connected to <srv>, and connection of it accepted by <srv>. Handler is closed once
+// test finishes.
+func newTestHandler(t *testing.T, srv *wstest.Server) (*Handler, *wstest.Conn) {
+	t.Helper()
+	return newTestHandlerWithOptions(t, srv, Options{RetryDelay: lo.ToPtr(testRetryDelay)})
+}
+
+// newTestHandlerWithOptions is like newTestHandler, but handler is created with <opts>.
+func newTestHandlerWithOptions(t *testing.T, srv *wstest.Server, opts Options) (*Handler, *wstest.Conn) {
+	t.Helper()
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	h, err := NewHandler(log, srv.Addr(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(h.CloseConn)
+	return h, srv.Accept()
+}
+
+// listen starts listening for messages with <h> until test finishes.
+func listen(t *testing.T, h *Handler) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := h.Listen(ctx); err != nil {
+			t.Errorf("Listen: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		h.CloseConn()
+		wg.Wait()
+	})
+}
+
+// receive returns channel receiving messages of <msgType> dispatched by <h>.
+func receive(h *Handler, msgType float64) <-chan map[string]any {
+	ch := make(chan map[string]any, 16)
+	h.AddOnTypeListener(msgType, func(resp map[string]any) {
+		ch <- resp
+	})
+	return ch
+}
+
+// next returns the next message received by <ch>, failing the test if it's not received within wstest.Timeout.
+func next[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(wstest.Timeout):
+		t.Fatalf("Nothing received within %v", wstest.Timeout)
+		var zero T
+		return zero
+	}
+}
+
+func TestHandlerLogin(t *testing.T) {
+	srv := wstest.NewServer(t)
+	h, conn := newTestHandler(t, srv)
+	logins := receive(h, protocol.TypeLoginResp)
+	listen(t, h)
+
+	err := h.WriteJSON(map[string]any{"type": protocol.TypeLoginReq, "nickname": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req := conn.ReadType(protocol.TypeLoginReq); req["nickname"] != "alice" {
+		t.Errorf("Login request nickname is %v, want alice", req["nickname"])
+	}
+	conn.Write(map[string]any{"type": protocol.TypeLoginResp, "status": protocol.StatusOk, "token": "secret"})
+
+	if resp := next(t, logins); resp["token"] != "secret" || resp["status"] != protocol.StatusOk {
+		t.Errorf("Login response is %v, want status %v and token secret", resp, protocol.StatusOk)
+	}
+}
+
+func TestHandlerCompression(t *testing.T) {
+	srv := wstest.NewCompressionServer(t)
+	out := &bytes.Buffer{}
+	log := logrus.New()
+	log.SetOutput(out)
+	log.SetLevel(logrus.DebugLevel)
+	h, err := NewHandler(log, srv.Addr(), Options{Compression: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = h.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(h.CloseConn)
+	conn := srv.Accept()
+	if !strings.Contains(out.String(), "Compression is enabled") {
+		t.Errorf("Compression is not negotiated, log: %q", out)
+	}
+	msgs := receive(h, protocol.TypeChatMessageToClient)
+	listen(t, h)
+
+	text := strings.Repeat("compressible ", 100)
+	if err = h.WriteJSON(map[string]any{"type": protocol.TypePostMessageReq, "msg": text}); err != nil {
+		t.Fatal(err)
+	}
+	if req := conn.ReadType(protocol.TypePostMessageReq); req["msg"] != text {
+		t.Errorf("Post message request text is %q, want %q", req["msg"], text)
+	}
+	conn.Write(map[string]any{"type": protocol.TypeChatMessageToClient, "msg": text})
+	if resp := next(t, msgs); resp["msg"] != text {
+		t.Errorf("Chat message text is %q, want %q", resp["msg"], text)
+	}
+}
+
+func TestHandlerPostAck(t *testing.T) {
+	srv := wstest.NewServer(t)
+	h, conn := newTestHandler(t, srv)
+	acks := receive(h, protocol.TypePostMessageResp)
+	listen(t, h)
+
+	for _, id := range []float64{1, 2} {
+		err := h.WriteJSON(map[string]any{"type": protocol.TypePostMessageReq, "msg": "hi", "id": id})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range []float64{1, 2} {
+		req := conn.ReadType(protocol.TypePostMessageReq)
+		if req["id"] != want {
+			t.Errorf("Post message request ID is %v, want %v", req["id"], want)
+		}
+		conn.Write(map[string]any{"type": protocol.TypePostMessageResp, "status": protocol.StatusOk, "id": req["id"]})
+	}
+
+	for _, want := range []float64{1, 2} {
+		if resp := next(t, acks); resp["id"] != want {
+			t.Errorf("Post message response ID is %v, want %v", resp["id"], want)
+		}
+	}
+}
+
+func TestHandlerReconnect(t *testing.T) {
+	srv := wstest.NewServer(t)
+	h, conn := newTestHandler(t, srv)
+	msgs := receive(h, protocol.TypeChatMessageToClient)
+	disconnects := make(chan error, 16)
+	h.AddOnDisconnectListener(func(err error) {
+		disconnects <- err
+		if err := h.Connect(context.Background()); err != nil {
+			t.Errorf("Reconnect: %v", err)
+		}
+	})
+	listen(t, h)
+
+	conn.Drop()
+	next(t, disconnects)
+	conn = srv.Accept()
+	conn.Write(map[string]any{"type": protocol.TypeChatMessageToClient, "msg": "welcome back"})
+
+	if msg := next(t, msgs); msg["msg"] != "welcome back" {
+		t.Errorf("Message after reconnect is %v, want 'welcome back'", msg["msg"])
+	}
+	if reconnects := h.Reconnects(); reconnects != 1 {
+		t.Errorf("Reconnects is %v, want 1", reconnects)
+	}
+	if h.LastDisconnectErr() == nil {
+		t.Error("LastDisconnectErr is nil after connection loss")
+	}
+}
+
+func TestHandlerInvite(t *testing.T) {
+	srv := wstest.NewServer(t)
+	h, conn := newTestHandlerWithOptions(t, srv, Options{Invite: "code"})
+	if invite := conn.Header().Get(inviteHeader); invite != "code" {
+		t.Errorf("Invite on the first connection is %q, want %q", invite, "code")
+	}
+
+	if err := h.Dial(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if invite := srv.Accept().Header().Get(inviteHeader); invite != "" {
+		t.Errorf("Invite is sent again on the next connection: %q", invite)
+	}
+}
+
+func TestHandlerMalformedFrame(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame string
+	}{
+		{name: "syntax error", frame: "{not json"},
+		{name: "truncated object", frame: `{"type": 5, "msg": "hi"`},
+		{name: "empty", frame: ""},
+		{name: "array", frame: `[1, 2]`},
+		{name: "string", frame: `"hello"`},
+		{name: "number", frame: `42`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := wstest.NewServer(t)
+			h, conn := newTestHandler(t, srv)
+			msgs := receive(h, protocol.TypeChatMessageToClient)
+			disconnects := make(chan error, 16)
+			h.AddOnDisconnectListener(func(err error) {
+				disconnects <- err
+			})
+			listen(t, h)
+
+			conn.WriteRaw(tt.frame)
+			conn.Write(map[string]any{"type": protocol.TypeChatMessageToClient, "msg": "still here"})
+
+			if msg := next(t, msgs); msg["msg"] != "still here" {
+				t.Errorf("Message after malformed one is %v, want 'still here'", msg["msg"])
+			}
+			select {
+			case err := <-disconnects:
+				t.Errorf("Disconnect listener is run with %v", err)
+			default:
+			}
+			if state := h.State(); state != StateConnected {
+				t.Errorf("State is %v, want %v", state, StateConnected)
+			}
+		})
+	}
+}
+
+// Run with -race to check that writes from several goroutines are serialized.
+func TestHandlerLoginDuringConcurrentPosts(t *testing.T) {
+	const posters, posts = 4, 25
+	srv := wstest.NewServer(t)
+	h, conn := newTestHandler(t, srv)
+	listen(t, h)
+
+	var wg sync.WaitGroup
+	for poster := 0; poster < posters; poster++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < posts; i++ {
+				if err := h.WriteJSON(map[string]any{"type": protocol.TypePostMessageReq, "msg": "hi"}); err != nil {
+					t.Errorf("Post: %v", err)
+				}
+			}
+		}()
+	}
+	if err := h.WriteJSON(map[string]any{"type": protocol.TypeLoginReq, "nickname": "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	counts := map[float64]int{}
+	for i := 0; i < posters*posts+1; i++ {
+		counts[conn.Read()["type"].(float64)]++
+	}
+	if counts[protocol.TypeLoginReq] != 1 || counts[protocol.TypePostMessageReq] != posters*posts {
+		t.Errorf("Server received %v, want 1 login and %v post requests", counts, posters*posts)
+	}
+}
+
+func TestHandlerWriteJSONWithoutConnect(t *testing.T) {
+	h, err := NewHandler(logrus.New(), "localhost:0", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.WriteJSON(map[string]any{"type": protocol.TypeLoginReq}); err == nil {
+		t.Error("WriteJSON without connection returned no error")
+	}
+}
+
+// Regression test: CloseConn used to dereference connection which was never established.
+func TestHandlerCloseConnWithoutConnect(t *testing.T) {
+	h, err := NewHandler(logrus.New(), "localhost:0", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.CloseConn()
+	h.CloseConn()
+
+	if state := h.State(); state != StateDisconnected {
+		t.Errorf("State is %v, want %v", state, StateDisconnected)
+	}
+	if _, ok := <-h.States(); ok {
+		t.Error("State channel is not closed")
+	}
+}
+
+// Reading from failed connection keeps returning errors until it's replaced, so disconnect listeners not restoring
+// connection, e.g. once user is kicked, must not be run again for the same disconnect.
+func TestHandlerSingleReconnectSequence(t *testing.T) {
+	srv := wstest.NewServer(t)
+	h, conn := newTestHandler(t, srv)
+	msgs := receive(h, protocol.TypeChatMessageToClient)
+	var disconnects atomic.Int32
+	disconnected := make(chan struct{}, 1)
+	h.AddOnDisconnectListener(func(err error) {
+		disconnects.Add(1)
+		select {
+		case disconnected <- struct{}{}:
+		default:
+		}
+	})
+	listen(t, h)
+
+	conn.Drop()
+	next(t, disconnected)
+	time.Sleep(testRetryDelay * 5)
+	if n := disconnects.Load(); n != 1 {
+		t.Fatalf("Disconnect listener is run %v times for the same disconnect, want 1", n)
+	}
+
+	if err := h.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	conn = srv.Accept()
+	conn.Write(map[string]any{"type": protocol.TypeChatMessageToClient, "msg": "welcome back"})
+	if msg := next(t, msgs); msg["msg"] != "welcome back" {
+		t.Errorf("Message after reconnect is %v, want 'welcome back'", msg["msg"])
+	}
+	if n := disconnects.Load(); n != 1 {
+		t.Errorf("Disconnect listener is run %v times after reconnect, want 1", n)
+	}
+}
+
+// waitForWaiters waits until <n> timers of <fake> are waiting, failing the test if it doesn't happen within
+// wstest.Timeout.
+func waitForWaiters(t *testing.T, fake *clock.Fake, n int) {
+	t.Helper()
+	deadline := time.Now().Add(wstest.Timeout)
+	for fake.Waiters() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%v timers are waiting, want %v", fake.Waiters(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHandlerCloseConnTimeout(t *testing.T) {
+	srv := wstest.NewServer(t)
+	// Deadline of close message is in the past, as if server didn't accept it within closeTimeout
+	fake := clock.NewFake(time.Now().Add(-closeTimeout * 2))
+	out := &bytes.Buffer{}
+	log := logrus.New()
+	log.SetOutput(out)
+	h, err := NewHandler(log, srv.Addr(), Options{Clock: fake})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = h.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	srv.Accept()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		h.CloseConn()
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("CloseConn blocked")
+	}
+	if !strings.Contains(out.String(), "Write close connection message") {
+		t.Errorf("Close message is sent before deadline, log: %q", out)
+	}
+	if err = h.WriteJSON(map[string]any{"type": protocol.TypeLoginReq}); err == nil {
+		t.Error("Connection is not closed once close message timed out")
+	}
+	if state := h.State(); state != StateDisconnected {
+		t.Errorf("State is %v, want %v", state, StateDisconnected)
+	}
+}
+
+func TestHandlerKeepAliveRTT(t *testing.T) {
+	const rtt = time.Millisecond * 50
+	srv := wstest.NewServer(t)
+	fake := clock.NewFake(time.Now())
+	h, conn := newTestHandlerWithOptions(t, srv, Options{Clock: fake})
+	listen(t, h)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.KeepAlive(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitForWaiters(t, fake, 1)
+	fake.Advance(pingInterval)
+	// Once KeepAlive waits for the next ping, the first one is sent.
+	waitForWaiters(t, fake, 1)
+	fake.Advance(rtt)
+	// Server answers ping while reading the next message.
+	if err := h.WriteJSON(map[string]any{"type": protocol.TypeOnlineUsersReq}); err != nil {
+		t.Fatal(err)
+	}
+	conn.ReadType(protocol.TypeOnlineUsersReq)
+
+	deadline := time.Now().Add(wstest.Timeout)
+	for h.RTT() != rtt {
+		if time.Now().After(deadline) {
+			t.Fatalf("RTT is %v, want %v", h.RTT(), rtt)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHandlerKeepAliveNotConnected(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	h, err := NewHandler(logrus.New(), "localhost:0", Options{Clock: fake})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.KeepAlive(ctx)
+	}()
+
+	waitForWaiters(t, fake, 1)
+	fake.Advance(pingInterval)
+	// KeepAlive waits for the next ping instead of sending one over connection which was never established.
+	waitForWaiters(t, fake, 1)
+	cancel()
+	<-done
+}
+
+func TestHandlerDialTimeout(t *testing.T) {
+	const timeout = time.Millisecond * 100
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Handshake request is accepted, but never answered with upgrade.
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	h, err := NewHandler(logrus.New(), strings.TrimPrefix(srv.URL, "http://"), Options{DialTimeout: timeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := h.Dial(context.Background()); err == nil {
+		t.Fatal("Dial returned no error, want timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Dial returned after %v, want about %v", elapsed, timeout)
+	}
+}
+
+func TestHandlerRetryDelay(t *testing.T) {
+	tests := []struct {
+		name  string
+		delay *time.Duration
+		want  time.Duration
+	}{
+		{name: "unset", delay: nil, want: DefaultRetryDelay},
+		{name: "no delay", delay: lo.ToPtr(time.Duration(0)), want: 0},
+		{name: "custom", delay: lo.ToPtr(time.Millisecond * 100), want: time.Millisecond * 100},
+		{name: "negative", delay: lo.ToPtr(-time.Second), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := NewHandler(logrus.New(), "localhost:0", Options{RetryDelay: tt.delay})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := h.RetryDelay(); got != tt.want {
+				t.Errorf("RetryDelay is %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
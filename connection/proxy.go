@@ -0,0 +1,63 @@
+package connection
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/cockroachdb/errors"
+	"github.com/gorilla/websocket"
+	"github.com/samber/lo"
+	"golang.org/x/net/proxy"
+)
+
+// setProxy configures <dialer> to connect through proxy at <rawURL>. Supported schemes are 'http', 'https', 'socks5'
+// and 'socks5h'. If <rawURL> is empty, proxy is taken from HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+func setProxy(dialer *websocket.Dialer, rawURL string) error {
+	if rawURL == "" {
+		dialer.Proxy = http.ProxyFromEnvironment
+		return nil
+	}
+
+	proxyURL, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.Wrap(err, "Parse proxy URL")
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https":
+		dialer.Proxy = http.ProxyURL(proxyURL)
+	case "socks5", "socks5h":
+		socksDialer, err := proxy.FromURL(proxyURL, proxy.Direct)
+		if err != nil {
+			return errors.Wrap(err, "Create SOCKS proxy dialer")
+		}
+		contextDialer, ok := socksDialer.(proxy.ContextDialer)
+		if !ok {
+			return errors.New("SOCKS proxy dialer does not support context")
+		}
+		dialer.Proxy = nil
+		dialer.NetDialContext = contextDialer.DialContext
+	default:
+		return errors.Newf("Unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+
+	return nil
+}
+
+// proxyFor returns URL of proxy used to connect to <serverURL> with settings <opts>, or nil if connection is direct.
+func proxyFor(serverURL url.URL, opts Options) *url.URL {
+	if opts.ProxyURL != "" {
+		proxyURL, err := url.Parse(opts.ProxyURL)
+		if err != nil {
+			return nil
+		}
+		return proxyURL
+	}
+	// Proxy environment variables are selected by HTTP scheme
+	serverURL.Scheme = lo.Ternary(opts.TLS, "https", "http")
+	proxyURL, err := http.ProxyFromEnvironment(&http.Request{URL: &serverURL})
+	if err != nil {
+		return nil
+	}
+	return proxyURL
+}
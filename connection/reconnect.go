@@ -0,0 +1,33 @@
+package connection
+
+import (
+	"slices"
+	"time"
+)
+
+// ReconnectEvent represents failed attempt to connect to server, on start or after connection is lost.
+type ReconnectEvent struct {
+	Attempt   int           // Number of consecutive failed attempt, starting from 1
+	NextDelay time.Duration // Time until the next attempt, 0 if GaveUp is true
+	Err       error         // Error the attempt failed with
+	GaveUp    bool          // Options.MaxAttempts is reached, no more attempts are made until Connect is called again
+}
+
+// AddOnReconnectListener registers function <l> to be run with details of every failed attempt to connect, e.g. to
+// show progress of reconnecting. Unlike state change listeners, it's run for each attempt, including the last one
+// before giving up.
+func (h *Handler) AddOnReconnectListener(l func(ReconnectEvent)) {
+	h.listenersMu.Lock()
+	defer h.listenersMu.Unlock()
+	h.onReconnect = append(h.onReconnect, l)
+}
+
+// runReconnectListeners runs functions registered with AddOnReconnectListener with <event>.
+func (h *Handler) runReconnectListeners(event ReconnectEvent) {
+	h.listenersMu.Lock()
+	listeners := slices.Clone(h.onReconnect)
+	h.listenersMu.Unlock()
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
@@ -0,0 +1,110 @@
+package connection
+
+import "slices"
+
+// State represents state of connection to server.
+type State int
+
+// represents connection states.
+const (
+	StateDisconnected State = iota
+	StateConnecting
+	StateConnected
+	StateReconnecting
+)
+
+// String returns human-readable name of the state. Used to implement fmt.Stringer interface.
+func (s State) String() string {
+	switch s {
+	case StateDisconnected:
+		return "Disconnected"
+	case StateConnecting:
+		return "Connecting"
+	case StateConnected:
+		return "Connected"
+	case StateReconnecting:
+		return "Reconnecting"
+	default:
+		return "Unknown"
+	}
+}
+
+// States returns channel receiving connection state every time it changes. If nobody reads from the channel and it's
+// buffer is full, new states are dropped. The channel is closed by CloseConn.
+func (h *Handler) States() <-chan State {
+	return h.states
+}
+
+// State returns current state of connection. It's safe to call it concurrently.
+func (h *Handler) State() State {
+	h.stateMu.Lock()
+	defer h.stateMu.Unlock()
+	return h.state
+}
+
+// Reconnects returns amount of successful reconnections since the first connection.
+func (h *Handler) Reconnects() int {
+	h.stateMu.Lock()
+	defer h.stateMu.Unlock()
+	return h.reconnects
+}
+
+// LastDisconnectErr returns error which caused the last connection loss, or nil if connection was never lost.
+func (h *Handler) LastDisconnectErr() error {
+	h.stateMu.Lock()
+	defer h.stateMu.Unlock()
+	return h.lastDisconnectErr
+}
+
+// countReconnect increments amount of successful reconnections.
+func (h *Handler) countReconnect() {
+	h.stateMu.Lock()
+	defer h.stateMu.Unlock()
+	h.reconnects++
+}
+
+// setDisconnectErr stores <err> as error which caused the last connection loss.
+func (h *Handler) setDisconnectErr(err error) {
+	h.stateMu.Lock()
+	defer h.stateMu.Unlock()
+	h.lastDisconnectErr = err
+}
+
+// AddOnStateChangeListener registers function <l> to be run with new connection state every time it changes.
+func (h *Handler) AddOnStateChangeListener(l func(State)) {
+	h.listenersMu.Lock()
+	defer h.listenersMu.Unlock()
+	h.onStateChange = append(h.onStateChange, l)
+}
+
+// setState stores <state> as current one and, if it differs from the previous one, runs state change listeners and
+// sends it to connection state channel, unless the channel is full or closed.
+func (h *Handler) setState(state State) {
+	h.stateMu.Lock()
+	if h.closed || h.state == state {
+		h.stateMu.Unlock()
+		return
+	}
+	h.state = state
+	select {
+	case h.states <- state:
+	default:
+		h.log.Debug("Connection state channel is full, dropping state ", state)
+	}
+	h.stateMu.Unlock()
+
+	h.listenersMu.Lock()
+	listeners := slices.Clone(h.onStateChange)
+	h.listenersMu.Unlock()
+	for _, listener := range listeners {
+		listener(state)
+	}
+}
+
+// closeStates closes connection state channel. States set after that are ignored.
+func (h *Handler) closeStates() {
+	h.stateMu.Lock()
+	defer h.stateMu.Unlock()
+	h.closed = true
+	close(h.states)
+}
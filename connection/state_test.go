@@ -0,0 +1,97 @@
+package connection
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"go_chat_client/util/wstest"
+
+	"github.com/sirupsen/logrus"
+)
+
+// drain returns states received by <states> until it's closed.
+func drain(states <-chan State) []State {
+	var received []State
+	for state := range states {
+		received = append(received, state)
+	}
+	return received
+}
+
+func TestSetState(t *testing.T) {
+	h, err := NewHandler(logrus.New(), "localhost:0", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var changes []State
+	h.AddOnStateChangeListener(func(state State) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, state)
+	})
+
+	h.setState(StateConnecting)
+	h.setState(StateConnecting)
+	h.setState(StateConnected)
+	h.CloseConn()
+	h.setState(StateReconnecting)
+
+	want := []State{StateConnecting, StateConnected, StateDisconnected}
+	if got := drain(h.States()); !slices.Equal(got, want) {
+		t.Errorf("State channel received %v, want %v", got, want)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(changes, want) {
+		t.Errorf("State change listeners are run with %v, want %v", changes, want)
+	}
+	if state := h.State(); state != StateDisconnected {
+		t.Errorf("State after CloseConn is %v, want %v", state, StateDisconnected)
+	}
+}
+
+func TestStatesOnReconnect(t *testing.T) {
+	srv := wstest.NewServer(t)
+	h, conn := newTestHandler(t, srv)
+	reconnected := make(chan struct{}, 1)
+	h.AddOnDisconnectListener(func(err error) {
+		if err := h.Connect(context.Background()); err != nil {
+			t.Errorf("Reconnect: %v", err)
+		}
+		reconnected <- struct{}{}
+	})
+	listen(t, h)
+
+	conn.Drop()
+	next(t, reconnected)
+	srv.Accept()
+	h.CloseConn()
+
+	want := []State{StateConnecting, StateConnected, StateDisconnected, StateReconnecting, StateConnected,
+		StateDisconnected}
+	if got := drain(h.States()); !slices.Equal(got, want) {
+		t.Errorf("State channel received %v, want %v", got, want)
+	}
+}
+
+func TestStatesBufferFull(t *testing.T) {
+	h, err := NewHandler(logrus.New(), "localhost:0", Options{StateBufferSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.setState(StateConnecting)
+	h.setState(StateConnected)
+	h.setState(StateReconnecting)
+	h.CloseConn()
+
+	want := []State{StateConnecting, StateConnected}
+	if got := drain(h.States()); !slices.Equal(got, want) {
+		t.Errorf("State channel received %v, want %v, newer states dropped", got, want)
+	}
+	if state := h.State(); state != StateDisconnected {
+		t.Errorf("State is %v, want %v", state, StateDisconnected)
+	}
+}
@@ -0,0 +1,41 @@
+package connection
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+
+	"github.com/cockroachdb/errors"
+)
+
+// tlsConfig returns TLS settings according to <opts>. Client certificate and CA bundle are loaded at once, so invalid
+// files are reported before connecting instead of failing the handshake. It returns error if files can't be loaded or
+// only one of client certificate and key is set.
+func tlsConfig(opts Options) (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
+
+	if (opts.ClientCert == "") != (opts.ClientKey == "") {
+		return nil, errors.New("Client certificate and key should be set together")
+	}
+	if opts.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Load client certificate %v with key %v", opts.ClientCert, opts.ClientKey)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if opts.CACert != "" {
+		pem, err := os.ReadFile(opts.CACert)
+		if err != nil {
+			return nil, errors.Wrap(err, "Read CA certificates")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Newf("No valid PEM certificates found in %v", opts.CACert)
+		}
+		cfg.RootCAs = pool
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,51 @@
+package connection
+
+import (
+	"context"
+	"time"
+)
+
+// Transport represents connection to server which chat handler sends requests through and receives messages from.
+// Chat handler depends only on it, so it can be used with any implementation, e.g. one which drives listeners
+// directly instead of connecting to server. Extra abilities, such as diagnostics, are provided by optional interfaces
+// below, which are checked with type assertion.
+type Transport interface {
+	Connect(ctx context.Context) error
+	Listen(ctx context.Context) error
+	CloseConn()
+	WriteJSON(req any) error
+	AddOnTypeListener(msgType float64, l func(map[string]any))
+	AddOnDisconnectListener(l func(error))
+}
+
+// Reconnector is implemented by Transport which can drop current connection to establish a new one.
+type Reconnector interface {
+	Reconnect() bool
+}
+
+// StateReporter is implemented by Transport which reports its state. Transport without it is considered connected.
+type StateReporter interface {
+	State() State
+}
+
+// RetryDelayer is implemented by Transport with configurable delay between attempts to connect. If Transport doesn't
+// implement it, DefaultRetryDelay is used.
+type RetryDelayer interface {
+	RetryDelay() time.Duration
+}
+
+// Diagnoser is implemented by Transport which measures quality of connection.
+type Diagnoser interface {
+	RTT() time.Duration
+	Reconnects() int
+	LastDisconnectErr() error
+}
+
+// Handler is a websocket Transport with all optional abilities.
+var (
+	_ Transport     = (*Handler)(nil)
+	_ Reconnector   = (*Handler)(nil)
+	_ StateReporter = (*Handler)(nil)
+	_ RetryDelayer  = (*Handler)(nil)
+	_ Diagnoser     = (*Handler)(nil)
+)
@@ -3,8 +3,10 @@ module go_chat_client
 go 1.21.4
 
 require (
+	github.com/atotto/clipboard v0.1.4
 	github.com/cockroachdb/errors v1.11.1
 	github.com/fatih/color v1.16.0
+	github.com/gen2brain/beeep v0.10.0
 	github.com/gorilla/websocket v1.5.1
 	github.com/jessevdk/go-flags v1.5.0
 	github.com/jroimartin/gocui v0.5.0
@@ -12,12 +14,19 @@ require (
 	github.com/pelletier/go-toml/v2 v2.1.1
 	github.com/samber/lo v1.39.0
 	github.com/sirupsen/logrus v1.9.3
+	golang.org/x/net v0.17.0
+	golang.org/x/term v0.13.0
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1
 )
 
 require (
+	git.sr.ht/~jackmordaunt/go-toast v1.1.2 // indirect
 	github.com/cockroachdb/logtags v0.0.0-20230118201751-21c54148d20b // indirect
 	github.com/cockroachdb/redact v1.1.5 // indirect
+	github.com/esiqveland/notify v0.13.3 // indirect
 	github.com/getsentry/sentry-go v0.18.0 // indirect
+	github.com/go-ole/go-ole v1.3.0 // indirect
+	github.com/godbus/dbus/v5 v5.1.0 // indirect
 	github.com/gogo/protobuf v1.3.2 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/kr/text v0.2.0 // indirect
@@ -27,8 +36,10 @@ require (
 	github.com/nsf/termbox-go v1.1.1 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/rogpeppe/go-internal v1.9.0 // indirect
+	github.com/sergeymakinen/go-bmp v1.0.0 // indirect
+	github.com/sergeymakinen/go-ico v1.0.0-beta.0 // indirect
+	github.com/tadvi/systray v0.0.0-20190226123456-11a2b8fa57af // indirect
 	golang.org/x/exp v0.0.0-20220303212507-bbda1eaf7a17 // indirect
-	golang.org/x/net v0.17.0 // indirect
 	golang.org/x/sys v0.15.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 )
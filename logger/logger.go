@@ -4,8 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"os"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/cockroachdb/errors"
@@ -13,22 +13,72 @@ import (
 	"github.com/jroimartin/gocui"
 	"github.com/samber/lo"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-// New returns new logger with log level <lvl> and destination <to>. It also mirroring the log to file.
-func New(lvl logrus.Level, to io.Writer) *logrus.Logger {
-	logFile, err := os.OpenFile("go_chat_client.log", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
-	if err != nil {
-		panic(errors.Wrap(err, "Open or create log file"))
-	}
-	log := logrus.Logger{
+// represents log file rotation settings.
+const (
+	logFileMaxSizeMB  = 10
+	logFileMaxBackups = 3
+)
+
+// represents log formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// New returns new logger with log level <lvl>, destination <to> and format <format>.
+func New(lvl logrus.Level, to io.Writer, format string) *logrus.Logger {
+	return &logrus.Logger{
 		Out:       to,
-		Formatter: formatter{},
+		Formatter: NewFormatter(format),
 		Level:     lvl,
 		Hooks:     make(logrus.LevelHooks),
 	}
-	log.AddHook(newFileHook(logFile))
-	return &log
+}
+
+// ParseLevel returns log level with <name>, which is one of trace, debug, info, warn, error or fatal, ignoring case.
+// Number from 0 (least verbose) to 6 (most verbose) is accepted as well. It returns error if level is unknown.
+func ParseLevel(name string) (logrus.Level, error) {
+	if number, err := strconv.Atoi(name); err == nil && number >= 0 && number <= int(logrus.TraceLevel) {
+		return logrus.Level(number), nil
+	}
+	lvl, err := logrus.ParseLevel(name)
+	if err != nil {
+		return 0, errors.Newf("Unknown log level '%v', it should be trace, debug, info, warn, error or fatal", name)
+	}
+	return lvl, nil
+}
+
+// NewFormatter returns colored human-readable formatter if <format> is FormatText and JSON formatter if it's
+// FormatJSON.
+func NewFormatter(format string) logrus.Formatter {
+	if format == FormatJSON {
+		return &logrus.JSONFormatter{}
+	}
+	return formatter{}
+}
+
+// NewOutputFormatter returns formatter as NewFormatter does, which drops entries more verbose than log level <lvl>, so
+// logger output shows less than hooks with own formatters receive, e.g. log file.
+func NewOutputFormatter(format string, lvl logrus.Level) logrus.Formatter {
+	return levelFormatter{Formatter: NewFormatter(format), lvl: lvl}
+}
+
+// levelFormatter represents logrus formatter dropping entries more verbose than <lvl>.
+type levelFormatter struct {
+	logrus.Formatter
+	lvl logrus.Level
+}
+
+// Format returns formatted []byte representation of <entry>, empty if it's more verbose than formatter level. Used to
+// implement logrus Formatter interface.
+func (f levelFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if entry.Level > f.lvl {
+		return nil, nil
+	}
+	return f.Formatter.Format(entry)
 }
 
 // formatter represents logrus formatter.
@@ -57,13 +107,17 @@ func (f formatter) Format(entry *logrus.Entry) ([]byte, error) {
 		levelColor = color.New(color.FgRed).SprintFunc()
 	case logrus.DebugLevel:
 		levelColor = color.New(color.FgBlue).SprintFunc()
+	case logrus.TraceLevel:
+		levelColor = color.New(color.FgMagenta).SprintFunc()
+	default:
+		levelColor = fmt.Sprint
 	}
 
 	level := strings.ToUpper(entry.Level.String())
 	buf.WriteString(levelColor(level))
 	buf.WriteByte(' ')
 
-	buf.WriteString(entry.Message)
+	buf.WriteString(formatMessage(entry))
 
 	buf.WriteString(formatFields(entry.Data, levelColor))
 
@@ -72,10 +126,24 @@ func (f formatter) Format(entry *logrus.Entry) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// formatFields returns formatted <fields> colored with <levelColor> as a string.
+// formatMessage returns message of <entry> followed by error set with WithError, like errors.Wrap formats them, or
+// only the error if there is no message.
+func formatMessage(entry *logrus.Entry) string {
+	err, ok := entry.Data[logrus.ErrorKey]
+	if !ok {
+		return entry.Message
+	}
+	if entry.Message == "" {
+		return fmt.Sprint(err)
+	}
+	return fmt.Sprintf("%v: %v", entry.Message, err)
+}
+
+// formatFields returns formatted <fields> colored with <levelColor> as a string. Error field is skipped, since it's
+// shown by formatMessage.
 func formatFields(fields logrus.Fields, levelColor func(a ...any) string) string {
 	var sb strings.Builder
-	keys := lo.Keys(fields)
+	keys := lo.Without(lo.Keys(fields), logrus.ErrorKey)
 	slices.Sort(keys)
 	for _, key := range keys {
 		val := fields[key]
@@ -87,14 +155,34 @@ func formatFields(fields logrus.Fields, levelColor func(a ...any) string) string
 	return sb.String()
 }
 
+// fileFormatter represents logrus formatter for log file. Same as formatter, but without colors and with date.
+type fileFormatter struct{}
+
+// Format returns formatted []byte representation of <entry>. Used to implement logrus Formatter interface.
+func (f fileFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	time := entry.Time.Format("2006-01-02 15:04:05")
+	level := strings.ToUpper(entry.Level.String())
+	return []byte(fmt.Sprintf("%s %s %s%s\n", time, level, formatMessage(entry), formatFields(entry.Data, nil))), nil
+}
+
 // fileHook represents logrus file hook.
 type fileHook struct {
-	file *os.File
+	file      io.Writer
+	formatter logrus.Formatter
 }
 
-// newFileHook returns new logrus file hook.
-func newFileHook(file *os.File) fileHook {
-	return fileHook{file: file}
+// NewFileHook returns new logrus hook mirroring the log to file at <path> in format <format>. The file is rotated when
+// it grows bigger than logFileMaxSizeMB, keeping up to logFileMaxBackups old files.
+func NewFileHook(path string, format string) fileHook {
+	file := &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    logFileMaxSizeMB,
+		MaxBackups: logFileMaxBackups,
+	}
+	if format == FormatJSON {
+		return fileHook{file: file, formatter: &logrus.JSONFormatter{}}
+	}
+	return fileHook{file: file, formatter: fileFormatter{}}
 }
 
 // Levels returns which levels to fire the hook at. Used to implement logrus Hook interface.
@@ -104,26 +192,30 @@ func (h fileHook) Levels() []logrus.Level {
 
 // Fire is executed when the hook runs, writing formatted <entry> to file. Used to implement logrus Hook interface.
 func (h fileHook) Fire(entry *logrus.Entry) error {
-	time := entry.Time.Format("15:04:05")
-	level := strings.ToUpper(entry.Level.String())
-	msg := fmt.Sprintf("%s %s %s%s\n", time, level, entry.Message, formatFields(entry.Data, nil))
-	_, err := h.file.WriteString(msg)
-	return err
+	msg, err := h.formatter.Format(entry)
+	if err != nil {
+		return errors.Wrap(err, "Format log entry")
+	}
+	_, err = h.file.Write(msg)
+	return errors.Wrap(err, "Write to log file")
 }
 
 // chatUIHook represents logrus chat UI hook.
 type chatUIHook struct {
 	gui *gocui.Gui
+	lvl logrus.Level
 }
 
-// newFileHook returns new logrus chat UI hook.
-func NewChatUIHook(gui *gocui.Gui) chatUIHook {
-	return chatUIHook{gui: gui}
+// NewChatUIHook returns new logrus chat UI hook, firing at log level <lvl> and less verbose ones.
+func NewChatUIHook(gui *gocui.Gui, lvl logrus.Level) chatUIHook {
+	return chatUIHook{gui: gui, lvl: lvl}
 }
 
 // Levels returns which levels to fire the hook at. Used to implement logrus Hook interface.
 func (h chatUIHook) Levels() []logrus.Level {
-	return logrus.AllLevels
+	return lo.Filter(logrus.AllLevels, func(lvl logrus.Level, _ int) bool {
+		return lvl <= h.lvl
+	})
 }
 
 // Fire is executed when the hook runs, updating chat GUI to reflect changes in internal buffers of every view in it.
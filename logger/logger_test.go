@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    logrus.Level
+		wantErr bool
+	}{
+		{name: "trace", want: logrus.TraceLevel},
+		{name: "Debug", want: logrus.DebugLevel},
+		{name: "INFO", want: logrus.InfoLevel},
+		{name: "warn", want: logrus.WarnLevel},
+		{name: "warning", want: logrus.WarnLevel},
+		{name: "error", want: logrus.ErrorLevel},
+		{name: "fatal", want: logrus.FatalLevel},
+		{name: "0", want: logrus.PanicLevel},
+		{name: "4", want: logrus.InfoLevel},
+		{name: "6", want: logrus.TraceLevel},
+		{name: "7", wantErr: true},
+		{name: "-1", wantErr: true},
+		{name: "verbose", wantErr: true},
+		{name: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lvl, err := ParseLevel(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel(%q) returned error %v, want error: %v", tt.name, err, tt.wantErr)
+			}
+			if !tt.wantErr && lvl != tt.want {
+				t.Errorf("ParseLevel(%q) = %v, want %v", tt.name, lvl, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatEveryLevel(t *testing.T) {
+	for _, lvl := range logrus.AllLevels {
+		t.Run(lvl.String(), func(t *testing.T) {
+			entry := &logrus.Entry{Level: lvl, Message: "hello", Data: logrus.Fields{"key": "value"}}
+			out, err := formatter{}.Format(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := strings.ToUpper(lvl.String()) + " hello key=value\n"
+			if !strings.HasSuffix(string(out), want) {
+				t.Errorf("Formatted entry is %q, want it to end with %q", out, want)
+			}
+		})
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	err := errors.Wrap(errors.New("connection refused"), "Dial")
+	tests := []struct {
+		name string
+		msg  string
+		data logrus.Fields
+		want string
+	}{
+		{"with message", "Connect", logrus.Fields{logrus.ErrorKey: err, "key": "value"},
+			" Connect: Dial: connection refused key=value\n"},
+		{"without message", "", logrus.Fields{logrus.ErrorKey: err}, " Dial: connection refused\n"},
+		{"without error", "Connect", logrus.Fields{"key": "value"}, " Connect key=value\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &logrus.Entry{Level: logrus.ErrorLevel, Message: tt.msg, Data: tt.data}
+			for name, f := range map[string]logrus.Formatter{"text": formatter{}, "file": fileFormatter{}} {
+				out, err := f.Format(entry)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.HasSuffix(string(out), tt.want) {
+					t.Errorf("Entry formatted for %v is %q, want it to end with %q", name, out, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONErrorField(t *testing.T) {
+	out := &bytes.Buffer{}
+	log := New(logrus.InfoLevel, out, FormatJSON)
+	log.WithError(errors.Wrap(errors.New("connection refused"), "Dial")).Error("Connect to server")
+
+	var line map[string]any
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("Decode log line %q: %v", out, err)
+	}
+	want := map[string]any{"level": "error", "msg": "Connect to server", "error": "Dial: connection refused"}
+	for key, val := range want {
+		if line[key] != val {
+			t.Errorf("%v is %v, want %v", key, line[key], val)
+		}
+	}
+}
+
+func TestChatUIHookLevels(t *testing.T) {
+	for _, uiLvl := range []logrus.Level{logrus.ErrorLevel, logrus.InfoLevel, logrus.TraceLevel} {
+		t.Run(uiLvl.String(), func(t *testing.T) {
+			levels := NewChatUIHook(nil, uiLvl).Levels()
+			for _, lvl := range logrus.AllLevels {
+				if fired, want := slices.Contains(levels, lvl), lvl <= uiLvl; fired != want {
+					t.Errorf("Hook fires at %v: %v, want %v", lvl, fired, want)
+				}
+			}
+		})
+	}
+}
@@ -1,27 +1,50 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strconv"
+	"sync"
+	"time"
 
 	"go_chat_client/chat"
 	"go_chat_client/cli"
+	"go_chat_client/client"
 	"go_chat_client/config"
 	"go_chat_client/connection"
 	"go_chat_client/logger"
 	"go_chat_client/ui"
 	stdinUtil "go_chat_client/util/stdin"
+	"go_chat_client/version"
 
+	"github.com/cockroachdb/errors"
+	"github.com/fatih/color"
 	goFlags "github.com/jessevdk/go-flags"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
 )
 
+// oneShotTimeout is the time to wait for login and message confirmation in one-shot send mode.
+const oneShotTimeout = time.Second * 10
+
+// flushTimeout is the maximum time to wait on exit for server to confirm messages sent right before it.
+const flushTimeout = time.Second * 3
+
 func main() {
-	log := logger.New(logrus.FatalLevel, os.Stderr)
+	log := logger.New(logrus.FatalLevel, os.Stderr, logger.FormatText)
 
 	flags, err := cli.Parse()
 	if flags.Version {
-		fmt.Println("v0.1.0")
+		fmt.Println(version.String())
+		os.Exit(0)
+	}
+	if flags.Completion != "" && err == nil {
+		script, err := cli.CompletionScript(flags.Completion)
+		if err != nil {
+			log.WithError(err).Fatal()
+		}
+		fmt.Print(script)
 		os.Exit(0)
 	}
 	if cli.IsErrOfType(err, goFlags.ErrHelp) {
@@ -29,71 +52,475 @@ func main() {
 		os.Exit(0)
 	}
 	if err != nil {
-		log.Fatal(err)
+		log.WithError(err).Fatal()
 	}
 
-	log.SetLevel(flags.LogLevel)
+	// Level from config is applied once it's read
+	lvl, err := logLevel(flags, &config.Config{})
+	if err != nil {
+		log.WithError(err).Fatal()
+	}
+	// Colors of output outside of UI are disabled as well if it's not a terminal, e.g. redirected to file
+	color.NoColor = colorsDisabled(flags) || !term.IsTerminal(int(os.Stderr.Fd()))
+	log.SetLevel(lvl)
+	log.SetFormatter(logger.NewOutputFormatter(flags.LogFormat, outputLevel(lvl, flags.Quiet)))
+	if flags.LogFile != "" {
+		log.AddHook(logger.NewFileHook(flags.LogFile, flags.LogFormat))
+	}
 
 	cfg, err := config.Read()
-	if err != nil {
-		log.Debug(err)
+	isFirstRun := errors.Is(err, config.ErrConfigNotFound)
+	if err != nil && !isFirstRun {
+		log.WithError(err).Warn("Use default settings, config file will be overwritten once settings are saved")
+	}
+	if err = cfg.Validate(); err != nil {
+		log.WithError(err).Fatal()
+	}
+	if lvl, err = logLevel(flags, cfg); err != nil {
+		log.WithError(err).Fatal()
+	}
+	quiet := flags.Quiet || cfg.Quiet
+	log.SetLevel(lvl)
+	log.SetFormatter(logger.NewOutputFormatter(flags.LogFormat, outputLevel(lvl, quiet)))
+
+	// Flags take precedence over environment, which takes precedence over config, which takes precedence over prompts
+	if err = config.ApplyEnv(cfg); err != nil {
+		log.WithError(err).Fatal()
+	}
+	if flags.Server != "" {
+		cfg.ServerAddress = flags.Server
+	}
+	if tlsMode := flags.TLSMode(); tlsMode != nil {
+		cfg.TLSMode = tlsMode
+	}
+	if flags.Nickname != "" {
+		if err := chat.ValidateNickname(flags.Nickname); err != nil {
+			log.WithError(err).Fatal("Validate --nickname")
+		}
+		cfg.Nickname = flags.Nickname
+	} else if err := chat.ValidateNickname(cfg.Nickname); cfg.Nickname != "" && err != nil {
+		log.WithError(err).Warn("Validate nickname from config or environment")
+		cfg.Nickname = ""
+	}
+
+	prompter := stdinUtil.NewPrompter(log, os.Stdin)
+	if cfg.ServerAddress == "" && !flags.Offline {
+		if cfg.ServerAddress, err = prompter.AskServerAddress(); err != nil {
+			logPromptErr(log, err)
+			return
+		}
+	}
+	if cfg.TLSMode == nil && !flags.Offline {
+		if cfg.TLSMode, err = prompter.AskTLSMode(); err != nil {
+			logPromptErr(log, err)
+			return
+		}
 	}
 
-	if cfg.ServerAddress == "" {
-		cfg.ServerAddress = stdinUtil.AskServerAddress(log)
+	// Cancelled with cause if program should exit because of error, e.g. once login prompt fails
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	if flags.Invite != "" {
+		cfg.Invite = flags.Invite
+	}
+	if flags.Proxy != "" {
+		cfg.ProxyURL = flags.Proxy
+	}
+	if flags.ClientCert != "" {
+		cfg.ClientCert = flags.ClientCert
+	}
+	if flags.ClientKey != "" {
+		cfg.ClientKey = flags.ClientKey
+	}
+	if flags.CACert != "" {
+		cfg.CACert = flags.CACert
 	}
-	if cfg.TLSMode == nil {
-		cfg.TLSMode = stdinUtil.AskTLSMode(log)
+	if flags.RetryDelay != "" {
+		delay, err := strconv.ParseFloat(flags.RetryDelay, 64)
+		if err != nil || delay < 0 {
+			log.Fatalf("Invalid --reconnect-delay '%v', it should be non-negative number of seconds", flags.RetryDelay)
+		}
+		cfg.ReconnectDelay = &delay
 	}
 
-	connHandler := connection.NewHandler(log, *cfg.TLSMode, cfg.ServerAddress)
-	connHandler.Connect()
+	var transport connection.Transport
+	if flags.Offline {
+		transport = chat.NewOfflineTransport(log)
+	} else {
+		transport = connect(ctx, log, cfg, flags)
+	}
+	cfg.Invite = ""
 
-	defer connHandler.CloseConn()
+	defer transport.CloseConn()
 
 	if cfg.Nickname == "" {
-		cfg.Nickname = stdinUtil.AskNickname(log)
+		if cfg.Nickname, err = prompter.AskNickname(chat.ValidateNickname); err != nil {
+			logPromptErr(log, err)
+			return
+		}
+	}
+
+	var password string
+	if flags.Password {
+		if password, err = prompter.AskPassword(); err != nil {
+			logPromptErr(log, err)
+			return
+		}
+	}
+
+	if flags.Message != "" {
+		sendOnce(ctx, log, cfg, transport, prompter, password, flags.Message)
+		return
+	}
+
+	chatHandler := chat.NewHandler(log, cfg, transport)
+	chatHandler.Prompter = prompter
+	chatHandler.Password = password
+	if flags.ChatLog != "" {
+		if err := chatHandler.OpenChatLog(flags.ChatLog); err != nil {
+			log.WithError(err).Error()
+		}
+		defer chatHandler.CloseChatLog()
 	}
 
+	chatHandler.HandleAll(ctx, cancel)
+
+	// Listening outlives <ctx>, so confirmations of messages sent right before exit are still received
+	listenCtx, stopListening := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
 	go func() {
-		if err := connHandler.Listen(); err != nil {
-			log.Fatal(err)
+		defer wg.Done()
+		if err := transport.Listen(listenCtx); err != nil {
+			log.WithError(err).Fatal()
 		}
 	}()
 
-	chatHandler := chat.NewHandler(log, cfg, connHandler)
+	if err := chatHandler.Start(ctx); err != nil {
+		stopListening()
+		transport.CloseConn()
+		wg.Wait()
+		logPromptErr(log, err)
+		return
+	}
 
-	chatHandler.HandleOnDisconnect()
-	chatHandler.HandleLoginResponse()
-	chatHandler.LoginAndWaitForToken()
+	if err := ui.SetNicknamePalette(cfg.NicknameColors); err != nil {
+		log.WithError(err).Error()
+	}
+	if err := ui.SetNicknameColors(cfg.ColorOverrides); err != nil {
+		log.WithError(err).Error()
+	}
+	if err := ui.SetSystemStyle(cfg.SystemLabel, cfg.SystemColor); err != nil {
+		log.WithError(err).Error()
+	}
 
-	chatUI, err := ui.NewChat(log)
+	outsideUINoColor := color.NoColor
+	color.NoColor = colorsDisabled(flags)
+	chatUI, err := ui.NewChat(log, ui.Options{
+		CompactTimestamps: cfg.CompactTimestamps,
+		TimestampFormat:   cfg.TimestampFormat,
+		RelativeTimes:     cfg.RelativeTimestamps,
+		KeyBindings:       cfg.KeyBindings,
+		DisableMouse:      cfg.DisableMouse,
+		NewMessagesBanner: cfg.NewMessagesBanner,
+		MaxMessageLength:  cfg.MessageLengthLimit(),
+		MessageTTL:        time.Second * time.Duration(cfg.MessageTTL),
+		Scrollback:        cfg.Scrollback,
+		SkipQuitConfirm:   cfg.SkipQuitConfirm,
+		Notifications:     cfg.Notifications,
+		DisableFormatting: cfg.DisableFormatting,
+		DisableOpenURL:    cfg.DisableOpenURL,
+		OnlineBoxWidth:    cfg.OnlineBoxWidth,
+		OnlineBoxLeft:     cfg.OnlineBoxLeft,
+		HistoryFile:       historyFile(log, cfg),
+	})
 	if err != nil {
-		log.Fatal(err)
+		log.WithError(err).Fatal()
 	}
-	chatHandler.ChatUI = chatUI
+	panes := make([]*ui.Chat, len(cfg.Connections))
+	for i := range panes {
+		panes[i] = chatUI.AddPane()
+	}
+	chatUI.SetIdentity(cfg.Nickname, cfg.ServerAddress)
+	chatUI.SetStatus(ui.StatusOnline)
+	uiClosed := make(chan struct{})
 	go func() {
+		defer close(uiClosed)
 		err := chatUI.Draw()
 		if err != nil {
-			log.Fatal(err)
+			log.WithError(err).Fatal()
 		}
+		cancel(nil)
 	}()
-	go chatUI.UpdateOnlineBox()
+	go chatUI.UpdateOnlineBox(ctx)
+	go chatUI.ExpireMessages(ctx)
+	go chatUI.RefreshTimestamps(ctx)
 
-	chatBoxView := chatUI.WaitForView(ui.ChatBoxName)
-	log.SetOutput(chatBoxView)
-	log.AddHook(logger.NewChatUIHook(chatUI.Gui))
+	chatUI.WaitForView(ui.ChatBoxName)
+	log.SetOutput(chatUI)
+	log.SetFormatter(logger.NewOutputFormatter(logger.FormatText, outputLevel(lvl, quiet)))
+	log.AddHook(logger.NewChatUIHook(chatUI.Gui, outputLevel(lvl, quiet)))
 
-	chatUI.AddOnMsgSendListener(chatHandler.PostMessage)
+	chatHandler.SetChatUI(chatUI)
+	go chatHandler.AutoAway(ctx)
+	chatHandler.PrintBacklog()
+	if isFirstRun {
+		chatHandler.PrintWelcome()
+	}
+
+	chatUI.AddOnMsgSendListener(chatHandler.HandleInput)
 	chatUI.AddOnOnlineBoxOpenListener(chatHandler.RequestOnlineUsers)
+	chatUI.AddOnTypingListener(chatHandler.SendTyping)
+	chatUI.AddOnReactListener(chatHandler.React)
+	chatUI.AddOnOnlineBoxToggleListener(func(open bool) {
+		cfg.OnlineBoxOpen = open
+		writeConfig(log, cfg)
+	})
+	if cfg.OnlineBoxOpen {
+		chatUI.OpenOnlineBox()
+	}
+	chatHandler.AddOnLoginListener(chatUI.RefreshOnlineBox)
+	chatHandler.AddOnConfigChangeListener(func() {
+		writeConfig(log, cfg)
+	})
+
+	writeConfig(log, cfg)
 
-	chatHandler.HandleChatMsgToClient()
-	chatHandler.HandlePostMessageResponse()
-	chatHandler.HandleOnlineUsers()
+	for i, conn := range cfg.Connections {
+		go runConnection(ctx, cfg.ForConnection(conn), flags, outputLevel(lvl, quiet), panes[i])
+	}
 
-	if err = config.Write(cfg); err != nil {
-		log.Error(err)
+	<-ctx.Done()
+	chatUI.Quit()
+	<-uiClosed
+	color.NoColor = outsideUINoColor
+	log.SetOutput(os.Stderr)
+	log.SetFormatter(logger.NewOutputFormatter(flags.LogFormat, outputLevel(lvl, quiet)))
+	if !chatHandler.Flush(flushTimeout) {
+		log.Warn("Some messages are not confirmed by server and may be lost")
+	}
+	stopListening()
+	transport.CloseConn()
+	wg.Wait()
+	if err := context.Cause(ctx); !errors.Is(err, context.Canceled) {
+		logPromptErr(log, err)
 	}
+}
+
+// sendOnce logs in with <password>, posts <msg> and waits for server confirmation without starting the UI. If login is
+// rejected, it asks for new credentials with <prompter>. It exits the program with non-zero code if message was not
+// posted.
+func sendOnce(ctx context.Context, log *logrus.Logger, cfg *config.Config, transport connection.Transport,
+	prompter *stdinUtil.Prompter, password string, msg string) {
+	chatClient := client.NewWithTransport(log, transport, client.Options{
+		Nickname:         cfg.Nickname,
+		Password:         password,
+		MaxMessageLength: cfg.MessageLengthLimit(),
+		Timeout:          oneShotTimeout,
+		Handshake:        cfg.Handshake,
+	})
 
-	select {}
+	err := chatClient.Connect(ctx)
+	for errors.Is(err, client.ErrNicknameRejected) || errors.Is(err, client.ErrAuthFailed) {
+		log.WithError(err).Warn()
+		if errors.Is(err, client.ErrNicknameRejected) {
+			cfg.Nickname, err = prompter.AskNickname(chat.ValidateNickname)
+		} else {
+			password, err = prompter.AskPassword()
+		}
+		if err != nil {
+			chatClient.Close()
+			logPromptErr(log, err)
+			os.Exit(0)
+		}
+		err = chatClient.Login(cfg.Nickname, password)
+	}
+	if err == nil {
+		err = chatClient.Send(msg)
+	}
+	if !chatClient.Flush(flushTimeout) && err == nil {
+		err = errors.New("Message is not confirmed by server and may be lost")
+	}
+	chatClient.Close()
+	if err != nil {
+		log.WithError(err).Error()
+		os.Exit(1)
+	}
+
+	writeConfig(log, cfg)
+	log.Info("Message posted")
+}
+
+// runConnection connects to server of extra connection <cfg> and logs in, showing it's messages and log entries of
+// <lvl> and less verbose ones in <pane> until <ctx> is cancelled. Unlike the main connection, errors are shown in
+// <pane> instead of exiting the program, rejected credentials are not asked again and settings changed by commands
+// are not saved.
+func runConnection(ctx context.Context, cfg *config.Config, flags cli.Flags, lvl logrus.Level, pane *ui.Chat) {
+	log := logger.New(lvl, pane, logger.FormatText)
+	log.SetFormatter(logger.NewOutputFormatter(logger.FormatText, lvl))
+	log.AddHook(logger.NewChatUIHook(pane.Gui, lvl))
+	pane.SetIdentity(cfg.Nickname, cfg.ServerAddress)
+	pane.SetStatus(ui.StatusConnecting)
+	if err := chat.ValidateNickname(cfg.Nickname); err != nil {
+		log.WithError(err).Errorf("Validate nickname for %v", cfg.ServerAddress)
+		pane.SetStatus(ui.StatusDisconnected)
+		return
+	}
+
+	var transport connection.Transport
+	if flags.Offline {
+		transport = chat.NewOfflineTransport(log)
+	} else {
+		connHandler, err := connection.NewHandler(log, cfg.ServerAddress, connOptions(cfg, flags))
+		if err == nil {
+			err = connHandler.Connect(ctx)
+		}
+		if err != nil {
+			log.WithError(err).Error()
+			pane.SetStatus(ui.StatusDisconnected)
+			return
+		}
+		go connHandler.KeepAlive(ctx)
+		transport = connHandler
+	}
+	defer transport.CloseConn()
+
+	// Cancelled with cause if login fails, without affecting other connections
+	loginCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	chatHandler := chat.NewHandler(log, cfg, transport)
+	chatHandler.Prompter = nil
+	chatHandler.HandleAll(ctx, cancel)
+
+	go func() {
+		if err := transport.Listen(ctx); err != nil && ctx.Err() == nil {
+			log.WithError(err).Error()
+		}
+	}()
+	pane.SetStatus(ui.StatusLoggingIn)
+	if err := chatHandler.Start(loginCtx); err != nil {
+		log.WithError(err).Error()
+		pane.SetStatus(ui.StatusDisconnected)
+		return
+	}
+	pane.SetStatus(ui.StatusOnline)
+
+	go pane.UpdateOnlineBox(ctx)
+	go pane.ExpireMessages(ctx)
+	go pane.RefreshTimestamps(ctx)
+	chatHandler.SetChatUI(pane)
+	go chatHandler.AutoAway(ctx)
+	chatHandler.PrintBacklog()
+
+	pane.AddOnMsgSendListener(chatHandler.HandleInput)
+	pane.AddOnOnlineBoxOpenListener(chatHandler.RequestOnlineUsers)
+	pane.AddOnTypingListener(chatHandler.SendTyping)
+	chatHandler.AddOnLoginListener(pane.RefreshOnlineBox)
+	pane.RefreshOnlineBox()
+
+	<-ctx.Done()
+}
+
+// connect connects to server according to <cfg> and <flags> and starts keepalive pings until <ctx> is cancelled. It
+// exits the program if connection can't be established.
+func connect(ctx context.Context, log *logrus.Logger, cfg *config.Config, flags cli.Flags) *connection.Handler {
+	connHandler, err := connection.NewHandler(log, cfg.ServerAddress, connOptions(cfg, flags))
+	if err != nil {
+		log.WithError(err).Fatal()
+	}
+	if flags.Once {
+		err = connHandler.Dial(ctx)
+	} else {
+		err = connHandler.Connect(ctx)
+	}
+	if err != nil {
+		log.WithError(err).Fatal()
+	}
+	go connHandler.KeepAlive(ctx)
+	return connHandler
+}
+
+// connOptions returns options of connection to server according to <cfg> and <flags>.
+func connOptions(cfg *config.Config, flags cli.Flags) connection.Options {
+	return connection.Options{
+		TLS:                *cfg.TLSMode,
+		InsecureSkipVerify: flags.Insecure || cfg.Insecure,
+		Path:               cfg.ServerPath,
+		Subprotocols:       cfg.Subprotocols,
+		Invite:             cfg.Invite,
+		ProxyURL:           cfg.ProxyURL,
+		ClientCert:         cfg.ClientCert,
+		ClientKey:          cfg.ClientKey,
+		CACert:             cfg.CACert,
+		MaxAttempts:        cfg.ReconnectAttempts,
+		ReadTimeout:        time.Duration(cfg.ReadTimeout) * time.Second,
+		DialTimeout:        time.Duration(cfg.DialTimeout) * time.Second,
+		Compression:        cfg.Compression,
+		RetryDelay:         cfg.RetryDelay(),
+	}
+}
+
+// logLevel returns logging level set by --log-level flag, or by log_level field of <cfg> if the flag is not set, or
+// info level if neither is set. It returns error if level is unknown.
+func logLevel(flags cli.Flags, cfg *config.Config) (logrus.Level, error) {
+	name, source := flags.LogLevel, "--log-level"
+	if name == "" {
+		name, source = cfg.LogLevel, "config value log_level"
+	}
+	lvl := logrus.InfoLevel
+	if name != "" {
+		parsed, err := logger.ParseLevel(name)
+		if err != nil {
+			return 0, errors.Wrapf(err, "Invalid %v", source)
+		}
+		lvl = parsed
+	}
+	return lvl, nil
+}
+
+// outputLevel returns log level of entries to show in terminal and chat box: <lvl>, lowered to warnings if <quiet>
+// is true. Log file gets entries of <lvl> regardless.
+func outputLevel(lvl logrus.Level, quiet bool) logrus.Level {
+	if quiet {
+		return min(lvl, logrus.WarnLevel)
+	}
+	return lvl
+}
+
+// historyFile returns path to save input history to, or empty string if it's disabled by <cfg> or user config
+// directory is unknown.
+func historyFile(log *logrus.Logger, cfg *config.Config) string {
+	if cfg.DisableHistoryFile {
+		return ""
+	}
+	path, err := config.HistoryPath()
+	if err != nil {
+		log.WithError(err).Warn("Input history will not be saved")
+		return ""
+	}
+	return path
+}
+
+// colorsDisabled returns true if user disabled colors with --no-color flag or NO_COLOR environment variable.
+func colorsDisabled(flags cli.Flags) bool {
+	return flags.NoColor || os.Getenv("NO_COLOR") != ""
+}
+
+// logPromptErr logs error <err> of prompt for user input. It exits the program with non-zero code unless prompt was
+// cancelled by user.
+func logPromptErr(log *logrus.Logger, err error) {
+	if !errors.Is(err, stdinUtil.ErrCancelled) {
+		log.WithError(err).Fatal()
+	}
+	log.WithError(err).Info()
+}
+
+// writeConfig writes <cfg> to file, warning if it's saved to fallback location because config file is read-only.
+func writeConfig(log *logrus.Logger, cfg *config.Config) {
+	if err := config.Write(cfg); errors.Is(err, config.ErrReadOnly) {
+		log.WithError(err).Warn()
+	} else if err != nil {
+		log.WithError(err).Error()
+	}
 }
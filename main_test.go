@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"go_chat_client/cli"
+	"go_chat_client/config"
+	"go_chat_client/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLogLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		flag    string
+		cfg     string
+		want    logrus.Level
+		wantErr bool
+	}{
+		{name: "default", want: logrus.InfoLevel},
+		{name: "config", cfg: "debug", want: logrus.DebugLevel},
+		{name: "flag", flag: "trace", want: logrus.TraceLevel},
+		{name: "flag over config", flag: "warn", cfg: "debug", want: logrus.WarnLevel},
+		{name: "number", flag: "6", want: logrus.TraceLevel},
+		{name: "invalid config", cfg: "loud", wantErr: true},
+		{name: "valid flag over invalid config", flag: "error", cfg: "loud", want: logrus.ErrorLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lvl, err := logLevel(cli.Flags{LogLevel: tt.flag}, &config.Config{LogLevel: tt.cfg})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("logLevel returned error %v, want error: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && lvl != tt.want {
+				t.Errorf("logLevel = %v, want %v", lvl, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputLevel(t *testing.T) {
+	levels := []logrus.Level{logrus.ErrorLevel, logrus.WarnLevel, logrus.InfoLevel, logrus.DebugLevel, logrus.TraceLevel}
+	tests := []struct {
+		name  string
+		lvl   logrus.Level
+		quiet bool
+		shown []logrus.Level
+	}{
+		{"debug", logrus.DebugLevel, false,
+			[]logrus.Level{logrus.ErrorLevel, logrus.WarnLevel, logrus.InfoLevel, logrus.DebugLevel}},
+		{"quiet debug", logrus.DebugLevel, true, []logrus.Level{logrus.ErrorLevel, logrus.WarnLevel}},
+		{"quiet error", logrus.ErrorLevel, true, []logrus.Level{logrus.ErrorLevel}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			log := logger.New(tt.lvl, out, logger.FormatText)
+			log.SetFormatter(logger.NewOutputFormatter(logger.FormatText, outputLevel(tt.lvl, tt.quiet)))
+			for _, lvl := range levels {
+				log.Log(lvl, "message at ", lvl)
+			}
+
+			for _, lvl := range levels {
+				shown, want := strings.Contains(out.String(), "message at "+lvl.String()), slices.Contains(tt.shown, lvl)
+				if shown != want {
+					t.Errorf("Entry at %v is shown: %v, want %v", lvl, shown, want)
+				}
+			}
+		})
+	}
+}
+
+func TestColorsDisabled(t *testing.T) {
+	tests := []struct {
+		name    string
+		flag    bool
+		noColor string
+		want    bool
+	}{
+		{"default", false, "", false},
+		{"flag", true, "", true},
+		{"environment", false, "1", true},
+		{"both", true, "yes", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.noColor)
+			if got := colorsDisabled(cli.Flags{NoColor: tt.flag}); got != tt.want {
+				t.Errorf("colorsDisabled = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package protocol
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/mitchellh/mapstructure"
+)
+
+// LoginReq represents login request to server.
+type LoginReq struct {
+	Type     float64 `json:"type"`
+	Nickname string  `json:"nickname"`
+	Password string  `json:"password,omitempty"`
+}
+
+// LoginResp represents login response from server.
+type LoginResp struct {
+	Type   float64 `json:"type"`
+	Token  string  `json:"token"`
+	Status float64 `json:"status"`
+}
+
+// PostMsgReq respresents post message request to server. <ID> is generated by client to match the response with.
+type PostMsgReq struct {
+	Type   float64 `json:"type"`
+	Token  string  `json:"token"`
+	Msg    string  `json:"msg"`
+	ID     int64   `json:"id"`
+	Room   string  `json:"room,omitempty"`
+	Action bool    `json:"action,omitempty"`
+}
+
+// PostMsgResp represents post message response from server. <ID> is 0 if server doesn't return IDs of messages.
+type PostMsgResp struct {
+	Type   float64 `json:"type"`
+	Status float64 `json:"status"`
+	ID     int64   `json:"id"`
+}
+
+// ChatMsgToClient represents chat message sent by server. <ID> is assigned by server, 0 if it doesn't support message
+// IDs.
+type ChatMsgToClient struct {
+	Type      float64 `json:"type"`
+	Nickname  string  `json:"nickname"`
+	Msg       string  `json:"msg"`
+	IsSystem  bool    `json:"isSystem"`
+	Priority  float64 `json:"priority"`
+	Timestamp int64   `json:"timestamp"`
+	Source    string  `json:"source"`
+	ID        int64   `json:"id"`
+	Room      string  `json:"room"`
+	Action    bool    `json:"action"`
+	Edited    bool    `json:"edited"`
+	Level     string  `json:"level"`
+}
+
+// OnlineUsersReq represents request for list of online users to send to server.
+type OnlineUsersReq struct {
+	Type  float64 `json:"type"`
+	Token string  `json:"token"`
+}
+
+// OnlineUsers represent list of online users received from server. Each user is either nickname string or OnlineUser
+// object, depending on server, see DecodeOnlineUsers.
+type OnlineUsers struct {
+	Type   float64 `json:"type"`
+	Status float64 `json:"status"`
+	Users  []any   `json:"users"`
+}
+
+// OnlineUser represents online user with details received from server.
+type OnlineUser struct {
+	Nickname string  `json:"nickname"`
+	Status   string  `json:"status"`
+	Idle     float64 `json:"idle"` // Seconds since the last activity
+	Role     string  `json:"role"`
+	Away     bool    `json:"away"`
+}
+
+// DecodeOnlineUsers returns online <users> from OnlineUsers, each being either nickname string or OnlineUser object.
+func DecodeOnlineUsers(users []any) ([]OnlineUser, error) {
+	decoded := make([]OnlineUser, 0, len(users))
+	for _, user := range users {
+		if nickname, ok := user.(string); ok {
+			decoded = append(decoded, OnlineUser{Nickname: nickname})
+			continue
+		}
+		var u OnlineUser
+		if err := mapstructure.Decode(user, &u); err != nil {
+			return nil, errors.Wrap(err, "Decode online user")
+		}
+		decoded = append(decoded, u)
+	}
+	return decoded, nil
+}
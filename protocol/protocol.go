@@ -0,0 +1,56 @@
+// Package protocol defines types of messages and statuses of responses of the chat server protocol, shared by clients
+// of it.
+package protocol
+
+// Version is the version of protocol implemented by client, announced to server in handshake request. It's increased on
+// incompatible changes of protocol.
+const Version = 1
+
+// used to distinguish between types of various JSON requests and responses.
+const (
+	TypeLoginReq float64 = iota + 1
+	TypeLoginResp
+	TypePostMessageReq
+	TypePostMessageResp
+	TypeChatMessageToClient
+	TypeOnlineUsersReq
+	TypeOnlineUsers
+	TypeHistoryReq
+	TypeHistory
+	TypePrivateMessageReq
+	TypePrivateMessageResp
+	TypePrivateMessageToClient
+	TypeTypingReq
+	TypeTypingToClient
+	TypeReportReq
+	TypeReportResp
+	TypeAwayReq
+	TypeOnlineCount
+	TypeJoinRoomReq
+	TypeLeaveRoomReq
+	TypeKicked
+	TypeEditReq
+	TypeEditResp
+	TypeDeleteReq
+	TypeDeleteResp
+	TypeMessageEdited
+	TypeMessageDeleted
+	TypeHandshakeReq
+	TypeHandshakeResp
+)
+
+// represents various statuses to receive in responses from server.
+const (
+	StatusOk float64 = iota + 1
+	StatusInvalidToken
+	StatusNameAlreadyTaken
+	StatusNameIsEmpty
+	StatusNameIsTooLong
+	StatusMessageIsEmpty
+	StatusMessageIsTooLong
+	StatusUserNotFound
+	StatusAuthFailed
+	StatusMessageNotFound
+	StatusNotAllowed
+	StatusIncompatibleVersion
+)
@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jroimartin/gocui"
+)
+
+// showBanner returns true if banner with amount of new messages should be shown: chat box is scrolled up, i.e. it's
+// not autoscrolling, and there are <unread> messages.
+func showBanner(autoscroll bool, unread int) bool {
+	return !autoscroll && unread > 0
+}
+
+// newMessagesBannerLayout is a GUI manager function for banner with amount of new messages. If it's enabled in
+// options, it's shown over the bottom of chat box while chat box is scrolled up and new messages arrive.
+func (c *Chat) newMessagesBannerLayout(gui *gocui.Gui) error {
+	chatBox, err := gui.View(ChatBoxName)
+	if err != nil {
+		return nil
+	}
+	x0, _, x1, y1, err := gui.ViewPosition(ChatBoxName)
+	if err != nil {
+		return nil
+	}
+
+	c.printMu.Lock()
+	unread := c.unread
+	c.printMu.Unlock()
+
+	if !c.opts.NewMessagesBanner || !showBanner(chatBox.Autoscroll, unread) {
+		if err := gui.DeleteView(newMessagesBannerName); err != nil && !errors.Is(err, gocui.ErrUnknownView) {
+			return errors.Wrap(err, "Delete view")
+		}
+		return nil
+	}
+
+	text := fmt.Sprintf(" ↓ %v new messages ", unread)
+	width := len([]rune(text)) + 1
+	bannerX0 := x0 + (x1-x0-width)/2
+
+	banner, err := gui.SetView(newMessagesBannerName, bannerX0, y1-2, bannerX0+width, y1)
+	if err != nil && !errors.Is(err, gocui.ErrUnknownView) {
+		return errors.Wrap(err, fmt.Sprintf("Create view for %v", newMessagesBannerName))
+	}
+	banner.Frame = false
+	banner.BgColor = gocui.ColorYellow
+	banner.FgColor = gocui.ColorBlack
+
+	banner.Clear()
+	_, err = fmt.Fprint(banner, text)
+	return errors.Wrap(err, "Print new messages banner")
+}
+
+// jumpToBottom scrolls <view> to the last line and turns autoscroll back on. If <view> is new messages banner, chat
+// box is scrolled.
+func (c *Chat) jumpToBottom(gui *gocui.Gui, view *gocui.View) error {
+	if view.Name() == newMessagesBannerName {
+		chatBox, err := gui.View(ChatBoxName)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("Get view %v", ChatBoxName))
+		}
+		view = chatBox
+	}
+	return scrollToBottom(gui, view)
+}
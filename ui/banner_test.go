@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/gocui"
+)
+
+func TestNewMessagesBanner(t *testing.T) {
+	tests := []struct {
+		name       string
+		enabled    bool
+		autoscroll bool
+		unread     int
+		want       string
+	}{
+		{name: "scrolled up with new messages", enabled: true, unread: 3, want: "↓ 3 new messages"},
+		{name: "scrolled up without new messages", enabled: true},
+		{name: "at the bottom", enabled: true, autoscroll: true, unread: 3},
+		{name: "disabled", unread: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gui := &gocui.Gui{}
+			chatBox, err := gui.SetView(ChatBoxName, 0, 0, 60, 20)
+			if err != nil && err != gocui.ErrUnknownView {
+				t.Fatal(err)
+			}
+			c := &Chat{opts: Options{NewMessagesBanner: tt.enabled}, unread: tt.unread}
+			// Banner shown before is removed once it shouldn't be shown anymore
+			chatBox.Autoscroll, c.unread = false, 1
+			if err = c.newMessagesBannerLayout(gui); err != nil {
+				t.Fatal(err)
+			}
+			chatBox.Autoscroll, c.unread = tt.autoscroll, tt.unread
+
+			if err = c.newMessagesBannerLayout(gui); err != nil {
+				t.Fatal(err)
+			}
+			banner, err := gui.View(newMessagesBannerName)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("Banner %q is shown, want none", banner.Buffer())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("Banner is not shown")
+			}
+			if text := strings.TrimSpace(banner.Buffer()); text != tt.want {
+				t.Errorf("Banner is %q, want %q", text, tt.want)
+			}
+		})
+	}
+}
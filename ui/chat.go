@@ -1,13 +1,18 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
+
+	"go_chat_client/util/clock"
 
 	"github.com/cockroachdb/errors"
-	"github.com/fatih/color"
 	"github.com/jroimartin/gocui"
 	"github.com/samber/lo"
 	"github.com/sirupsen/logrus"
@@ -15,50 +20,158 @@ import (
 
 // represents names for various views.
 const (
-	ChatBoxName    = "chat_box"
-	inputFieldName = "input_field"
-	onlineBoxName  = "online_box"
+	ChatBoxName           = "chat_box"
+	inputFieldName        = "input_field"
+	onlineBoxName         = "online_box"
+	statusBarName         = "status_bar"
+	pinsName              = "pins"
+	reactionPickerName    = "reaction_picker"
+	scrollbackSearchName  = "scrollback_search"
+	newMessagesBannerName = "new_messages_banner"
+	quitConfirmName       = "quit_confirm"
+	keysHelpName          = "keys_help"
+	tooSmallName          = "too_small"
+)
+
+// inputFieldTitle is the default title of input field.
+const inputFieldTitle = "Input"
+
+// inputFullTitle is appended to input field title once typing is stopped by Options.MaxMessageLength.
+const inputFullTitle = " - limit reached"
+
+// inputLengthWarnRatio is the part of Options.MaxMessageLength after which input length in input field title is
+// highlighted.
+const inputLengthWarnRatio = 0.9
+
+// DefaultScrollback is the maximum amount of entries kept in chat box, used if Options.Scrollback is 0.
+const DefaultScrollback = 5000
+
+// represents special values of Options.TimestampFormat.
+const (
+	DefaultTimestampFormat = "15:04:05"
+	TimestampsOff          = "off"
 )
 
+// sourceGlyphs maps devices messages can be sent from to glyphs shown after nickname.
+var sourceGlyphs = map[string]string{
+	"mobile":  "📱",
+	"desktop": "💻",
+	"web":     "🌐",
+}
+
+// typingInterval is the minimum interval between two runs of typing listeners.
+const typingInterval = time.Second * 2
+
 // Chat represents UI for chat window.
 type Chat struct {
-	Gui             *gocui.Gui
-	OnlineUsersCh   chan []string
-	log             *logrus.Logger
-	visibleViews    []string
-	currentViewIdx  int
-	onMsgSend       []func(string)
-	onOnlineBoxOpen []func()
+	Gui               *gocui.Gui
+	log               *logrus.Logger
+	clock             clock.Clock
+	opts              Options
+	keyBindings       map[string][]binding
+	visibleViews      []string
+	currentViewIdx    int
+	onlineUsers       []OnlineUser
+	onlineBoxOpen     bool
+	onlineUsersCh     chan []OnlineUser // Holds only the latest list not drawn yet
+	detailedOnline    bool
+	onlineCount       int
+	completion        completion
+	history           *history
+	search            reverseSearch
+	paste             paste
+	inputFull         bool // Typing is stopped by Options.MaxMessageLength until message gets shorter than it
+	chatSearch        scrollbackSearch
+	status            status
+	pins              pins
+	reaction          reactionPicker
+	confirmingQuit    bool
+	keysHelp          keysHelp
+	typingAt          time.Time
+	mutedUntil        atomic.Int64
+	lastKeyAt         atomic.Int64
+	printMu           sync.Mutex
+	lastTimestamp     string
+	relativeTimes     bool
+	chatBoxLog        chatBoxLog
+	drawnEntries      int
+	chatBoxStale      bool
+	urls              recentURLs
+	viewWaiters       viewWaiters
+	unread            int
+	onMsgSend         []func(string)
+	onOnlineBoxOpen   []func()
+	onOnlineBoxToggle []func(bool)
+	onTyping          []func()
+	onReact           []func(string, string)
+	main              *Chat   // Chat this one is a pane of, see AddPane. Nil for main chat
+	panes             []*Chat // Main chat followed by it's panes, empty if there are none
+	targetIdx         int     // Index of pane which is target of input field
+	chatBoxName       string  // Name of chat box view of pane, see chatBoxView
 }
 
-// NewChat returns new UI for chat window and starts it's initializaton.
-func NewChat(log *logrus.Logger) (Chat, error) {
+// Options represents chat UI settings.
+type Options struct {
+	CompactTimestamps bool                // Show message time only if it differs from time of the previous message
+	TimestampFormat   string              // Go time layout of message time. If empty, DefaultTimestampFormat is used
+	RelativeTimes     bool                // Show message time relative to now, e.g. "2m ago", instead of TimestampFormat
+	KeyBindings       map[string][]string // Key names to bind actions to by action names, replacing default keys
+	DisableMouse      bool                // Do not capture mouse, leaving text selection to terminal
+	NewMessagesBanner bool                // Show amount of new messages over chat box while it's scrolled up
+	MaxMessageLength  int                 // Maximum amount of symbols allowed to type in input field. Must be positive
+	MessageTTL        time.Duration       // Time after which messages are removed from chat box. If 0, they are kept
+	Scrollback        int                 // Maximum amount of entries kept in chat box. If 0, DefaultScrollback is used
+	SkipQuitConfirm   bool                // Quit without confirmation even if input field is not empty
+	Notifications     string              // Way to notify about new messages: NotifyOff, NotifyBell or NotifyDesktop
+	DisableFormatting bool                // Show formatting markers in messages as is instead of styling text
+	DisableOpenURL    bool                // Do not open URLs from chat box in browser, e.g. on headless systems
+	OnlineBoxWidth    int                 // Width of online users box in columns. If 0, it fits the longest line of it
+	OnlineBoxLeft     bool                // Show online users box at the left side instead of the right one
+	Clock             clock.Clock         // Source of time of printed messages and relative timestamps. If nil, clock.Real
+	HistoryFile       string              // File to load input history from and save it to. If empty, it's not saved
+}
+
+// NewChat returns new UI for chat window with settings <opts> and starts it's initializaton. It returns error if key
+// bindings in <opts> are invalid.
+func NewChat(log *logrus.Logger, opts Options) (*Chat, error) {
+	c := &Chat{
+		onlineUsersCh: make(chan []OnlineUser, 1),
+		log:           log,
+		opts:          opts,
+		history:       newHistory(historySize, opts.HistoryFile),
+		relativeTimes: opts.RelativeTimes,
+		clock:         lo.Ternary[clock.Clock](opts.Clock != nil, opts.Clock, clock.Real{}),
+	}
+	keyBindings, err := c.parseKeyBindings(opts.KeyBindings)
+	if err != nil {
+		return nil, err
+	}
+	c.keyBindings = keyBindings
+	if err = c.history.load(); err != nil {
+		log.WithError(err).Warn()
+	}
+
 	gui, err := gocui.NewGui(gocui.OutputNormal)
 	if err != nil {
-		return Chat{}, errors.Wrap(err, "Create GUI")
+		return nil, errors.Wrap(err, "Create GUI")
 	}
 
 	gui.Highlight = true
 	gui.Cursor = true
 	gui.SelFgColor = gocui.ColorGreen
+	gui.Mouse = !opts.DisableMouse
+	c.Gui = gui
 
-	return Chat{Gui: gui, OnlineUsersCh: make(chan []string), log: log}, nil
+	return c, nil
 }
 
-// WaitForView returns view with the specified <name> as soon as it becomes available.
-func (c *Chat) WaitForView(name string) *gocui.View {
-	viewCh := make(chan *gocui.View)
-	c.Gui.Update(func(g *gocui.Gui) error {
-		for {
-			view, err := g.View(name)
-			if err != nil {
-				continue
-			}
-			viewCh <- view
-			return nil
-		}
-	})
-	return <-viewCh
+// OnlineUsers returns nicknames from the last received list of online users, or nil if it wasn't received yet. Should
+// be called from UI goroutine, e.g. from message send listener.
+func (c *Chat) OnlineUsers() []string {
+	if c.onlineUsers == nil {
+		return nil
+	}
+	return nicknames(c.onlineUsers)
 }
 
 // AddOnMsgSendListener registers function <l> to be run when message from input field is sent.
@@ -66,47 +179,58 @@ func (c *Chat) AddOnMsgSendListener(l func(string)) {
 	c.onMsgSend = append(c.onMsgSend, l)
 }
 
+// AddOnTypingListener registers function <l> to be run when user types in input field, no more often than once per
+// typingInterval.
+func (c *Chat) AddOnTypingListener(l func()) {
+	c.onTyping = append(c.onTyping, l)
+}
+
 // AddOnOnlineBoxOpenListener registers function <l> to be run when online users box is open.
 func (c *Chat) AddOnOnlineBoxOpenListener(l func()) {
 	c.onOnlineBoxOpen = append(c.onOnlineBoxOpen, l)
 }
 
+// AddOnOnlineBoxToggleListener registers function <l> to be run with true when online users box is open by user and
+// with false when it's closed. Used to remember online users box state.
+func (c *Chat) AddOnOnlineBoxToggleListener(l func(bool)) {
+	c.onOnlineBoxToggle = append(c.onOnlineBoxToggle, l)
+}
+
+// OpenOnlineBox opens online users box if it's closed, without running online box toggle listeners.
+func (c *Chat) OpenOnlineBox() {
+	c.Gui.Update(func(g *gocui.Gui) error {
+		if c.onlineBoxOpen {
+			return nil
+		}
+		return c.showOnlineBox(g, true)
+	})
+}
+
+// RefreshOnlineBox runs online box open listeners again if online users box is open, so it's content is requested
+// again, e.g. after connection is restored.
+func (c *Chat) RefreshOnlineBox() {
+	c.Gui.Update(func(g *gocui.Gui) error {
+		if !c.root().onlineBoxOpen {
+			return nil
+		}
+		for _, listener := range c.onOnlineBoxOpen {
+			listener()
+		}
+		return nil
+	})
+}
+
 // Draw sets layout managers, sets keybindings and runs main UI loop, finishing initialization. It blocks until Ctrl+C
-// is pressed or unknown error occurs.
+// is pressed or unknown error occurs. Bracketed paste mode of terminal is on while main UI loop runs.
 func (c *Chat) Draw() error {
-	c.Gui.SetManager(gocui.ManagerFunc(c.chatBoxLayout), gocui.ManagerFunc(c.inputFieldLayout))
+	c.Gui.SetManager(c.managers()...)
 
-	if err := c.Gui.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone, quit); err != nil {
-		return errors.Wrap(err, "Set keybinding")
-	}
-	if err := c.Gui.SetKeybinding("", gocui.KeyTab, gocui.ModNone, c.nextView); err != nil {
-		return errors.Wrap(err, "Set keybinding")
-	}
-	if err := c.Gui.SetKeybinding("", gocui.KeyF2, gocui.ModNone, c.toggleOnlineBox); err != nil {
-		return errors.Wrap(err, "Set keybinding")
-	}
-	if err := c.Gui.SetKeybinding(inputFieldName, gocui.KeyEnter, gocui.ModNone, c.sendMessage); err != nil {
-		return errors.Wrap(err, "Set keybinding")
-	}
-	// Insert new line on F3.
-	// Why not Shift+Enter? - This library only supports Alt modifier.
-	// Why not Alt+Enter? - On Windows, Alt+Enter toggles console window fullscreen mode.
-	if err := c.Gui.SetKeybinding(inputFieldName, gocui.KeyF3, gocui.ModNone, insertNewline); err != nil {
-		return errors.Wrap(err, "Set keybinding")
-	}
-	if err := c.Gui.SetKeybinding(ChatBoxName, gocui.KeyArrowUp, gocui.ModNone, scrollUp); err != nil {
-		return errors.Wrap(err, "Set keybinding")
-	}
-	if err := c.Gui.SetKeybinding(ChatBoxName, gocui.KeyArrowDown, gocui.ModNone, scrollDown); err != nil {
-		return errors.Wrap(err, "Set keybinding")
-	}
-	if err := c.Gui.SetKeybinding(onlineBoxName, gocui.KeyArrowUp, gocui.ModNone, scrollUp); err != nil {
-		return errors.Wrap(err, "Set keybinding")
-	}
-	if err := c.Gui.SetKeybinding(onlineBoxName, gocui.KeyArrowDown, gocui.ModNone, scrollDown); err != nil {
-		return errors.Wrap(err, "Set keybinding")
+	if err := c.setKeybindings(); err != nil {
+		return err
 	}
 
+	setBracketedPaste(true)
+	defer setBracketedPaste(false)
 	if err := c.Gui.MainLoop(); err != nil && err != gocui.ErrQuit {
 		return errors.Wrap(err, "Run main UI loop")
 	}
@@ -114,109 +238,211 @@ func (c *Chat) Draw() error {
 	return nil
 }
 
-// UpdateOnlineBox redraw online users box as soon as list of users is received from the respective channel.
-// It blocks current goroutine forever.
-func (c *Chat) UpdateOnlineBox() {
+// managers returns GUI managers of chat layout in order they are run. Managers of views are skipped while terminal is
+// too small for them, see tooSmallLayout.
+func (c *Chat) managers() []gocui.Manager {
+	return []gocui.Manager{
+		c.unlessTooSmall(c.pinsLayout),
+		c.unlessTooSmall(c.chatBoxLayout),
+		c.unlessTooSmall(c.panesLayout),
+		c.unlessTooSmall(c.onlineBoxLayout),
+		c.unlessTooSmall(c.inputFieldLayout),
+		c.unlessTooSmall(c.statusBarLayout),
+		c.unlessTooSmall(c.newMessagesBannerLayout),
+		c.unlessTooSmall(c.reactionPickerLayout),
+		c.unlessTooSmall(c.scrollbackSearchLayout),
+		c.unlessTooSmall(c.keysHelpLayout),
+		c.unlessTooSmall(c.quitConfirmLayout),
+		gocui.ManagerFunc(c.tooSmallLayout),
+		gocui.ManagerFunc(c.viewWaitersLayout),
+	}
+}
+
+// UpdateOnlineBox redraws online users box as soon as list of users is set with SetOnlineUsers. It blocks current
+// goroutine until <ctx> is cancelled.
+func (c *Chat) UpdateOnlineBox(ctx context.Context) {
 	for {
-		onlineUsers := <-c.OnlineUsersCh
+		var onlineUsers []OnlineUser
+		select {
+		case <-ctx.Done():
+			return
+		case onlineUsers = <-c.onlineUsersCh:
+		}
 
 		c.Gui.Update(func(g *gocui.Gui) error {
-			onlineBox, err := g.View(onlineBoxName)
-			if err != nil {
-				return nil
-			}
-
-			onlineBox.Clear()
-			onlineBox.Title = fmt.Sprintf("%v online", len(onlineUsers))
-
-			slices.Sort(onlineUsers)
-			_, err = fmt.Fprint(onlineBox, strings.Join(onlineUsers, "\n"))
-			if err != nil {
-				c.log.Error(errors.Wrap(err, "Print online users"))
+			c.onlineUsers = onlineUsers
+			c.onlineCount = len(onlineUsers)
+			if err := c.drawOnlineBox(g); err != nil {
+				c.log.WithError(err).Error()
 			}
-
 			return nil
 		})
 	}
 }
 
-// PrintToChatBox prints <msg> to chat chat box view, prefixed with current time and <nickname>. If <isSystem> is true,
-// <nickname> is replaced with "SYSTEM" and printed with another color.
-func (c *Chat) PrintToChatBox(nickname string, msg string, isSystem bool) error {
-	chatBox, err := c.Gui.View(ChatBoxName)
-	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf("Get view %v", ChatBoxName))
+// LastKeyAt returns time of the last key press in input field, or zero time if no key is pressed yet.
+func (c *Chat) LastKeyAt() time.Time {
+	if ns := c.root().lastKeyAt.Load(); ns != 0 {
+		return time.Unix(0, ns)
 	}
-	time := color.GreenString("%v", time.Now().Format("15:04:05"))
-	if isSystem {
-		nickname = color.CyanString("%v", "SYSTEM")
-	} else {
-		nickname = color.YellowString("%v", nickname)
+	return time.Time{}
+}
+
+// Unread returns amount of messages printed while chat box is scrolled up.
+func (c *Chat) Unread() int {
+	c.printMu.Lock()
+	defer c.printMu.Unlock()
+	return c.unread
+}
+
+// SetOnlineUsers queues list of online <users> to be drawn by UpdateOnlineBox. It never blocks: if previous list is not
+// drawn yet, e.g. UpdateOnlineBox is not running, it's replaced with <users>, since only the latest list matters.
+func (c *Chat) SetOnlineUsers(users []OnlineUser) {
+	for {
+		select {
+		case c.onlineUsersCh <- users:
+			return
+		default:
+		}
+		select {
+		case <-c.onlineUsersCh:
+		default:
+		}
 	}
+}
 
-	_, err = fmt.Fprintln(chatBox, time, nickname, msg)
-	if err != nil {
-		return errors.Wrap(err, "Print message to chat box")
+// PrintToChatBox prints <msg> to chat chat box view, prefixed with current time and <nickname>. If <isSystem> is true,
+// <nickname> is replaced with "SYSTEM" and printed with another color. If <isImportant> is true, message is marked with
+// "!" sign. It's a shortcut for AppendMessage.
+func (c *Chat) PrintToChatBox(nickname string, msg string, isSystem bool, isImportant bool) error {
+	return c.AppendMessage(Message{
+		Nickname: nickname, Text: msg, IsSystem: isSystem, IsImportant: isImportant, Time: c.clock.Now(),
+	})
+}
+
+// Notify draws user attention by ringing the terminal bell, unless notifications are muted.
+func (c *Chat) Notify() {
+	if c.notificationsMuted() {
+		return
 	}
+	fmt.Print("\a")
+}
 
+// MuteNotifications suppresses notifications for duration <d>. Zero <d> unmutes notifications immediately.
+func (c *Chat) MuteNotifications(d time.Duration) {
+	c.mutedUntil.Store(c.clock.Now().Add(d).UnixNano())
+}
+
+// notificationsMuted returns true if duration set by MuteNotifications didn't pass yet.
+func (c *Chat) notificationsMuted() bool {
+	return c.clock.Now().UnixNano() < c.mutedUntil.Load()
+}
+
+// ClearChatBox clears chat box view and turns autoscroll back on. It doesn't affect history stored on server.
+func (c *Chat) ClearChatBox() {
 	c.Gui.Update(func(g *gocui.Gui) error {
-		return nil
+		return c.clearChatBox(g, nil)
 	})
+}
 
-	return nil
+// ResetChatBox clears chat box view and turns autoscroll back on immediately, so it can be filled with other messages.
+// Should be called from UI goroutine, e.g. from message send listener.
+func (c *Chat) ResetChatBox() error {
+	return c.clearChatBox(c.Gui, nil)
+}
+
+// clearChatBox clears chat box view and turns autoscroll back on.
+func (c *Chat) clearChatBox(gui *gocui.Gui, view *gocui.View) error {
+	chatBox, err := gui.View(c.chatBoxView())
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Get view %v", c.chatBoxView()))
+	}
+
+	c.printMu.Lock()
+	defer c.printMu.Unlock()
+
+	chatBox.Clear()
+	c.chatBoxLog = chatBoxLog{}
+	c.drawnEntries = 0
+	c.urls = recentURLs{}
+	c.lastTimestamp = ""
+	chatBox.Autoscroll = true
+	return errors.Wrap(chatBox.SetOrigin(0, 0), "Reset chat box origin")
 }
 
-// chatBoxLayout is a GUI manager function for chat box.
+// chatBoxLayout is a GUI manager function for chat box. While it's scrolled up, title shows amount of unread messages
+// and that autoscroll is paused. Selected line is highlighted while chat box is focused. If there are panes, chat box
+// shares the width with their chat boxes, see panesLayout.
 func (c *Chat) chatBoxLayout(gui *gocui.Gui) error {
 	maxX, maxY := gui.Size()
 
-	chatBox, err := gui.SetView(ChatBoxName, 0, 0, maxX-1, maxY-8)
-	if !errors.Is(err, gocui.ErrUnknownView) {
+	columns := paneColumns(maxX, len(c.chats()))[0]
+	y0, y1 := c.chatBoxRows(maxY)
+	chatBox, err := gui.SetView(ChatBoxName, columns[0], y0, columns[1], y1)
+	if err != nil && !errors.Is(err, gocui.ErrUnknownView) {
 		return errors.Wrap(err, fmt.Sprintf("Create view for %v", ChatBoxName))
 	}
-	c.visibleViews = append(c.visibleViews, ChatBoxName)
-	chatBox.Title = "Chat"
-	chatBox.Wrap = true
-	chatBox.Autoscroll = true
+	if errors.Is(err, gocui.ErrUnknownView) {
+		c.addVisibleView(ChatBoxName)
+		chatBox.Wrap = true
+		chatBox.Autoscroll = true
+	}
+
+	c.printMu.Lock()
+	defer c.printMu.Unlock()
+	// Scrolled back to the bottom
+	if chatBox.Autoscroll {
+		c.unread = 0
+	}
+	c.highlightSelection(gui, chatBox)
+	chatBox.Title = c.chatBoxTitle(chatBox)
 
 	return nil
 }
 
+// chatBoxRows returns the first and the last row of chat box and chat boxes of panes in terminal <maxY> rows high:
+// below pinned messages and above input field.
+func (c *Chat) chatBoxRows(maxY int) (int, int) {
+	return c.pins.height(), maxY - 9
+}
+
+// chatBoxTitle returns title of <chatBox> of <c> showing amount of unread messages and that autoscroll is paused while
+// it's scrolled up. If there are panes, it also shows nickname and server of <c> and marks target of input field. It
+// should be called with printMu locked.
+func (c *Chat) chatBoxTitle(chatBox *gocui.View) string {
+	title := "Chat"
+	if len(c.chats()) > 1 {
+		title = fmt.Sprintf("%vChat: %v@%v", lo.Ternary(c.target() == c, paneTargetMark, ""), c.status.nickname,
+			c.status.server)
+	}
+	if c.unread > 0 {
+		title += fmt.Sprintf(" (%v new)", c.unread)
+	}
+	if !chatBox.Autoscroll {
+		title += " - paused"
+		if key := c.actionKey("jump_to_bottom", chatBox.Name()); key != "" {
+			title += fmt.Sprintf(", press %v to resume", key)
+		}
+	}
+	return title
+}
+
 // inputFieldLayout is a GUI manager function for input field.
 func (c *Chat) inputFieldLayout(gui *gocui.Gui) error {
 	maxX, maxY := gui.Size()
 
-	inputField, err := gui.SetView(inputFieldName, 0, maxY-7, maxX-1, maxY-1)
-	if !errors.Is(err, gocui.ErrUnknownView) {
+	inputField, err := gui.SetView(inputFieldName, 0, maxY-8, maxX-1, maxY-2)
+	if err != nil && !errors.Is(err, gocui.ErrUnknownView) {
 		return errors.Wrap(err, fmt.Sprintf("Create view for %v", inputFieldName))
 	}
-	c.visibleViews = append(c.visibleViews, inputFieldName)
-	inputField.Title = "Input"
+	c.showInputLength(gui, inputField)
+	if err == nil {
+		return nil
+	}
+	c.addVisibleView(inputFieldName)
 	inputField.Editable = true
 	inputField.Wrap = true
-	inputField.Editor = gocui.EditorFunc(func(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
-		maxSymbols := 2000
-		if len(v.Buffer()) <= maxSymbols {
-			gocui.DefaultEditor.Edit(v, key, ch, mod)
-			return
-		}
-		switch {
-		case key == gocui.KeyBackspace || key == gocui.KeyBackspace2:
-			v.EditDelete(true)
-		case key == gocui.KeyDelete:
-			v.EditDelete(false)
-		case key == gocui.KeyArrowDown:
-			v.MoveCursor(0, 1, false)
-		case key == gocui.KeyArrowUp:
-			v.MoveCursor(0, -1, false)
-		case key == gocui.KeyArrowLeft:
-			v.MoveCursor(-1, 0, false)
-		case key == gocui.KeyArrowRight:
-			v.MoveCursor(1, 0, false)
-		default:
-			c.log.Warnf("Message is longer than %v symbols", maxSymbols)
-		}
-	})
+	inputField.Editor = gocui.EditorFunc(c.editInput)
 
 	if _, err = gui.SetCurrentView(inputFieldName); err != nil {
 		return errors.Wrap(err, fmt.Sprintf("Focus view %v", inputFieldName))
@@ -225,18 +451,110 @@ func (c *Chat) inputFieldLayout(gui *gocui.Gui) error {
 	return nil
 }
 
-// sendMessage runs listeners passing trimmed input field buffer to them, clears input filed and sets cursor to initial
-// position.
+// editInput handles <key> and <ch> with <mod> typed in input field <view>. Typing stops once message reaches
+// Options.MaxMessageLength, except for deleting and moving cursor. Newlines of pasted text are typed as is instead of
+// sending message.
+func (c *Chat) editInput(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	c.completion.reset()
+	pasting := c.isPasting()
+	c.lastKeyAt.Store(c.clock.Now().UnixNano())
+	if c.editPaste(v, key, ch, mod) {
+		return
+	}
+	if c.search.active && c.editSearch(v, key, ch, mod) {
+		return
+	}
+	if (ch != 0 && mod == 0) || key == gocui.KeySpace {
+		c.notifyTyping()
+	}
+	if inputLength(v) <= c.opts.MaxMessageLength {
+		if key == gocui.KeyCtrlJ && pasting {
+			v.EditNewLine()
+			return
+		}
+		gocui.DefaultEditor.Edit(v, key, ch, mod)
+		return
+	}
+	switch {
+	case key == gocui.KeyBackspace || key == gocui.KeyBackspace2:
+		v.EditDelete(true)
+	case key == gocui.KeyDelete:
+		v.EditDelete(false)
+	case key == gocui.KeyArrowDown:
+		v.MoveCursor(0, 1, false)
+	case key == gocui.KeyArrowUp:
+		v.MoveCursor(0, -1, false)
+	case key == gocui.KeyArrowLeft:
+		v.MoveCursor(-1, 0, false)
+	case key == gocui.KeyArrowRight:
+		v.MoveCursor(1, 0, false)
+	default:
+		c.inputFull = true
+	}
+}
+
+// showInputLength shows length of input field <view> contents in it's title, unless reverse history search is active.
+// Length close to Options.MaxMessageLength is highlighted with color of input field frame while it's focused. Once
+// typing is stopped by the limit, title says so until message gets shorter than the limit.
+func (c *Chat) showInputLength(gui *gocui.Gui, view *gocui.View) {
+	length, limit := inputLength(view), c.opts.MaxMessageLength
+	if length < limit {
+		c.inputFull = false
+	}
+	if !c.search.active {
+		view.Title = fmt.Sprintf("%v (%v/%v)%v", inputFieldTitle, length, limit, lo.Ternary(c.inputFull, inputFullTitle, ""))
+	}
+	switch {
+	case gui.CurrentView() != view || length < int(float64(limit)*inputLengthWarnRatio):
+		gui.SelFgColor = gocui.ColorGreen
+	case length < limit:
+		gui.SelFgColor = gocui.ColorYellow
+	default:
+		gui.SelFgColor = gocui.ColorRed
+	}
+}
+
+// inputLength returns amount of symbols in input field <view>.
+func inputLength(view *gocui.View) int {
+	return utf8.RuneCountInString(strings.TrimSuffix(view.Buffer(), "\n"))
+}
+
+// sendMessage runs listeners of target of input field passing trimmed input field buffer to them, saves it to input
+// history, clears input filed and sets cursor to initial position. Multi-line buffer is passed as a single message:
+// only leading and trailing whitespace is trimmed, blank lines inside are kept. If reverse history search is active,
+// it only accepts found message instead. Enter pasted as a part of text inserts newline, so message is sent only by
+// Enter pressed manually.
 func (c *Chat) sendMessage(gui *gocui.Gui, view *gocui.View) error {
 	inputField, err := gui.View(inputFieldName)
 	if err != nil {
 		return errors.Wrap(err, fmt.Sprintf("Get view %v", inputFieldName))
 	}
 
-	for _, listener := range c.onMsgSend {
-		listener(strings.TrimSpace(inputField.Buffer()))
+	if c.isPasting() {
+		c.lastKeyAt.Store(c.clock.Now().UnixNano())
+		if inputLength(inputField) <= c.opts.MaxMessageLength {
+			inputField.EditNewLine()
+		} else {
+			c.inputFull = true
+		}
+		return nil
 	}
 
+	if c.search.active {
+		c.acceptSearch(inputField)
+		return nil
+	}
+
+	msg := strings.TrimSpace(inputField.Buffer())
+	for _, listener := range c.target().onMsgSend {
+		listener(msg)
+	}
+	c.history.add(msg)
+	if err := c.history.save(); err != nil {
+		c.log.WithError(err).Warn()
+	}
+
+	c.completion.reset()
 	inputField.Clear()
 	if err = inputField.SetCursor(0, 0); err != nil {
 		return errors.Wrap(err, "Reset cursor after message was sent")
@@ -245,8 +563,25 @@ func (c *Chat) sendMessage(gui *gocui.Gui, view *gocui.View) error {
 	return nil
 }
 
-// nextView cycling between views, focusing next visible one on each call.
+// notifyTyping runs typing listeners of target of input field, unless they were run less than typingInterval ago.
+func (c *Chat) notifyTyping() {
+	if c.clock.Now().Sub(c.typingAt) < typingInterval {
+		return
+	}
+	c.typingAt = c.clock.Now()
+	for _, listener := range c.target().onTyping {
+		listener()
+	}
+}
+
+// nextView cycling between views, focusing next visible one on each call. Views can be focused without it, e.g. input
+// field on start or chat box once search is closed, so cycling starts from the focused view.
 func (c *Chat) nextView(gui *gocui.Gui, view *gocui.View) error {
+	if current := gui.CurrentView(); current != nil {
+		if idx := slices.Index(c.visibleViews, current.Name()); idx != -1 {
+			c.currentViewIdx = idx
+		}
+	}
 	nextViewIdx := (c.currentViewIdx + 1) % len(c.visibleViews)
 	nextView := c.visibleViews[nextViewIdx]
 
@@ -254,37 +589,57 @@ func (c *Chat) nextView(gui *gocui.Gui, view *gocui.View) error {
 		return errors.Wrap(err, fmt.Sprintf("Focus view %v", nextView))
 	}
 
-	gui.Cursor = lo.Ternary(view.Name() == ChatBoxName, true, false)
+	gui.Cursor = nextView == inputFieldName
 
 	c.currentViewIdx = nextViewIdx
 
 	return nil
 }
 
-// toggleOnlineBox opens online users box if it's closed and closes it if it's open.
+// focusView focuses clicked <view>.
+func (c *Chat) focusView(gui *gocui.Gui, view *gocui.View) error {
+	if _, err := gui.SetCurrentView(view.Name()); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Focus view %v", view.Name()))
+	}
+	gui.Cursor = view.Name() == inputFieldName
+	if idx := slices.Index(c.visibleViews, view.Name()); idx != -1 {
+		c.currentViewIdx = idx
+	}
+	return nil
+}
+
+// toggleOnlineBox opens online users box if it's closed and closes it if it's open, running online box toggle
+// listeners.
 func (c *Chat) toggleOnlineBox(gui *gocui.Gui, view *gocui.View) error {
-	_, err := gui.View(onlineBoxName)
+	show := !c.onlineBoxOpen
+	if err := c.showOnlineBox(gui, show); err != nil {
+		return err
+	}
+	for _, listener := range c.onOnlineBoxToggle {
+		listener(show)
+	}
+	return nil
+}
 
-	if errors.Is(err, gocui.ErrUnknownView) {
-		maxX, maxY := gui.Size()
+// showOnlineBox opens online users box if <show> is true and closes it otherwise. Open box is created and positioned
+// by onlineBoxLayout.
+func (c *Chat) showOnlineBox(gui *gocui.Gui, show bool) error {
+	c.onlineBoxOpen = show
+	if show {
+		return c.onlineBoxLayout(gui)
+	}
 
-		onlineBox, err := gui.SetView(onlineBoxName, maxX-20, 0, maxX-1, maxY-8)
-		if !errors.Is(err, gocui.ErrUnknownView) {
-			return errors.Wrap(err, fmt.Sprintf("Create view for %v", onlineBoxName))
-		}
-		c.visibleViews = append(c.visibleViews, onlineBoxName)
-		onlineBox.Title = "0 online"
+	c.visibleViews = lo.Without(c.visibleViews, onlineBoxName)
+	err := gui.DeleteView(onlineBoxName)
+	return errors.Wrap(err, "Delete view")
+}
 
-		for _, listener := range c.onOnlineBoxOpen {
-			listener()
-		}
-	} else if err == nil {
-		c.visibleViews = lo.Without(c.visibleViews, onlineBoxName)
-		err := gui.DeleteView(onlineBoxName)
-		return errors.Wrap(err, "Delete view")
+// addVisibleView adds view with <name> to views cycled by nextView, unless it's already there. Views can be created
+// again, e.g. after being deleted, so adding must not duplicate them.
+func (c *Chat) addVisibleView(name string) {
+	if !slices.Contains(c.visibleViews, name) {
+		c.visibleViews = append(c.visibleViews, name)
 	}
-
-	return nil
 }
 
 // insertNewline insert a new line under the cursor of the given <view>.
@@ -305,20 +660,67 @@ func scrollDown(gui *gocui.Gui, view *gocui.View) error {
 	return nil
 }
 
-// scroll sets origin position of the <view> internal buffer <step> rows lower. <step> can be negative. 
+// scrollToBottom sets origin position of the <view> internal buffer to the last page and turns autoscroll on.
+func scrollToBottom(gui *gocui.Gui, view *gocui.View) error {
+	_, sizeY := view.Size()
+	originX, _ := view.Origin()
+	view.Autoscroll = true
+	return errors.Wrap(view.SetOrigin(originX, max(viewRows(view)-sizeY, 0)), "Scroll to bottom")
+}
+
+// scrollToTop sets origin position of the <view> internal buffer to the first line and turns autoscroll off.
+func scrollToTop(gui *gocui.Gui, view *gocui.View) error {
+	originX, _ := view.Origin()
+	view.Autoscroll = false
+	return errors.Wrap(view.SetOrigin(originX, 0), "Scroll to top")
+}
+
+// scroll sets origin position of the <view> internal buffer <step> rows lower. <step> can be negative. Autoscroll is
+// turned on once the last line is reached and turned off otherwise.
 func scroll(step int, view *gocui.View) {
 	_, sizeY := view.Size()
 	originX, originY := view.Origin()
 
-	// If we're at the bottom
-	if originY+step > strings.Count(view.ViewBuffer(), "\n")-sizeY-1 {
-		view.Autoscroll = true
-	} else {
-		view.Autoscroll = false
-		if originY+step > 0 {
-			_ = view.SetOrigin(originX, originY+step)
-		}
+	originY, atBottom := scrollOrigin(originY, step, viewRows(view), sizeY)
+	view.Autoscroll = atBottom
+	if !atBottom {
+		_ = view.SetOrigin(originX, originY)
+	}
+}
+
+// scrollOrigin returns vertical origin of the view <sizeY> rows high showing buffer of <lines>, after scrolling from
+// <originY> by <step> rows, and true if the last line is reached. Origin stays within the buffer, so buffers shorter
+// than the view never scroll.
+func scrollOrigin(originY int, step int, lines int, sizeY int) (int, bool) {
+	maxOriginY := max(lines-sizeY, 0)
+	originY = min(max(originY+step, 0), maxOriginY)
+	return originY, originY == maxOriginY
+}
+
+// viewRows returns amount of rows buffer of <view> takes on screen, with long lines wrapped at current view width if
+// wrapping is enabled. Unlike ViewBufferLines, which are updated only once view is drawn, it accounts for text written
+// since the last draw.
+func viewRows(view *gocui.View) int {
+	lines := view.BufferLines()
+	if !view.Wrap {
+		return len(lines)
+	}
+	width, _ := view.Size()
+	var rows int
+	for _, line := range lines {
+		rows += lineRows(utf8.RuneCountInString(line), width)
+	}
+	return rows
+}
+
+// lineRows returns amount of rows line of <length> symbols takes in view <width> columns wide with wrapping enabled.
+// As UI library wraps lines, line at least as long as view width takes an extra row for the rest after the last full
+// row, even if it's empty.
+func lineRows(length int, width int) int {
+	if length < width || width <= 0 {
+		return 1
 	}
+	return length/width + 1
 }
 
 // quit closes the <gui> and returns ErrQuit, making main UI loop exit.
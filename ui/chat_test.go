@@ -0,0 +1,248 @@
+package ui
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/jroimartin/gocui"
+)
+
+// newTestView returns view <width> columns wide and <height> rows high with <text> written to it. View is created on
+// GUI which is never initialized, so it's never drawn.
+func newTestView(t *testing.T, width int, height int, text string) *gocui.View {
+	t.Helper()
+	view, err := (&gocui.Gui{}).SetView("test", 0, 0, width+1, height+1)
+	if err != nil && err != gocui.ErrUnknownView {
+		t.Fatalf("Create view: %v", err)
+	}
+	if _, err := fmt.Fprint(view, text); err != nil {
+		t.Fatalf("Write to view: %v", err)
+	}
+	return view
+}
+
+// newSizedGui returns GUI which is never initialized, as if it ran in terminal <maxX> columns wide and <maxY> rows
+// high.
+func newSizedGui(maxX int, maxY int) *gocui.Gui {
+	gui := &gocui.Gui{}
+	resize(gui, maxX, maxY)
+	return gui
+}
+
+// resize sets size of <gui> as if terminal was resized to <maxX> columns and <maxY> rows. Size is only set by the
+// library once terminal is initialized, so it's written to unexported fields.
+func resize(gui *gocui.Gui, maxX int, maxY int) {
+	fields := reflect.ValueOf(gui).Elem()
+	for name, value := range map[string]int{"maxX": maxX, "maxY": maxY} {
+		field := fields.FieldByName(name)
+		reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().SetInt(int64(value))
+	}
+}
+
+func TestScrollOrigin(t *testing.T) {
+	tests := []struct {
+		name         string
+		originY      int
+		step         int
+		lines        int
+		wantOriginY  int
+		wantAtBottom bool
+	}{
+		{name: "empty buffer", originY: 0, step: 1, lines: 0, wantOriginY: 0, wantAtBottom: true},
+		{name: "short buffer down", originY: 0, step: 1, lines: 3, wantOriginY: 0, wantAtBottom: true},
+		{name: "short buffer up", originY: 0, step: -1, lines: 3, wantOriginY: 0, wantAtBottom: true},
+		{name: "buffer as high as view", originY: 0, step: 1, lines: 5, wantOriginY: 0, wantAtBottom: true},
+		{name: "down in the middle", originY: 2, step: 1, lines: 20, wantOriginY: 3, wantAtBottom: false},
+		{name: "up in the middle", originY: 2, step: -1, lines: 20, wantOriginY: 1, wantAtBottom: false},
+		{name: "up past top", originY: 1, step: -5, lines: 20, wantOriginY: 0, wantAtBottom: false},
+		{name: "down to last page", originY: 14, step: 1, lines: 20, wantOriginY: 15, wantAtBottom: true},
+		{name: "down past last page", originY: 14, step: 10, lines: 20, wantOriginY: 15, wantAtBottom: true},
+		{name: "origin past shrunk buffer", originY: 30, step: -1, lines: 20, wantOriginY: 15, wantAtBottom: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			originY, atBottom := scrollOrigin(test.originY, test.step, test.lines, 5)
+			if originY != test.wantOriginY || atBottom != test.wantAtBottom {
+				t.Errorf("scrollOrigin is (%v, %v), want (%v, %v)", originY, atBottom, test.wantOriginY,
+					test.wantAtBottom)
+			}
+		})
+	}
+}
+
+func TestLineRows(t *testing.T) {
+	tests := []struct {
+		length int
+		width  int
+		want   int
+	}{
+		{length: 0, width: 10, want: 1},
+		{length: 9, width: 10, want: 1},
+		{length: 10, width: 10, want: 2},
+		{length: 19, width: 10, want: 2},
+		{length: 20, width: 10, want: 3},
+		{length: 10000, width: 10, want: 1001},
+		{length: 10000, width: 0, want: 1},
+		{length: 5, width: -1, want: 1},
+	}
+	for _, test := range tests {
+		if rows := lineRows(test.length, test.width); rows != test.want {
+			t.Errorf("lineRows(%v, %v) is %v, want %v", test.length, test.width, rows, test.want)
+		}
+	}
+}
+
+func TestViewRows(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		wrap bool
+		want int
+	}{
+		{name: "empty", text: "", want: 0},
+		{name: "short lines", text: "a\nb\nc", want: 3},
+		{name: "long line without wrap", text: strings.Repeat("x", 25), want: 1},
+		{name: "long line with wrap", text: strings.Repeat("x", 25), wrap: true, want: 3},
+		{name: "very long line with wrap", text: "a\n" + strings.Repeat("x", 1000), wrap: true, want: 102},
+		{name: "multibyte line with wrap", text: strings.Repeat("я", 15), wrap: true, want: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			view := newTestView(t, 10, 3, test.text)
+			view.Wrap = test.wrap
+			if rows := viewRows(view); rows != test.want {
+				t.Errorf("viewRows is %v, want %v", rows, test.want)
+			}
+		})
+	}
+}
+
+func TestNextView(t *testing.T) {
+	gui := &gocui.Gui{}
+	c := &Chat{}
+	for _, name := range []string{ChatBoxName, inputFieldName, onlineBoxName} {
+		if _, err := gui.SetView(name, 0, 0, 10, 10); err != nil && err != gocui.ErrUnknownView {
+			t.Fatalf("Create view %v: %v", name, err)
+		}
+		c.addVisibleView(name)
+	}
+	c.addVisibleView(ChatBoxName)
+	if _, err := gui.SetCurrentView(inputFieldName); err != nil {
+		t.Fatal(err)
+	}
+
+	cycle := func(want ...string) {
+		t.Helper()
+		for _, name := range want {
+			if err := c.nextView(gui, nil); err != nil {
+				t.Fatalf("Switch view: %v", err)
+			}
+			if current := gui.CurrentView().Name(); current != name {
+				t.Fatalf("Current view is %v, want %v", current, name)
+			}
+			if gui.Cursor != (name == inputFieldName) {
+				t.Errorf("Cursor is %v in %v", gui.Cursor, name)
+			}
+		}
+	}
+	cycle(onlineBoxName, ChatBoxName, inputFieldName, onlineBoxName, ChatBoxName)
+
+	if err := c.showOnlineBox(gui, false); err != nil {
+		t.Fatalf("Close online box: %v", err)
+	}
+	cycle(inputFieldName, ChatBoxName, inputFieldName)
+
+	chatBox, _ := gui.View(ChatBoxName)
+	if err := c.focusView(gui, chatBox); err != nil {
+		t.Fatalf("Focus chat box: %v", err)
+	}
+	cycle(inputFieldName)
+}
+
+func TestLayoutVisibleViews(t *testing.T) {
+	gui := newSizedGui(100, 40)
+	c := &Chat{history: newHistory(historySize, ""), opts: Options{MaxMessageLength: 100}, onlineBoxOpen: true}
+	layouts := []func(*gocui.Gui) error{c.chatBoxLayout, c.inputFieldLayout, c.onlineBoxLayout}
+	runLayouts := func() {
+		t.Helper()
+		for i := 0; i < 3; i++ {
+			for _, layout := range layouts {
+				if err := layout(gui); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+
+	runLayouts()
+	// Online box is created again once it's closed and opened
+	if err := gui.DeleteView(onlineBoxName); err != nil {
+		t.Fatal(err)
+	}
+	runLayouts()
+
+	want := []string{ChatBoxName, inputFieldName, onlineBoxName}
+	if !slices.Equal(c.visibleViews, want) {
+		t.Errorf("Visible views are %v, want %v", c.visibleViews, want)
+	}
+}
+
+func TestLayoutResize(t *testing.T) {
+	gui := newSizedGui(90, 40)
+	c := &Chat{history: newHistory(historySize, ""), opts: Options{MaxMessageLength: 100},
+		onlineUsers: []OnlineUser{{Nickname: "alice"}}}
+	layouts := []func(*gocui.Gui) error{c.chatBoxLayout, c.onlineBoxLayout, c.inputFieldLayout}
+	runLayouts := func() {
+		t.Helper()
+		for _, layout := range layouts {
+			if err := layout(gui); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	runLayouts()
+	if err := c.showOnlineBox(gui, true); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		maxX int
+		maxY int
+		want map[string][4]int
+	}{
+		{90, 40, map[string][4]int{ChatBoxName: {0, 0, 89, 31}, onlineBoxName: {70, 0, 89, 31},
+			inputFieldName: {0, 32, 89, 38}}},
+		{60, 30, map[string][4]int{ChatBoxName: {0, 0, 59, 21}, onlineBoxName: {40, 0, 59, 21},
+			inputFieldName: {0, 22, 59, 28}}},
+		{150, 50, map[string][4]int{ChatBoxName: {0, 0, 149, 41}, onlineBoxName: {130, 0, 149, 41},
+			inputFieldName: {0, 42, 149, 48}}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%vx%v", tt.maxX, tt.maxY), func(t *testing.T) {
+			resize(gui, tt.maxX, tt.maxY)
+			runLayouts()
+			for name, want := range tt.want {
+				x0, y0, x1, y1, err := gui.ViewPosition(name)
+				if err != nil {
+					t.Fatalf("Get position of %v: %v", name, err)
+				}
+				if got := [4]int{x0, y0, x1, y1}; got != want {
+					t.Errorf("Position of %v is %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+
+	if err := c.showOnlineBox(gui, false); err != nil {
+		t.Fatal(err)
+	}
+	resize(gui, 90, 40)
+	runLayouts()
+	if _, err := gui.View(onlineBoxName); err == nil {
+		t.Error("Closed online box is created again after resize")
+	}
+}
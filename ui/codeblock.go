@@ -0,0 +1,58 @@
+package ui
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/samber/lo"
+)
+
+// codeFence is the delimiter of code block in message.
+const codeFence = "```"
+
+// codeBlockColor is the color of code block lines.
+var codeBlockColor = color.New(color.FgHiWhite, color.BgHiBlack)
+
+// renderCodeBlocks returns <msg> with every code block enclosed in codeFence printed on separate lines with distinct
+// background, preserving indentation. Language name following opening fence is omitted. Unclosed fence is left as is.
+func renderCodeBlocks(msg string) string {
+	parts := strings.Split(msg, codeFence)
+	if len(parts) < 3 {
+		return msg
+	}
+
+	var sb strings.Builder
+	for i, part := range parts {
+		isLast := i == len(parts)-1
+		switch {
+		case i%2 == 0:
+			sb.WriteString(part)
+		case isLast:
+			// Unclosed fence
+			sb.WriteString(codeFence + part)
+		default:
+			sb.WriteString("\n" + renderCodeBlock(part) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// renderCodeBlock returns lines of <code> padded to the same width and colored with codeBlockColor.
+func renderCodeBlock(code string) string {
+	// Text on the line of opening fence is a language name, e.g. "```go"
+	if lang, rest, ok := strings.Cut(code, "\n"); ok && !strings.Contains(strings.TrimSpace(lang), " ") {
+		code = rest
+	}
+	code = strings.Trim(strings.ReplaceAll(code, "\t", "    "), "\n")
+
+	lines := strings.Split(code, "\n")
+	width := lo.Max(lo.Map(lines, func(line string, _ int) int {
+		return utf8.RuneCountInString(line)
+	}))
+	for i, line := range lines {
+		padding := strings.Repeat(" ", width-utf8.RuneCountInString(line))
+		lines[i] = codeBlockColor.Sprint(" " + line + padding + " ")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,29 @@
+package ui
+
+import "testing"
+
+func TestRenderCodeBlocks(t *testing.T) {
+	withColors(t)
+	line := func(text string) string { return codeBlockColor.Sprint(" " + text + " ") }
+	tests := []struct {
+		name string
+		msg  string
+		want string
+	}{
+		{name: "no fence", msg: "hello", want: "hello"},
+		{name: "unclosed", msg: "see ```code", want: "see ```code"},
+		{name: "inline", msg: "run ```go test``` now", want: "run \n" + line("go test") + "\n now"},
+		{name: "language", msg: "```go\nx := 1\n```", want: "\n" + line("x := 1") + "\n"},
+		{name: "lines padded", msg: "```\nif a {\n\tb()\n}\n```",
+			want: "\n" + line("if a { ") + "\n" + line("    b()") + "\n" + line("}      ") + "\n"},
+		{name: "two blocks", msg: "```a``` and ```b```", want: "\n" + line("a") + "\n and \n" + line("b") + "\n"},
+		{name: "second unclosed", msg: "```a``` and ```b", want: "\n" + line("a") + "\n and ```b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderCodeBlocks(tt.msg); got != tt.want {
+				t.Errorf("renderCodeBlocks(%q) is %q, want %q", tt.msg, got, tt.want)
+			}
+		})
+	}
+}
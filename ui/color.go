@@ -0,0 +1,180 @@
+package ui
+
+import (
+	"hash/fnv"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/fatih/color"
+	"github.com/jroimartin/gocui"
+	"github.com/samber/lo"
+)
+
+// colorsByName maps color names allowed in config to color attributes.
+var colorsByName = map[string]color.Attribute{
+	"black":      color.FgBlack,
+	"red":        color.FgRed,
+	"green":      color.FgGreen,
+	"yellow":     color.FgYellow,
+	"blue":       color.FgBlue,
+	"magenta":    color.FgMagenta,
+	"cyan":       color.FgCyan,
+	"white":      color.FgWhite,
+	"hi_black":   color.FgHiBlack,
+	"hi_red":     color.FgHiRed,
+	"hi_green":   color.FgHiGreen,
+	"hi_yellow":  color.FgHiYellow,
+	"hi_blue":    color.FgHiBlue,
+	"hi_magenta": color.FgHiMagenta,
+	"hi_cyan":    color.FgHiCyan,
+	"hi_white":   color.FgHiWhite,
+}
+
+// DefaultNicknameColor is the color name which removes color set for nickname, so it's picked from nickname palette
+// again.
+const DefaultNicknameColor = "default"
+
+// DefaultSystemLabel is the label printed instead of nickname in system messages, used if it's not set in config.
+const DefaultSystemLabel = "SYSTEM"
+
+// represents levels of system messages, distinguished by color of their label.
+const (
+	SystemLevelInfo  = "info"
+	SystemLevelWarn  = "warn"
+	SystemLevelError = "error"
+)
+
+// systemLabel is the label printed instead of nickname in system messages.
+var systemLabel = DefaultSystemLabel
+
+// systemColor is the color of label of system messages with SystemLevelInfo or unknown level.
+var systemColor = color.FgCyan
+
+// systemLevelColors maps levels of system messages to colors of their label, overriding systemColor.
+var systemLevelColors = map[string]color.Attribute{
+	SystemLevelWarn:  color.FgYellow,
+	SystemLevelError: color.FgRed,
+}
+
+// nicknamePalette is a set of colors to pick nickname colors from. Cyan is reserved for system messages.
+var nicknamePalette = []color.Attribute{
+	color.FgRed,
+	color.FgGreen,
+	color.FgYellow,
+	color.FgBlue,
+	color.FgMagenta,
+	color.FgHiRed,
+	color.FgHiGreen,
+	color.FgHiYellow,
+	color.FgHiBlue,
+	color.FgHiMagenta,
+}
+
+// nicknameColorsMu guards nicknameColors, since messages are rendered from several goroutines.
+var nicknameColorsMu sync.RWMutex
+
+// nicknameColors maps nicknames to colors set for them, overriding nickname palette.
+var nicknameColors = map[string]color.Attribute{}
+
+// SetNicknamePalette replaces colors used for nicknames with colors named <names>, e.g. "red" or "hi_blue". If <names>
+// is empty, default palette is kept. It returns error if any of <names> is unknown.
+func SetNicknamePalette(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	palette := make([]color.Attribute, 0, len(names))
+	for _, name := range names {
+		attr, ok := colorsByName[strings.ToLower(name)]
+		if !ok {
+			return errors.Newf("Unknown nickname color %q", name)
+		}
+		palette = append(palette, attr)
+	}
+	nicknamePalette = palette
+	return nil
+}
+
+// SetSystemStyle replaces label of system messages with <label> and it's color with color named <colorName>, e.g.
+// "cyan". Empty values keep defaults. It returns error if <colorName> is unknown.
+func SetSystemStyle(label string, colorName string) error {
+	if label != "" {
+		systemLabel = sanitize(label)
+	}
+	if colorName == "" {
+		return nil
+	}
+	attr, ok := colorsByName[strings.ToLower(colorName)]
+	if !ok {
+		return errors.Newf("Unknown system message color %q", colorName)
+	}
+	systemColor = attr
+	return nil
+}
+
+// systemLabelFor returns colored label of system message with <level>.
+func systemLabelFor(level string) string {
+	attr, ok := systemLevelColors[level]
+	return color.New(lo.Ternary(ok, attr, systemColor)).Sprint(systemLabel)
+}
+
+// ColorNames returns sorted names of colors allowed in config and commands.
+func ColorNames() []string {
+	names := lo.Keys(colorsByName)
+	slices.Sort(names)
+	return names
+}
+
+// SetNicknameColors sets colors of nicknames from map of nicknames to color names <colors>, e.g. "alice": "hi_blue",
+// replacing colors set before. It returns error if any of color names is unknown.
+func SetNicknameColors(colors map[string]string) error {
+	attrs := make(map[string]color.Attribute, len(colors))
+	for nickname, name := range colors {
+		attr, ok := colorsByName[strings.ToLower(name)]
+		if !ok {
+			return errors.Newf("Unknown color %q of nickname %v", name, nickname)
+		}
+		attrs[nickname] = attr
+	}
+	nicknameColorsMu.Lock()
+	defer nicknameColorsMu.Unlock()
+	nicknameColors = attrs
+	return nil
+}
+
+// SetNicknameColor sets color of <nickname> to color named <colorName>, or removes it if <colorName> is
+// DefaultNicknameColor, and redraws chat and online users boxes with it. It returns error if <colorName> is unknown.
+func (c *Chat) SetNicknameColor(nickname string, colorName string) error {
+	attr, ok := colorsByName[strings.ToLower(colorName)]
+	if !ok && !strings.EqualFold(colorName, DefaultNicknameColor) {
+		return errors.Newf("Unknown color %q", colorName)
+	}
+	nicknameColorsMu.Lock()
+	if ok {
+		nicknameColors[nickname] = attr
+	} else {
+		delete(nicknameColors, nickname)
+	}
+	nicknameColorsMu.Unlock()
+
+	c.renderMessages()
+	c.Gui.Update(func(g *gocui.Gui) error {
+		return c.drawOnlineBox(g)
+	})
+	return nil
+}
+
+// ColorForNickname returns color for <name>: color set for it with SetNicknameColor or SetNicknameColors, or picked
+// from nickname palette by hash of <name> otherwise, so the same nickname always gets the same color.
+func ColorForNickname(name string) *color.Color {
+	nicknameColorsMu.RLock()
+	attr, ok := nicknameColors[name]
+	nicknameColorsMu.RUnlock()
+	if ok {
+		return color.New(attr)
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(name))
+	return color.New(nicknamePalette[hash.Sum32()%uint32(len(nicknamePalette))])
+}
@@ -0,0 +1,33 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNoColor(t *testing.T) {
+	msg := Message{Nickname: "alice", Text: "*hi* see https://example.com ||Bruce||", IsImportant: true,
+		Time: time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name   string
+		colors func(t *testing.T)
+		want   bool
+	}{
+		{"enabled", withColors, true},
+		{"disabled", withoutColors, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.colors(t)
+			if nickname := ColorForNickname("alice").Sprint("alice"); (nickname != "alice") != tt.want {
+				t.Errorf("Nickname is %q, want colored: %v", nickname, tt.want)
+			}
+			c := &Chat{opts: Options{TimestampFormat: TimestampsOff}}
+			text := c.renderEntry(logEntry{msg: &msg, repeats: 1}, new(string))
+			if colored := strings.Contains(text, "\x1b["); colored != tt.want {
+				t.Errorf("Message is rendered as %q, want colored: %v", text, tt.want)
+			}
+		})
+	}
+}
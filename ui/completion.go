@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+)
+
+// completion represents state of nickname completion in input field, kept between repeated Tab presses.
+type completion struct {
+	candidates []string
+	idx        int
+	inserted   string
+}
+
+// reset discards current completion state, so next Tab press starts a new completion.
+func (c *completion) reset() {
+	*c = completion{}
+}
+
+// completeNickname replaces partial word under the cursor of the <view> with matching online user nickname. Repeated
+// calls with the same prefix cycle through all matching nicknames.
+func (c *Chat) completeNickname(gui *gocui.Gui, view *gocui.View) error {
+	word := wordBeforeCursor(view)
+
+	if c.completion.candidates != nil && word == c.completion.inserted {
+		c.completion.idx = (c.completion.idx + 1) % len(c.completion.candidates)
+	} else {
+		candidates := nicknameCandidates(nicknames(c.target().onlineUsers), word)
+		if word == "" || len(candidates) == 0 {
+			return nil
+		}
+		c.completion = completion{candidates: candidates}
+	}
+
+	for range []rune(word) {
+		view.EditDelete(true)
+	}
+	c.completion.inserted = c.completion.candidates[c.completion.idx]
+	for _, ch := range c.completion.inserted {
+		view.EditWrite(ch)
+	}
+
+	return nil
+}
+
+// nicknameCandidates returns sorted list of <users> starting with <prefix>, ignoring case.
+func nicknameCandidates(users []string, prefix string) []string {
+	var candidates []string
+	for _, user := range users {
+		if strings.HasPrefix(strings.ToLower(user), strings.ToLower(prefix)) {
+			candidates = append(candidates, user)
+		}
+	}
+	slices.Sort(candidates)
+	return candidates
+}
+
+// wordBeforeCursor returns part of the word located to the left of the cursor of the <view>.
+func wordBeforeCursor(view *gocui.View) string {
+	cx, cy := view.Cursor()
+	ox, oy := view.Origin()
+
+	lines := view.ViewBufferLines()
+	if oy+cy >= len(lines) {
+		return ""
+	}
+	line := []rune(lines[oy+cy])
+	if ox+cx < len(line) {
+		line = line[:ox+cx]
+	}
+
+	start := strings.LastIndexAny(string(line), " \t") + 1
+	return string(line)[start:]
+}
@@ -0,0 +1,44 @@
+package ui
+
+import (
+	"github.com/fatih/color"
+)
+
+// DeliveryStatus represents delivery status of own message, shown after it's text.
+type DeliveryStatus int
+
+// represents delivery statuses of message.
+const (
+	DeliveryNone    DeliveryStatus = iota // Not own message or status is unknown, it is not shown
+	DeliverySending                       // Sent, but not confirmed by server yet
+	DeliveryOk                            // Confirmed by server
+	DeliveryFailed                        // Rejected by server or not confirmed after all attempts
+)
+
+// deliveryGlyphs maps delivery statuses to glyphs shown after message text and their colors.
+var deliveryGlyphs = map[DeliveryStatus]struct {
+	text  string
+	color *color.Color
+}{
+	DeliverySending: {"…", color.New(color.FgHiBlack)},
+	DeliveryOk:      {"✓", color.New(color.FgGreen)},
+	DeliveryFailed:  {"✗", color.New(color.FgRed)},
+}
+
+// glyph returns colored glyph of delivery status <s>.
+func (s DeliveryStatus) glyph() string {
+	glyph := deliveryGlyphs[s]
+	return glyph.color.Sprint(glyph.text)
+}
+
+// SetDeliveryStatus updates delivery status shown after own message with client-generated <localID>. It does nothing
+// if message is not in chat box anymore.
+func (c *Chat) SetDeliveryStatus(localID int64, status DeliveryStatus) {
+	c.updateMessage(func(msg *Message) bool {
+		if msg.LocalID != localID || msg.Status == status {
+			return false
+		}
+		msg.Status = status
+		return true
+	})
+}
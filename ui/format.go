@@ -0,0 +1,165 @@
+package ui
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/samber/lo"
+)
+
+// codeSpanMarker is the delimiter of inline code in message, e.g. "`code`".
+const codeSpanMarker = '`'
+
+// formatSpan represents kind of inline formatting span in message, e.g. "*bold*".
+type formatSpan struct {
+	marker byte
+	attr   color.Attribute
+}
+
+// formatSpans are the kinds of inline formatting spans, outer first. Spans can be nested in outer ones. Italic is shown
+// underlined, since UI library doesn't support italic text.
+var formatSpans = []formatSpan{{'*', color.Bold}, {'_', color.Underline}}
+
+// urlPattern matches http and https URLs. Punctuation at the end is treated as a part of the sentence, not URL.
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]*[^\s<>".,;:!?'")\]}]`)
+
+// renderMessage returns <msg> with spoilers, URLs, formatting, unless it's disabled in Options, and code blocks
+// rendered.
+// Spoilers are revealed if <revealSpoilers> is true.
+func (c *Chat) renderMessage(msg string, revealSpoilers bool) string {
+	msg = renderSpoilers(msg, revealSpoilers)
+	return renderCodeBlocks(renderFormatting(msg, !c.opts.DisableFormatting))
+}
+
+// renderFormatting returns <msg> with URLs styled with urlAttrs and, if <spans> is true, "*bold*", "_italic_" and
+// "`code`" spans styled and their markers removed. Code blocks enclosed in codeFence are left as is. Marker opens
+// span only at the beginning of a word and closes it only at the end of a word, so e.g. snake_case is not formatted.
+// Unclosed markers are left as is.
+func renderFormatting(msg string, spans bool) string {
+	parts := strings.Split(msg, codeFence)
+	for i, part := range parts {
+		isCodeBlock := i%2 == 1 && i != len(parts)-1
+		if !isCodeBlock {
+			parts[i] = renderURLs(part, lo.Ternary(spans, renderCodeSpans, func(text string) string { return text }))
+		}
+	}
+	return strings.Join(parts, codeFence)
+}
+
+// renderURLs returns <text> with URLs styled with urlAttrs and the rest of text rendered with <render>.
+func renderURLs(text string, render func(string) string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range urlPattern.FindAllStringIndex(text, -1) {
+		sb.WriteString(render(text[last:loc[0]]))
+		sb.WriteString(style(text[loc[0]:loc[1]], urlAttrs))
+		last = loc[1]
+	}
+	sb.WriteString(render(text[last:]))
+	return sb.String()
+}
+
+// renderCodeSpans returns <text> with code spans colored with codeBlockColor and formatting spans styled outside of
+// them.
+func renderCodeSpans(text string) string {
+	var sb strings.Builder
+	for i, piece := range splitSpans(text, codeSpanMarker) {
+		if i%2 == 1 {
+			sb.WriteString(codeBlockColor.Sprint(piece))
+		} else {
+			sb.WriteString(renderSpans(piece, nil, formatSpans))
+		}
+	}
+	return sb.String()
+}
+
+// renderSpans returns <text> styled with <attrs> and with <spans> styled with their attributes in addition.
+func renderSpans(text string, attrs []color.Attribute, spans []formatSpan) string {
+	if len(spans) == 0 {
+		if len(attrs) == 0 || text == "" {
+			return text
+		}
+		return style(text, attrs)
+	}
+	var sb strings.Builder
+	for i, piece := range splitSpans(text, spans[0].marker) {
+		pieceAttrs := attrs
+		if i%2 == 1 {
+			pieceAttrs = append(append([]color.Attribute{}, attrs...), spans[0].attr)
+		}
+		sb.WriteString(renderSpans(piece, pieceAttrs, spans[1:]))
+	}
+	return sb.String()
+}
+
+// style returns <text> styled with <attrs>. Unlike color.Color, it resets all attributes after <text>, since UI library
+// doesn't support resetting individual attributes.
+func style(text string, attrs []color.Attribute) string {
+	if color.NoColor {
+		return text
+	}
+	codes := lo.Map(attrs, func(attr color.Attribute, _ int) string {
+		return strconv.Itoa(int(attr))
+	})
+	return "\x1b[" + strings.Join(codes, ";") + "m" + text + "\x1b[0m"
+}
+
+// splitSpans returns <text> split to pieces by spans enclosed in <marker>. Pieces with odd indexes are span contents
+// without markers.
+func splitSpans(text string, marker byte) []string {
+	var pieces []string
+	start := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] != marker || !isSpanStart(text, i) {
+			continue
+		}
+		end := spanEnd(text, i)
+		if end == -1 {
+			continue
+		}
+		pieces = append(pieces, text[start:i], text[i+1:end])
+		start = end + 1
+		i = end
+	}
+	return append(pieces, text[start:])
+}
+
+// isSpanStart returns true if marker at index <i> of <text> is at the beginning of a word. Marker escaped with "\",
+// e.g. in "¯\_(ツ)_/¯", doesn't open span.
+func isSpanStart(text string, i int) bool {
+	if i > 0 {
+		if prev, _ := utf8.DecodeLastRuneInString(text[:i]); !isWordBoundary(prev) || prev == '\\' {
+			return false
+		}
+	}
+	next, _ := utf8.DecodeRuneInString(text[i+1:])
+	return i+1 < len(text) && !unicode.IsSpace(next) && next != rune(text[i])
+}
+
+// spanEnd returns index of marker closing span opened at index <start> of <text>, or -1 if span is not closed on the
+// same line.
+func spanEnd(text string, start int) int {
+	for j := start + 2; j < len(text); j++ {
+		if text[j] == '\n' {
+			return -1
+		}
+		if text[j] != text[start] {
+			continue
+		}
+		prev, _ := utf8.DecodeLastRuneInString(text[:j])
+		next, _ := utf8.DecodeRuneInString(text[j+1:])
+		if !unicode.IsSpace(prev) && (j+1 == len(text) || isWordBoundary(next)) {
+			return j
+		}
+	}
+	return -1
+}
+
+// isWordBoundary returns true if <r> can't be a part of a word.
+func isWordBoundary(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+// withColors enables colored output for the duration of the test, since it's disabled if output is not a terminal.
+func withColors(t *testing.T) {
+	t.Helper()
+	noColor := color.NoColor
+	color.NoColor = false
+	t.Cleanup(func() { color.NoColor = noColor })
+}
+
+func TestRenderFormatting(t *testing.T) {
+	withColors(t)
+	bold := func(text string) string { return style(text, []color.Attribute{color.Bold}) }
+	italic := func(text string) string { return style(text, []color.Attribute{color.Underline}) }
+	code := codeBlockColor.Sprint
+	tests := []struct {
+		name string
+		msg  string
+		want string
+	}{
+		{name: "plain", msg: "hello", want: "hello"},
+		{name: "bold", msg: "say *hi* now", want: "say " + bold("hi") + " now"},
+		{name: "italic", msg: "_hi_", want: italic("hi")},
+		{name: "code", msg: "run `go test`", want: "run " + code("go test")},
+		{name: "bold with punctuation", msg: "(*hi*)!", want: "(" + bold("hi") + ")!"},
+		{name: "multiple", msg: "*a* and *b*", want: bold("a") + " and " + bold("b")},
+		{name: "italic in bold", msg: "*a _b_*", want: bold("a ") + style("b", []color.Attribute{color.Bold,
+			color.Underline})},
+		{name: "formatting in code", msg: "`*a*`", want: code("*a*")},
+		{name: "snake case", msg: "snake_case_name", want: "snake_case_name"},
+		{name: "inside word", msg: "2*3*4", want: "2*3*4"},
+		{name: "opening inside word", msg: "a*b* c", want: "a*b* c"},
+		{name: "unclosed", msg: "*hi", want: "*hi"},
+		{name: "space after opening", msg: "* hi*", want: "* hi*"},
+		{name: "space before closing", msg: "*hi *", want: "*hi *"},
+		{name: "empty", msg: "**", want: "**"},
+		{name: "escaped", msg: `¯\_(ツ)_/¯`, want: `¯\_(ツ)_/¯`},
+		{name: "across lines", msg: "*a\nb*", want: "*a\nb*"},
+		{name: "unicode", msg: "*привет*", want: bold("привет")},
+		{name: "code block", msg: "```\n*a*\n```", want: "```\n*a*\n```"},
+		{name: "after code block", msg: "```\na\n``` *b*", want: "```\na\n``` " + bold("b")},
+		{name: "URL", msg: "see https://example.com/a_b_c.", want: "see " + style("https://example.com/a_b_c",
+			urlAttrs) + "."},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if msg := renderFormatting(test.msg, true); msg != test.want {
+				t.Errorf("renderFormatting(%q) is %q, want %q", test.msg, msg, test.want)
+			}
+		})
+	}
+}
+
+func TestRenderFormattingDisabled(t *testing.T) {
+	withColors(t)
+	msg := "*a* _b_ `c` https://example.com"
+	want := "*a* _b_ `c` " + style("https://example.com", urlAttrs)
+	if rendered := renderFormatting(msg, false); rendered != want {
+		t.Errorf("renderFormatting(%q) is %q, want %q", msg, rendered, want)
+	}
+}
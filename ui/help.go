@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jroimartin/gocui"
+)
+
+// keysHelp represents state of keybindings help view.
+type keysHelp struct {
+	shown      bool
+	returnView string // View to focus back when help is closed
+}
+
+// toggleKeysHelp opens keybindings help over chat box, or closes it if it's already open.
+func (c *Chat) toggleKeysHelp(gui *gocui.Gui, view *gocui.View) error {
+	if c.keysHelp.shown {
+		return c.closeKeysHelp(gui, view)
+	}
+	c.keysHelp.shown = true
+	c.keysHelp.returnView = inputFieldName
+	if current := gui.CurrentView(); current != nil {
+		c.keysHelp.returnView = current.Name()
+	}
+	return nil
+}
+
+// closeKeysHelp closes keybindings help and focuses back the view which was focused before it was open.
+func (c *Chat) closeKeysHelp(gui *gocui.Gui, view *gocui.View) error {
+	if !c.keysHelp.shown {
+		return nil
+	}
+	c.keysHelp.shown = false
+	if err := gui.DeleteView(keysHelpName); err != nil && !errors.Is(err, gocui.ErrUnknownView) {
+		return errors.Wrap(err, "Delete view")
+	}
+	if _, err := gui.SetCurrentView(c.keysHelp.returnView); err != nil {
+		// View could be closed meanwhile, e.g. online users box
+		_, err = gui.SetCurrentView(inputFieldName)
+		return errors.Wrap(err, fmt.Sprintf("Focus view %v", inputFieldName))
+	}
+	return nil
+}
+
+// keysHelpLayout is a GUI manager function for keybindings help view. It's shown over chat box only while it's open.
+// Help is rendered from actual key bindings, so keys from config are shown.
+func (c *Chat) keysHelpLayout(gui *gocui.Gui) error {
+	if !c.keysHelp.shown {
+		return nil
+	}
+
+	maxX, maxY := gui.Size()
+	y0, y1 := c.chatBoxRows(maxY)
+	help, err := gui.SetView(keysHelpName, 0, y0, maxX-1, y1)
+	if err != nil && !errors.Is(err, gocui.ErrUnknownView) {
+		return errors.Wrap(err, fmt.Sprintf("Create view for %v", keysHelpName))
+	}
+	if errors.Is(err, gocui.ErrUnknownView) {
+		help.Title = "Keys (Esc to close)"
+		help.Wrap = true
+		if _, err = fmt.Fprint(help, strings.Join(c.KeybindingsHelp(), "\n")); err != nil {
+			return errors.Wrap(err, "Print keybindings help")
+		}
+		if _, err = gui.SetCurrentView(keysHelpName); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("Focus view %v", keysHelpName))
+		}
+	}
+
+	return nil
+}
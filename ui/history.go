@@ -0,0 +1,213 @@
+package ui
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jroimartin/gocui"
+	"github.com/samber/lo"
+)
+
+// historySize is the maximum amount of sent messages to keep in input history.
+const historySize = 100
+
+// secretCommandWords are the parts of command names implying that command carries credentials, e.g. /password or
+// /identify. Such commands are not written to history file.
+var secretCommandWords = []string{"pass", "auth", "login", "identify", "register", "token", "secret"}
+
+// history represents ring of previously sent messages, oldest first.
+type history struct {
+	entries []string
+	size    int
+	path    string // File to save history to, as JSON string per line. If empty, history is not saved
+}
+
+// newHistory returns new input history holding up to <size> messages, saved to file at <path> unless it's empty.
+func newHistory(size int, path string) *history {
+	return &history{size: size, path: path}
+}
+
+// load reads history saved to file by previous sessions. Missing file is not an error.
+func (h *history) load() error {
+	if h.path == "" {
+		return nil
+	}
+	file, err := os.Open(h.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "Open history file")
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var msg string
+		if json.Unmarshal(scanner.Bytes(), &msg) == nil {
+			h.add(msg)
+		}
+	}
+	return errors.Wrap(scanner.Err(), "Read history file")
+}
+
+// save writes history to file, skipping commands carrying credentials, so it's loaded by the next session. History
+// is not saved anymore after the first failure, so error is returned once.
+func (h *history) save() error {
+	if h.path == "" {
+		return nil
+	}
+	var sb strings.Builder
+	var last string
+	for _, msg := range h.entries {
+		if isSecretCommand(msg) || msg == last {
+			continue
+		}
+		line, _ := json.Marshal(msg) // Strings are always encoded
+		sb.Write(line)
+		sb.WriteByte('\n')
+		last = msg
+	}
+	err := os.MkdirAll(filepath.Dir(h.path), 0700)
+	if err == nil {
+		// Private messages are saved as well, so file is readable only by owner
+		err = os.WriteFile(h.path, []byte(sb.String()), 0600)
+	}
+	if err != nil {
+		h.path = ""
+		return errors.Wrap(err, "Write history file, input history will not be saved")
+	}
+	return nil
+}
+
+// isSecretCommand returns true if <msg> is a command which name contains one of secretCommandWords.
+func isSecretCommand(msg string) bool {
+	if !strings.HasPrefix(msg, "/") {
+		return false
+	}
+	name, _, _ := strings.Cut(strings.ToLower(strings.TrimPrefix(msg, "/")), " ")
+	return lo.SomeBy(secretCommandWords, func(word string) bool {
+		return strings.Contains(name, word)
+	})
+}
+
+// add appends <msg> to the history, dropping the oldest message if history is full. Empty messages and repeats of the
+// latest message are ignored.
+func (h *history) add(msg string) {
+	if msg == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == msg) {
+		return
+	}
+	h.entries = append(h.entries, msg)
+	if len(h.entries) > h.size {
+		h.entries = h.entries[len(h.entries)-h.size:]
+	}
+}
+
+// search returns most recent message containing <query>, skipping <skip> newer matches, and true if it was found.
+func (h *history) search(query string, skip int) (string, bool) {
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		if !strings.Contains(strings.ToLower(h.entries[i]), strings.ToLower(query)) {
+			continue
+		}
+		if skip == 0 {
+			return h.entries[i], true
+		}
+		skip--
+	}
+	return "", false
+}
+
+// reverseSearch represents state of reverse history search in input field.
+type reverseSearch struct {
+	active   bool
+	query    string
+	skip     int
+	match    string
+	original string
+}
+
+// startOrContinueSearch enters reverse history search mode. If search is already active, it jumps to the next older
+// match of the current query.
+func (c *Chat) startOrContinueSearch(gui *gocui.Gui, view *gocui.View) error {
+	if !c.search.active {
+		c.search = reverseSearch{active: true, original: strings.TrimSuffix(view.Buffer(), "\n")}
+	} else if _, ok := c.history.search(c.search.query, c.search.skip+1); ok {
+		c.search.skip++
+	}
+	c.updateSearch(view)
+	return nil
+}
+
+// cancelSearch leaves reverse history search mode, restoring input field contents prior to search.
+func (c *Chat) cancelSearch(gui *gocui.Gui, view *gocui.View) error {
+	if !c.search.active {
+		return nil
+	}
+	setText(view, c.search.original)
+	c.stopSearch(view)
+	return nil
+}
+
+// acceptSearch leaves reverse history search mode, keeping found message in input field.
+func (c *Chat) acceptSearch(view *gocui.View) {
+	c.stopSearch(view)
+}
+
+// stopSearch resets reverse history search state and input field title.
+func (c *Chat) stopSearch(view *gocui.View) {
+	c.search = reverseSearch{}
+	view.Title = inputFieldTitle
+}
+
+// editSearch handles <key> and <ch> typed while reverse history search is active. It returns false if key is not
+// related to search, accepting current match.
+func (c *Chat) editSearch(view *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) bool {
+	switch {
+	case ch != 0 && mod == 0:
+		c.search.query += string(ch)
+	case key == gocui.KeySpace:
+		c.search.query += " "
+	case key == gocui.KeyBackspace || key == gocui.KeyBackspace2:
+		query := []rune(c.search.query)
+		if len(query) > 0 {
+			c.search.query = string(query[:len(query)-1])
+		}
+	default:
+		c.acceptSearch(view)
+		return false
+	}
+	c.search.skip = 0
+	c.updateSearch(view)
+	return true
+}
+
+// updateSearch finds message matching current search query and shows it in the <view>.
+func (c *Chat) updateSearch(view *gocui.View) {
+	match, ok := c.history.search(c.search.query, c.search.skip)
+	if ok {
+		c.search.match = match
+		setText(view, match)
+	}
+	failed := lo.Ternary(ok || c.search.query == "", "", "failed ")
+	view.Title = fmt.Sprintf("%vreverse-search: %v", failed, c.search.query)
+}
+
+// setText replaces contents of the <view> with <text>, placing the cursor at the end.
+func setText(view *gocui.View, text string) {
+	view.Clear()
+	_ = view.SetOrigin(0, 0)
+	_ = view.SetCursor(0, 0)
+	for _, ch := range text {
+		if ch == '\n' {
+			view.EditNewLine()
+		} else {
+			view.EditWrite(ch)
+		}
+	}
+}
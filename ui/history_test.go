@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+// newTestHistory returns history without file containing <entries>, oldest first.
+func newTestHistory(entries ...string) *history {
+	h := newHistory(historySize, "")
+	for _, msg := range entries {
+		h.add(msg)
+	}
+	return h
+}
+
+func TestHistorySearch(t *testing.T) {
+	h := newTestHistory("hello alice", "/join dev", "Hello bob", "bye")
+	tests := []struct {
+		name   string
+		query  string
+		skip   int
+		want   string
+		wantOk bool
+	}{
+		{"match", "join", 0, "/join dev", true},
+		{"ignoring case", "HELLO", 0, "Hello bob", true},
+		{"older match", "hello", 1, "hello alice", true},
+		{"no older match", "hello", 2, "", false},
+		{"no match", "carol", 0, "", false},
+		{"empty query", "", 0, "bye", true},
+		{"empty query older", "", 3, "hello alice", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := h.search(tt.query, tt.skip)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("search(%q, %v) = %q, %v, want %q, %v", tt.query, tt.skip, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestReverseSearchSteps(t *testing.T) {
+	c := &Chat{history: newTestHistory("hello alice", "bye", "hello bob")}
+	view := newTestView(t, 30, 1, "draft")
+	if err := c.startOrContinueSearch(nil, view); err != nil {
+		t.Fatal(err)
+	}
+	for _, ch := range "hello" {
+		if !c.editSearch(view, 0, ch, 0) {
+			t.Fatalf("Typed %q is not handled by search", ch)
+		}
+	}
+
+	for _, want := range []string{"hello bob", "hello alice", "hello alice"} {
+		if got := strings.TrimSuffix(view.Buffer(), "\n"); got != want {
+			t.Errorf("Input field is %q, want %q", got, want)
+		}
+		if err := c.startOrContinueSearch(nil, view); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.cancelSearch(nil, view); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSuffix(view.Buffer(), "\n"); got != "draft" {
+		t.Errorf("Input field is %q after search is cancelled, want %q", got, "draft")
+	}
+}
@@ -0,0 +1,389 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jroimartin/gocui"
+	"github.com/samber/lo"
+)
+
+// keyNames maps keys available for binding to their human-readable names.
+var keyNames = map[gocui.Key]string{
+	gocui.KeyF1:          "F1",
+	gocui.KeyF2:          "F2",
+	gocui.KeyF3:          "F3",
+	gocui.KeyF4:          "F4",
+	gocui.KeyF5:          "F5",
+	gocui.KeyF6:          "F6",
+	gocui.KeyF7:          "F7",
+	gocui.KeyF8:          "F8",
+	gocui.KeyF9:          "F9",
+	gocui.KeyF10:         "F10",
+	gocui.KeyF11:         "F11",
+	gocui.KeyF12:         "F12",
+	gocui.KeyInsert:      "Insert",
+	gocui.KeyDelete:      "Delete",
+	gocui.KeyHome:        "Home",
+	gocui.KeyEnd:         "End",
+	gocui.KeyPgup:        "PageUp",
+	gocui.KeyPgdn:        "PageDown",
+	gocui.KeyArrowUp:     "Up",
+	gocui.KeyArrowDown:   "Down",
+	gocui.KeyArrowLeft:   "Left",
+	gocui.KeyArrowRight:  "Right",
+	gocui.KeyTab:         "Tab",
+	gocui.KeyEnter:       "Enter",
+	gocui.KeyEsc:         "Esc",
+	gocui.KeyCtrlSpace:   "Ctrl+Space",
+	gocui.KeyCtrlA:       "Ctrl+A",
+	gocui.KeyCtrlB:       "Ctrl+B",
+	gocui.KeyCtrlC:       "Ctrl+C",
+	gocui.KeyCtrlD:       "Ctrl+D",
+	gocui.KeyCtrlE:       "Ctrl+E",
+	gocui.KeyCtrlF:       "Ctrl+F",
+	gocui.KeyCtrlG:       "Ctrl+G",
+	gocui.KeyCtrlK:       "Ctrl+K",
+	gocui.KeyCtrlL:       "Ctrl+L",
+	gocui.KeyCtrlN:       "Ctrl+N",
+	gocui.KeyCtrlO:       "Ctrl+O",
+	gocui.KeyCtrlP:       "Ctrl+P",
+	gocui.KeyCtrlQ:       "Ctrl+Q",
+	gocui.KeyCtrlR:       "Ctrl+R",
+	gocui.KeyCtrlS:       "Ctrl+S",
+	gocui.KeyCtrlT:       "Ctrl+T",
+	gocui.KeyCtrlU:       "Ctrl+U",
+	gocui.KeyCtrlV:       "Ctrl+V",
+	gocui.KeyCtrlW:       "Ctrl+W",
+	gocui.KeyCtrlX:       "Ctrl+X",
+	gocui.KeyCtrlY:       "Ctrl+Y",
+	gocui.KeyCtrlZ:       "Ctrl+Z",
+	gocui.MouseLeft:      "MouseLeft",
+	gocui.MouseWheelUp:   "WheelUp",
+	gocui.MouseWheelDown: "WheelDown",
+}
+
+// mouseKeys is the list of mouse keys, bound only if mouse support is enabled.
+var mouseKeys = []gocui.Key{gocui.MouseLeft, gocui.MouseWheelUp, gocui.MouseWheelDown}
+
+// binding represents key with modifier bound in the specified view. Empty view means binding is global.
+type binding struct {
+	key  gocui.Key
+	view string
+	mod  gocui.Modifier
+}
+
+// action represents UI action triggered by any of it's bindings.
+type action struct {
+	name        string
+	description string
+	bindings    []binding
+	handler     func(*gocui.Gui, *gocui.View) error
+}
+
+// actions returns registry of all UI actions with their bindings, in order of appearance in help. Bindings from config
+// replace default ones.
+func (c *Chat) actions() []action {
+	actions := c.defaultActions()
+	for i, action := range actions {
+		if bindings, ok := c.keyBindings[action.name]; ok {
+			actions[i].bindings = bindings
+		}
+		if c.opts.DisableMouse {
+			actions[i].bindings = lo.Reject(actions[i].bindings, func(b binding, _ int) bool {
+				return slices.Contains(mouseKeys, b.key)
+			})
+		}
+	}
+	return actions
+}
+
+// defaultActions returns registry of all UI actions with their default bindings.
+func (c *Chat) defaultActions() []action {
+	return []action{
+		{
+			name:        "toggle_keys_help",
+			description: "Show or hide this list of keys",
+			bindings:    []binding{{gocui.KeyF1, "", gocui.ModNone}},
+			handler:     c.toggleKeysHelp,
+		},
+		{
+			name:        "close_keys_help",
+			description: "Close list of keys",
+			bindings:    []binding{{gocui.KeyEsc, keysHelpName, gocui.ModNone}},
+			handler:     c.closeKeysHelp,
+		},
+		{
+			name:        "quit",
+			description: "Exit, asking for confirmation if input window is not empty",
+			bindings:    []binding{{gocui.KeyCtrlC, "", gocui.ModNone}},
+			handler:     c.quit,
+		},
+		{
+			name:        "next_view",
+			description: "Focus next window",
+			bindings: []binding{
+				{gocui.KeyCtrlSpace, "", gocui.ModNone},
+				{gocui.KeyTab, ChatBoxName, gocui.ModNone},
+				{gocui.KeyTab, onlineBoxName, gocui.ModNone},
+			},
+			handler: c.nextView,
+		},
+		{
+			name:        "focus_view",
+			description: "Focus clicked window",
+			bindings: []binding{
+				{gocui.MouseLeft, ChatBoxName, gocui.ModNone},
+				{gocui.MouseLeft, inputFieldName, gocui.ModNone},
+				{gocui.MouseLeft, onlineBoxName, gocui.ModNone},
+			},
+			handler: c.focusView,
+		},
+		{
+			name:        "complete_nickname",
+			description: "Complete nickname of online user in input window",
+			bindings:    []binding{{gocui.KeyTab, inputFieldName, gocui.ModNone}},
+			handler:     c.completeNickname,
+		},
+		{
+			name:        "send_message",
+			description: "Send message from input window",
+			bindings:    []binding{{gocui.KeyEnter, inputFieldName, gocui.ModNone}},
+			handler:     c.sendMessage,
+		},
+		{
+			name:        "search_history",
+			description: "Search input history backwards in input window",
+			bindings:    []binding{{gocui.KeyCtrlR, inputFieldName, gocui.ModNone}},
+			handler:     c.startOrContinueSearch,
+		},
+		{
+			name:        "cancel_search",
+			description: "Cancel input history search",
+			bindings:    []binding{{gocui.KeyEsc, inputFieldName, gocui.ModNone}},
+			handler:     c.cancelSearch,
+		},
+		// Insert new line on F3.
+		// Why not Shift+Enter? - This library only supports Alt modifier.
+		// Why not Alt+Enter? - On Windows, Alt+Enter toggles console window fullscreen mode.
+		{
+			name:        "insert_newline",
+			description: "Insert newline in input window",
+			bindings:    []binding{{gocui.KeyF3, inputFieldName, gocui.ModNone}},
+			handler:     insertNewline,
+		},
+		{
+			name:        "scroll_up",
+			description: "Scroll chat or online users window upwards",
+			bindings: []binding{
+				{gocui.KeyArrowUp, onlineBoxName, gocui.ModNone},
+				{gocui.KeyArrowUp, keysHelpName, gocui.ModNone},
+				{gocui.MouseWheelUp, ChatBoxName, gocui.ModNone},
+				{gocui.MouseWheelUp, onlineBoxName, gocui.ModNone},
+			},
+			handler: scrollUp,
+		},
+		{
+			name:        "scroll_down",
+			description: "Scroll chat or online users window downwards",
+			bindings: []binding{
+				{gocui.KeyArrowDown, onlineBoxName, gocui.ModNone},
+				{gocui.KeyArrowDown, keysHelpName, gocui.ModNone},
+				{gocui.MouseWheelDown, ChatBoxName, gocui.ModNone},
+				{gocui.MouseWheelDown, onlineBoxName, gocui.ModNone},
+			},
+			handler: scrollDown,
+		},
+		{
+			name:        "select_previous",
+			description: "Select previous line of chat window, scrolling it at the top",
+			bindings:    []binding{{gocui.KeyArrowUp, ChatBoxName, gocui.ModNone}},
+			handler:     selectPrevious,
+		},
+		{
+			name:        "select_next",
+			description: "Select next line of chat window, scrolling it at the bottom",
+			bindings:    []binding{{gocui.KeyArrowDown, ChatBoxName, gocui.ModNone}},
+			handler:     selectNext,
+		},
+		{
+			name:        "jump_to_bottom",
+			description: "Scroll chat or online users window to the end, turning autoscroll on",
+			bindings: []binding{
+				{gocui.KeyEnd, ChatBoxName, gocui.ModNone},
+				{gocui.KeyEnd, onlineBoxName, gocui.ModNone},
+				{gocui.MouseLeft, newMessagesBannerName, gocui.ModNone},
+			},
+			handler: c.jumpToBottom,
+		},
+		{
+			name:        "jump_to_top",
+			description: "Scroll chat or online users window to the beginning",
+			bindings: []binding{
+				{gocui.KeyHome, ChatBoxName, gocui.ModNone},
+				{gocui.KeyHome, onlineBoxName, gocui.ModNone},
+			},
+			handler: scrollToTop,
+		},
+		{
+			name:        "toggle_pin",
+			description: "Pin or unpin selected message of chat window",
+			bindings:    []binding{{gocui.KeyCtrlP, ChatBoxName, gocui.ModNone}},
+			handler:     c.togglePin,
+		},
+		{
+			name:        "search_chat",
+			description: "Search chat window, jumping between matches with arrows",
+			bindings:    []binding{{gocui.KeyCtrlF, ChatBoxName, gocui.ModNone}},
+			handler:     c.openScrollbackSearch,
+		},
+		{
+			name:        "react",
+			description: "React to selected message of chat window, choosing emoji with arrows and Enter",
+			bindings:    []binding{{gocui.KeyCtrlE, ChatBoxName, gocui.ModNone}},
+			handler:     c.openReactionPicker,
+		},
+		{
+			name:        "toggle_spoiler",
+			description: "Reveal or hide spoilers of selected message of chat window",
+			bindings:    []binding{{gocui.KeyCtrlR, ChatBoxName, gocui.ModNone}},
+			handler:     c.toggleSpoiler,
+		},
+		{
+			name:        "open_url",
+			description: "Open the newest URL of chat window in browser, press again for older one",
+			bindings:    []binding{{gocui.KeyCtrlO, ChatBoxName, gocui.ModNone}},
+			handler:     c.openURL,
+		},
+		{
+			name:        "clear_chat_box",
+			description: "Clear chat window",
+			bindings:    []binding{{gocui.KeyCtrlL, "", gocui.ModNone}},
+			handler:     c.clearChatBox,
+		},
+		{
+			name:        "toggle_online_box",
+			description: "Open or close online users window",
+			bindings:    []binding{{gocui.KeyF2, "", gocui.ModNone}},
+			handler:     c.toggleOnlineBox,
+		},
+		{
+			name:        "switch_target",
+			description: "Send messages from input window to the next connected server, if there are several",
+			bindings:    []binding{{gocui.KeyF5, "", gocui.ModNone}},
+			handler:     c.switchTarget,
+		},
+		{
+			name:        "toggle_timestamps",
+			description: "Switch message time between relative and absolute one",
+			bindings:    []binding{{gocui.KeyF4, "", gocui.ModNone}},
+			handler:     c.toggleRelativeTimestamps,
+		},
+	}
+}
+
+// parseKeyBindings returns bindings for actions by their names from map of action names to key names <keys>, e.g.
+// "next_view": ["Ctrl+Space", "Alt+Enter"]. Keys are bound in the same views as default keys of the action. It returns
+// error if action or key name is unknown.
+func (c *Chat) parseKeyBindings(keys map[string][]string) (map[string][]binding, error) {
+	bindings := map[string][]binding{}
+	for name, keyNames := range keys {
+		action, ok := lo.Find(c.defaultActions(), func(a action) bool {
+			return a.name == name
+		})
+		if !ok {
+			return nil, errors.Newf("Unknown action %q in key bindings", name)
+		}
+		views := lo.Uniq(lo.Map(action.bindings, func(b binding, _ int) string {
+			return b.view
+		}))
+		// Global binding fires in every view, so binding it in specific views too would run the action twice
+		if slices.Contains(views, "") {
+			views = []string{""}
+		}
+		for _, keyName := range keyNames {
+			key, mod, err := parseKey(keyName)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Parse key for action %q", name)
+			}
+			for _, view := range views {
+				bindings[name] = append(bindings[name], binding{key: key, view: view, mod: mod})
+			}
+		}
+	}
+	return bindings, nil
+}
+
+// parseKey returns key and modifier by it's case-insensitive <name>, e.g. "F2" or "Alt+Enter".
+func parseKey(name string) (gocui.Key, gocui.Modifier, error) {
+	mod := gocui.ModNone
+	keyName := name
+	if prefix, rest, ok := strings.Cut(name, "+"); ok && strings.EqualFold(prefix, "Alt") {
+		mod = gocui.ModAlt
+		keyName = rest
+	}
+	for key, n := range keyNames {
+		if strings.EqualFold(n, keyName) {
+			return key, mod, nil
+		}
+	}
+	return 0, 0, errors.Newf("Unknown key %q", name)
+}
+
+// keyName returns human-readable name of key and modifier of <b>.
+func keyName(b binding) string {
+	return lo.Ternary(b.mod == gocui.ModAlt, "Alt+", "") + keyNames[b.key]
+}
+
+// setKeybindings binds keys of every UI action. Chat box bindings of paneActions are set in chat boxes of panes too.
+func (c *Chat) setKeybindings() error {
+	for _, action := range c.actions() {
+		for _, b := range action.bindings {
+			views := []string{b.view}
+			if b.view == ChatBoxName && slices.Contains(paneActions, action.name) {
+				for _, pane := range lo.Drop(c.chats(), 1) {
+					views = append(views, pane.chatBoxView())
+				}
+			}
+			for _, view := range views {
+				if err := c.Gui.SetKeybinding(view, b.key, b.mod, action.handler); err != nil {
+					return errors.Wrap(err, fmt.Sprintf("Set keybinding for %v", action.name))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// actionKey returns name of the first keyboard key bound to action with <name> in <view> or globally, or empty string
+// if there is none.
+func (c *Chat) actionKey(name string, view string) string {
+	for _, action := range c.actions() {
+		if action.name != name {
+			continue
+		}
+		for _, b := range action.bindings {
+			if (b.view == view || b.view == "") && !slices.Contains(mouseKeys, b.key) {
+				return keyName(b)
+			}
+		}
+	}
+	return ""
+}
+
+// KeybindingsHelp returns list of lines, each describing UI action and keys bound to it.
+func (c *Chat) KeybindingsHelp() []string {
+	var lines []string
+	for _, action := range c.actions() {
+		if len(action.bindings) == 0 {
+			continue
+		}
+		keys := lo.Uniq(lo.Map(action.bindings, func(b binding, _ int) string {
+			return keyName(b)
+		}))
+		lines = append(lines, fmt.Sprintf("%v - %v", strings.Join(keys, ", "), action.description))
+	}
+	return lines
+}
@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/gocui"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     gocui.Key
+		mod     gocui.Modifier
+		wantErr bool
+	}{
+		{name: "F2", key: gocui.KeyF2, mod: gocui.ModNone},
+		{name: "home", key: gocui.KeyHome, mod: gocui.ModNone},
+		{name: "Alt+Delete", key: gocui.KeyDelete, mod: gocui.ModAlt},
+		{name: "alt+f5", key: gocui.KeyF5, mod: gocui.ModAlt},
+		{name: "F13", wantErr: true},
+		{name: "Alt+", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, mod, err := parseKey(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseKey(%q) returned error %v, want error: %v", tt.name, err, tt.wantErr)
+			}
+			if !tt.wantErr && (key != tt.key || mod != tt.mod) {
+				t.Errorf("parseKey(%q) = %v, %v, want %v, %v", tt.name, key, mod, tt.key, tt.mod)
+			}
+		})
+	}
+}
+
+func TestKeybindingsHelp(t *testing.T) {
+	c := &Chat{}
+	help := strings.Join(c.KeybindingsHelp(), "\n")
+	for _, action := range c.defaultActions() {
+		if len(action.bindings) > 0 && !strings.Contains(help, " - "+action.description) {
+			t.Errorf("Help doesn't list action %v:\n%v", action.name, help)
+		}
+	}
+
+	var err error
+	if c.keyBindings, err = c.parseKeyBindings(map[string][]string{"toggle_keys_help": {"F2", "Alt+F2"}}); err != nil {
+		t.Fatal(err)
+	}
+	if lines := c.KeybindingsHelp(); lines[0] != "F2, Alt+F2 - Show or hide this list of keys" {
+		t.Errorf("Help of overridden action is %q", lines[0])
+	}
+}
+
+func TestParseKeyBindingsUnknownAction(t *testing.T) {
+	if _, err := (&Chat{}).parseKeyBindings(map[string][]string{"fly": {"F2"}}); err == nil {
+		t.Error("parseKeyBindings returned no error for unknown action")
+	}
+}
@@ -0,0 +1,247 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/errors"
+	"github.com/fatih/color"
+	"github.com/jroimartin/gocui"
+	"github.com/samber/lo"
+)
+
+// Message represents message in chat box. Chat box text is rendered from messages each time it's redrawn, so message
+// can be changed after it's printed, e.g. to update it's delivery status.
+type Message struct {
+	ID          int64          // ID assigned by server, printed before text to reference message in commands
+	LocalID     int64          // Client-generated ID of own message, used to update it's delivery status
+	Nickname    string         // Author of message, or the other side of private message
+	Source      string         // Device message is sent from, e.g. "mobile", shown as glyph after nickname if known
+	Text        string         // Text of message, with formatting and spoiler markers
+	Time        time.Time      // Time message is sent at
+	IsSystem    bool           // Message is sent by server, nickname is replaced with "SYSTEM" label
+	Level       string         // Level of system message, e.g. SystemLevelWarn, changing color of it's label
+	IsImportant bool           // Message is marked with "!" sign
+	IsAction    bool           // Message is action, e.g. "/me waves", printed as "* nickname waves"
+	IsPrivate   bool           // Message is private, printed with "[PM from nickname]" label
+	IsOutgoing  bool           // Private message is sent by user, printed with "[PM to nickname]" label
+	IsEdited    bool           // Message text was changed by author, marked with "(edited)"
+	Status      DeliveryStatus // Delivery status of own message, DeliveryNone for others
+}
+
+// label returns colored nickname part of message <m>, printed between time and text.
+func (m Message) label() string {
+	switch {
+	case m.IsPrivate:
+		return color.MagentaString("[PM %v %v]", lo.Ternary(m.IsOutgoing, "to", "from"), m.Nickname)
+	case m.IsAction && !m.IsSystem:
+		label := "* " + m.Nickname
+		if glyph, ok := sourceGlyphs[m.Source]; ok {
+			label += " " + glyph
+		}
+		label = ColorForNickname(m.Nickname).Add(color.Bold).Sprint(label)
+		return lo.Ternary(m.ID == 0, label, fmt.Sprintf("#%v %v", m.ID, label))
+	}
+	label := lo.Ternary(m.IsSystem, systemLabelFor(m.Level), ColorForNickname(m.Nickname).Sprint(m.Nickname))
+	if glyph, ok := sourceGlyphs[m.Source]; ok {
+		label += " " + glyph
+	}
+	if m.IsImportant {
+		label = color.New(color.FgRed, color.Bold).Sprint("!") + " " + label
+	}
+	return label
+}
+
+// text returns text of message <m>, prefixed with ID if it's known, unless it's printed before the label.
+func (m Message) text() string {
+	if m.ID == 0 || (m.IsAction && !m.IsSystem) {
+		return m.Text
+	}
+	return fmt.Sprintf("#%v %v", m.ID, m.Text)
+}
+
+// isRepeatOf returns true if message <m> should be collapsed with the <previous> one, showing repeat count. Own
+// messages with delivery status and messages with spoilers, which can be revealed separately, are never collapsed.
+func (m Message) isRepeatOf(previous Message) bool {
+	if m.LocalID != 0 || previous.LocalID != 0 || hasSpoilers(m.Text) {
+		return false
+	}
+	m.Time, previous.Time = time.Time{}, time.Time{}
+	return m == previous
+}
+
+// AppendMessage prints <msg> to chat box view. Message identical to the previous one is collapsed with it, showing
+// repeat count. Spoilers in message are hidden until revealed. If chat box is scrolled up, message is counted as
+// unread. The oldest messages are removed once there are more than Options.Scrollback of them. Message is added to chat
+// box log right away, while view is updated from it in GUI goroutine, so it's safe to call from any goroutine.
+func (c *Chat) AppendMessage(msg Message) error {
+	msg.Nickname, msg.Source, msg.Text = sanitize(msg.Nickname), sanitize(msg.Source), sanitize(msg.Text)
+	msg.Level = sanitize(msg.Level)
+
+	c.printMu.Lock()
+	c.urls.add(findURLs(msg.Text))
+	if c.chatBoxLog.addMessage(msg, c.clock.Now()) {
+		// Repeat count of already printed message is changed
+		c.chatBoxStale = true
+	}
+	c.printMu.Unlock()
+
+	c.Gui.Update(func(g *gocui.Gui) error {
+		chatBox, err := g.View(c.chatBoxView())
+		if err != nil {
+			return nil
+		}
+		c.printMu.Lock()
+		defer c.printMu.Unlock()
+		if !chatBox.Autoscroll {
+			c.unread++
+		}
+		return c.drawChatBox(chatBox)
+	})
+	return nil
+}
+
+// SetMessageID sets ID assigned by server to own message with client-generated <localID>, so it's printed and message
+// can be referenced in commands.
+func (c *Chat) SetMessageID(localID int64, id int64) {
+	c.updateMessage(func(msg *Message) bool {
+		if msg.LocalID != localID || msg.ID == id {
+			return false
+		}
+		msg.ID = id
+		return true
+	})
+}
+
+// EditMessage replaces text of message with server-assigned <id> with <text>, marking it as edited. It returns false
+// if message is not in chat box, e.g. it's removed by /clear or was never received.
+func (c *Chat) EditMessage(id int64, text string) bool {
+	text = sanitize(text)
+	return c.updateMessage(func(msg *Message) bool {
+		if msg.ID != id {
+			return false
+		}
+		msg.Text = text
+		msg.IsEdited = true
+		return true
+	})
+}
+
+// DeleteMessage removes message with server-assigned <id> from chat box. It returns false if message is not in chat
+// box.
+func (c *Chat) DeleteMessage(id int64) bool {
+	c.printMu.Lock()
+	count := len(c.chatBoxLog.entries)
+	c.chatBoxLog.entries = slices.DeleteFunc(c.chatBoxLog.entries, func(entry logEntry) bool {
+		return entry.msg != nil && entry.msg.ID == id
+	})
+	deleted := len(c.chatBoxLog.entries) != count
+	c.chatBoxStale = c.chatBoxStale || deleted
+	c.printMu.Unlock()
+	if deleted {
+		c.renderMessages()
+	}
+	return deleted
+}
+
+// updateMessage runs <update> for messages in chat box, newest first, until it returns true, meaning the message is
+// changed. Chat box is redrawn then. It returns false if no message is changed.
+func (c *Chat) updateMessage(update func(msg *Message) bool) bool {
+	c.printMu.Lock()
+	changed := false
+	for i := len(c.chatBoxLog.entries) - 1; i >= 0 && !changed; i-- {
+		if msg := c.chatBoxLog.entries[i].msg; msg != nil {
+			changed = update(msg)
+		}
+	}
+	c.printMu.Unlock()
+	if changed {
+		c.renderMessages()
+	}
+	return changed
+}
+
+// renderEntry returns text of chat box log <entry>, ending with new line. Message is prefixed with time, unless it
+// equals to <lastTimestamp> and compact timestamps are enabled. <lastTimestamp> is updated with time of message. Lines
+// of multi-line message after the first one are indented to start under it's text.
+func (c *Chat) renderEntry(entry logEntry, lastTimestamp *string) string {
+	if entry.msg == nil {
+		return entry.text
+	}
+	msg := *entry.msg
+	prefix := msg.label()
+	if c.opts.TimestampFormat != TimestampsOff {
+		timestamp := c.timestamp(msg)
+		time := color.GreenString("%v", timestamp)
+		if c.opts.CompactTimestamps && timestamp == *lastTimestamp {
+			time = strings.Repeat(" ", utf8.RuneCountInString(timestamp))
+		}
+		*lastTimestamp = timestamp
+		prefix = time + " " + prefix
+	}
+	// Continuation lines of multi-line message are aligned with the first one
+	indent := "\n" + strings.Repeat(" ", utf8.RuneCountInString(ansiEscape.ReplaceAllString(prefix, ""))+1)
+	text := prefix + " " + strings.ReplaceAll(c.renderMessage(msg.text(), entry.revealed), "\n", indent)
+	if msg.IsEdited {
+		text += " " + color.HiBlackString("(edited)")
+	}
+	if entry.repeats > 1 {
+		text += fmt.Sprintf(" (x%v)", entry.repeats)
+	}
+	if msg.Status != DeliveryNone {
+		text += " " + msg.Status.glyph()
+	}
+	return text + "\n"
+}
+
+// renderEntries returns texts of all chat box log entries in order. It should be called with printMu locked.
+func (c *Chat) renderEntries() []string {
+	c.lastTimestamp = ""
+	return lo.Map(c.chatBoxLog.entries, func(entry logEntry, _ int) string {
+		return c.renderEntry(entry, &c.lastTimestamp)
+	})
+}
+
+// redrawChatBox replaces contents of <chatBox> view with text rendered from chat box log. Scroll position is kept. It
+// should be called with printMu locked.
+func (c *Chat) redrawChatBox(chatBox *gocui.View) error {
+	chatBox.Clear()
+	_, err := fmt.Fprint(chatBox, strings.Join(c.renderEntries(), ""))
+	c.drawnEntries, c.chatBoxStale = len(c.chatBoxLog.entries), false
+	return errors.Wrap(err, "Redraw chat box")
+}
+
+// drawChatBox prints chat box log entries added since <chatBox> view was drawn last time, or redraws it if printed
+// entries are changed or removed since then. The oldest entries are trimmed according to Options.Scrollback. It
+// should be called in GUI goroutine with printMu locked.
+func (c *Chat) drawChatBox(chatBox *gocui.View) error {
+	if c.chatBoxStale || c.drawnEntries > len(c.chatBoxLog.entries) {
+		if err := c.redrawChatBox(chatBox); err != nil {
+			return err
+		}
+		return c.trimScrollback(chatBox)
+	}
+	for _, entry := range c.chatBoxLog.entries[c.drawnEntries:] {
+		if _, err := fmt.Fprint(chatBox, c.renderEntry(entry, &c.lastTimestamp)); err != nil {
+			return errors.Wrap(err, "Print to chat box")
+		}
+	}
+	c.drawnEntries = len(c.chatBoxLog.entries)
+	return c.trimScrollback(chatBox)
+}
+
+// renderMessages redraws chat box from chat box log in GUI goroutine, e.g. after messages are changed.
+func (c *Chat) renderMessages() {
+	c.Gui.Update(func(g *gocui.Gui) error {
+		chatBox, err := g.View(c.chatBoxView())
+		if err != nil {
+			return nil
+		}
+		c.printMu.Lock()
+		defer c.printMu.Unlock()
+		return c.redrawChatBox(chatBox)
+	})
+}
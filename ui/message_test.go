@@ -0,0 +1,40 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+// withoutColors disables colors until test <t> finishes, so rendered text can be compared as is.
+func withoutColors(t *testing.T) {
+	t.Helper()
+	noColor := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = noColor })
+}
+
+func TestMessageLabel(t *testing.T) {
+	withoutColors(t)
+	tests := []struct {
+		name string
+		msg  Message
+		want string
+	}{
+		{"normal", Message{Nickname: "alice"}, "alice"},
+		{"important", Message{Nickname: "alice", IsImportant: true}, "! alice"},
+		{"mobile", Message{Nickname: "alice", Source: "mobile"}, "alice 📱"},
+		{"desktop", Message{Nickname: "alice", Source: "desktop"}, "alice 💻"},
+		{"web", Message{Nickname: "alice", Source: "web"}, "alice 🌐"},
+		{"unknown source", Message{Nickname: "alice", Source: "fridge"}, "alice"},
+		{"action from mobile", Message{Nickname: "alice", Source: "mobile", IsAction: true}, "* alice 📱"},
+		{"important from mobile", Message{Nickname: "alice", Source: "mobile", IsImportant: true}, "! alice 📱"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if label := tt.msg.label(); label != tt.want {
+				t.Errorf("Label is %q, want %q", label, tt.want)
+			}
+		})
+	}
+}
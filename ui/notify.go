@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gen2brain/beeep"
+)
+
+// represents ways to notify about new messages.
+const (
+	NotifyOff     = ""
+	NotifyBell    = "bell"
+	NotifyDesktop = "desktop"
+)
+
+// unattendedAfter is the time without key presses in input field after which user is considered away from terminal.
+// Terminal focus can't be detected, so it's used instead.
+const unattendedAfter = time.Minute
+
+// NotifyMessage notifies about new message <msg> from user with <nickname> in the way set by Options.Notifications,
+// if user is away from terminal or chat box is scrolled up. If <isMention> is true, it notifies in any case. It does
+// nothing while notifications are muted.
+func (c *Chat) NotifyMessage(nickname string, msg string, isMention bool) {
+	if c.opts.Notifications == NotifyOff || c.notificationsMuted() {
+		return
+	}
+	if !isMention && !c.isUnattended() {
+		return
+	}
+	if c.opts.Notifications == NotifyDesktop {
+		if err := beeep.Notify(nickname, msg, ""); err != nil {
+			c.log.WithError(err).Debug("Show desktop notification, ringing the bell instead")
+		} else {
+			return
+		}
+	}
+	fmt.Print("\a")
+}
+
+// isUnattended returns true if no key was pressed in input field within unattendedAfter or chat box is scrolled up.
+func (c *Chat) isUnattended() bool {
+	if c.clock.Now().Sub(time.Unix(0, c.root().lastKeyAt.Load())) > unattendedAfter {
+		return true
+	}
+	chatBox, err := c.Gui.View(c.chatBoxView())
+	return err == nil && !chatBox.Autoscroll
+}
@@ -0,0 +1,32 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"go_chat_client/util/clock"
+)
+
+func TestMuteNotifications(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC))
+	c := &Chat{clock: fake}
+	if c.notificationsMuted() {
+		t.Fatal("Notifications are muted by default")
+	}
+
+	c.MuteNotifications(time.Minute * 30)
+	fake.Advance(time.Minute*30 - time.Second)
+	if !c.notificationsMuted() {
+		t.Error("Notifications are unmuted before duration passed")
+	}
+	fake.Advance(time.Second)
+	if c.notificationsMuted() {
+		t.Error("Notifications are still muted once duration passed")
+	}
+
+	c.MuteNotifications(time.Hour)
+	c.MuteNotifications(0)
+	if c.notificationsMuted() {
+		t.Error("Notifications are muted after zero duration is set")
+	}
+}
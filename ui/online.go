@@ -0,0 +1,163 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jroimartin/gocui"
+	"github.com/samber/lo"
+)
+
+// OnlineUser represents user shown in online users box. Fields except Nickname are empty if server doesn't send them.
+type OnlineUser struct {
+	Nickname string
+	Status   string        // Custom status text, e.g. "busy"
+	Idle     time.Duration // Time since the last activity of user
+	Role     string        // Role of user on server, e.g. "admin"
+	Away     bool          // User has set away status
+	Muted    bool          // Messages of user are hidden
+}
+
+// rolePrefixes maps roles of users to prefixes shown before their nicknames in online users box.
+var rolePrefixes = map[string]string{
+	"owner":     "~",
+	"admin":     "@",
+	"moderator": "%",
+	"mod":       "%",
+}
+
+// represents limits of online users box width, if it's not set in Options.
+const (
+	minOnlineBoxWidth      = 20 // Columns, including borders
+	maxOnlineBoxWidthRatio = 3  // Box takes at most 1/maxOnlineBoxWidthRatio of terminal width
+)
+
+// nicknames returns nicknames of <users>.
+func nicknames(users []OnlineUser) []string {
+	return lo.Map(users, func(u OnlineUser, _ int) string {
+		return u.Nickname
+	})
+}
+
+// renderOnlineUsers returns <users> sorted by nickname, one per line, prefixed according to rolePrefixes and marking
+// away and muted users. If <detailed> is true, role, status and idle time of user are shown on indented line below nickname, if
+// there are any.
+func renderOnlineUsers(users []OnlineUser, detailed bool) string {
+	users = slices.Clone(users)
+	slices.SortFunc(users, func(a, b OnlineUser) int {
+		return strings.Compare(a.Nickname, b.Nickname)
+	})
+
+	lines := make([]string, 0, len(users))
+	for _, user := range users {
+		line := rolePrefixes[strings.ToLower(user.Role)] + sanitize(user.Nickname)
+		line += lo.Ternary(user.Away, " (away)", "") + lo.Ternary(user.Muted, " (muted)", "")
+		lines = append(lines, line)
+		if !detailed {
+			continue
+		}
+		var details []string
+		if user.Role != "" {
+			details = append(details, sanitize(user.Role))
+		}
+		if user.Status != "" {
+			details = append(details, sanitize(user.Status))
+		}
+		if user.Idle >= time.Minute {
+			details = append(details, fmt.Sprintf("idle %v", user.Idle.Truncate(time.Minute)))
+		}
+		if len(details) > 0 {
+			lines = append(lines, "  "+strings.Join(details, ", "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SetDetailedOnlineBox switches online users box between showing only nicknames and showing <detailed> information
+// about users, and redraws it.
+func (c *Chat) SetDetailedOnlineBox(detailed bool) {
+	c.Gui.Update(func(g *gocui.Gui) error {
+		c.detailedOnline = detailed
+		return c.drawOnlineBox(g)
+	})
+}
+
+// SetOnlineCount sets amount of online users to show in title of online users box without changing the list of them,
+// and redraws the title.
+func (c *Chat) SetOnlineCount(count int) {
+	c.Gui.Update(func(g *gocui.Gui) error {
+		c.onlineCount = count
+		if onlineBox, err := g.View(onlineBoxName); err == nil && c.target() == c {
+			onlineBox.Title = onlineBoxTitle(count)
+		}
+		return nil
+	})
+}
+
+// onlineBoxTitle returns title of online users box showing <count> of online users.
+func onlineBoxTitle(count int) string {
+	return fmt.Sprintf("%v online", count)
+}
+
+// drawOnlineBox prints the last received list of online users to online users box view, if it exists and <c> is
+// target of input field.
+func (c *Chat) drawOnlineBox(gui *gocui.Gui) error {
+	onlineBox, err := gui.View(onlineBoxName)
+	if err != nil || c.target() != c {
+		return nil
+	}
+
+	onlineBox.Clear()
+	onlineBox.Title = onlineBoxTitle(c.onlineCount)
+	_, err = fmt.Fprint(onlineBox, renderOnlineUsers(c.onlineUsers, c.detailedOnline))
+	return errors.Wrap(err, "Print online users")
+}
+
+// onlineBoxLayout is a GUI manager function for online users box. It's shown while the box is open, see
+// showOnlineBox, and is positioned again on every layout, e.g. after terminal is resized or the longest line of it is
+// changed. It shows users of target of input field, which online box open listeners run once the box is created.
+func (c *Chat) onlineBoxLayout(gui *gocui.Gui) error {
+	if !c.onlineBoxOpen {
+		return nil
+	}
+
+	target := c.target()
+	maxX, maxY := gui.Size()
+	x0, x1 := target.onlineBoxColumns(maxX)
+	onlineBox, err := gui.SetView(onlineBoxName, x0, 0, x1, maxY-9)
+	if err != nil && !errors.Is(err, gocui.ErrUnknownView) {
+		return errors.Wrap(err, fmt.Sprintf("Create view for %v", onlineBoxName))
+	}
+	if errors.Is(err, gocui.ErrUnknownView) {
+		c.addVisibleView(onlineBoxName)
+		onlineBox.Title = onlineBoxTitle(target.onlineCount)
+		for _, listener := range target.onOnlineBoxOpen {
+			listener()
+		}
+	}
+
+	return nil
+}
+
+// onlineBoxColumns returns the first and the last column of online users box in terminal <maxX> columns wide. Width is
+// taken from Options.OnlineBoxWidth or fits the longest line of the box, from minOnlineBoxWidth to
+// 1/maxOnlineBoxWidthRatio of terminal width.
+func (c *Chat) onlineBoxColumns(maxX int) (int, int) {
+	width := c.opts.OnlineBoxWidth
+	if width <= 0 {
+		lines := strings.Split(renderOnlineUsers(c.onlineUsers, c.detailedOnline), "\n")
+		longest := lo.Max(lo.Map(lines, func(line string, _ int) int {
+			return utf8.RuneCountInString(line)
+		}))
+		width = min(max(longest+2, minOnlineBoxWidth), max(maxX/maxOnlineBoxWidthRatio, minOnlineBoxWidth))
+	}
+	width = min(width, maxX)
+	if c.opts.OnlineBoxLeft {
+		return 0, width - 1
+	}
+	return maxX - width, maxX - 1
+}
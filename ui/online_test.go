@@ -0,0 +1,142 @@
+package ui
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jroimartin/gocui"
+)
+
+// newOnlineUsersChat returns chat with online users queue as created by NewChat, without GUI.
+func newOnlineUsersChat() *Chat {
+	return &Chat{onlineUsersCh: make(chan []OnlineUser, 1)}
+}
+
+func TestRenderOnlineUsers(t *testing.T) {
+	users := []OnlineUser{
+		{Nickname: "carol", Status: "busy", Idle: time.Minute*5 + time.Second*30},
+		{Nickname: "alice", Role: "Admin", Away: true},
+		{Nickname: "bob", Idle: time.Second * 59, Muted: true},
+		{Nickname: "dave", Role: "guest", Status: "on a trip"},
+	}
+	tests := []struct {
+		name     string
+		detailed bool
+		want     string
+	}{
+		{"names", false, "@alice (away)\nbob (muted)\ncarol\ndave"},
+		{"detailed", true, "@alice (away)\n  Admin\nbob (muted)\ncarol\n  busy, idle 5m0s\ndave\n  guest, on a trip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderOnlineUsers(users, tt.detailed); got != tt.want {
+				t.Errorf("renderOnlineUsers(%v) = %q, want %q", tt.detailed, got, tt.want)
+			}
+			if users[0].Nickname != "carol" {
+				t.Errorf("renderOnlineUsers sorted given users")
+			}
+		})
+	}
+}
+
+func TestSetOnlineUsersNeverBlocks(t *testing.T) {
+	c := newOnlineUsersChat()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			c.SetOnlineUsers([]OnlineUser{{Nickname: "alice", Idle: time.Duration(i)}})
+		}
+		c.SetOnlineUsers([]OnlineUser{{Nickname: "bob"}})
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("SetOnlineUsers blocked without UpdateOnlineBox running")
+	}
+
+	want := []OnlineUser{{Nickname: "bob"}}
+	if users := <-c.onlineUsersCh; !slices.Equal(users, want) {
+		t.Errorf("Queued users are %v, want the latest %v", users, want)
+	}
+	select {
+	case users := <-c.onlineUsersCh:
+		t.Errorf("Outdated users %v are queued", users)
+	default:
+	}
+}
+
+func TestSetOnlineUsersConcurrently(t *testing.T) {
+	c := newOnlineUsersChat()
+	stop := make(chan struct{})
+	var reader sync.WaitGroup
+	reader.Add(1)
+	go func() {
+		defer reader.Done()
+		for {
+			select {
+			case <-c.onlineUsersCh:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var writers sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			for j := 0; j < 100; j++ {
+				c.SetOnlineUsers([]OnlineUser{{Nickname: "alice"}})
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		writers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("SetOnlineUsers blocked while called concurrently")
+	}
+	close(stop)
+	reader.Wait()
+}
+
+func TestDrawOnlineBoxCount(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+		want  string
+	}{
+		{"count of the list", 2, "2 online"},
+		{"count pushed by server", 150, "150 online"},
+		{"nobody online", 0, "0 online"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gui := &gocui.Gui{}
+			onlineBox, err := gui.SetView(onlineBoxName, 0, 0, 30, 10)
+			if err != nil && err != gocui.ErrUnknownView {
+				t.Fatal(err)
+			}
+			c := &Chat{onlineUsers: []OnlineUser{{Nickname: "bob"}, {Nickname: "alice"}}, onlineCount: tt.count}
+
+			if err = c.drawOnlineBox(gui); err != nil {
+				t.Fatal(err)
+			}
+			if onlineBox.Title != tt.want {
+				t.Errorf("Title is %q, want %q", onlineBox.Title, tt.want)
+			}
+			if users := strings.TrimSpace(onlineBox.Buffer()); users != "alice\nbob" {
+				t.Errorf("Online box is %q, want the list of users kept", users)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jroimartin/gocui"
+	"github.com/samber/lo"
+)
+
+// paneTargetMark is shown at the beginning of title of chat box which is target of input field, if there are panes.
+// UI library positions title symbols by bytes, so it's ASCII.
+const paneTargetMark = "* "
+
+// paneActions is the list of names of UI actions which are bound in chat boxes of panes like in chat box of main chat.
+// Panes are never focused, so only mouse bindings of them fire, e.g. wheel scrolling.
+var paneActions = []string{"scroll_up", "scroll_down"}
+
+// AddPane returns new chat shown in it's own chat box next to chat box of <c>, e.g. for connection to another server.
+// Pane shares input field, online users box and status bar with <c>: messages from input field are sent to the target
+// chosen with switchTarget, which status and online users are shown. It should be called before Draw.
+func (c *Chat) AddPane() *Chat {
+	if len(c.panes) == 0 {
+		c.panes = []*Chat{c}
+	}
+	pane := &Chat{
+		Gui:           c.Gui,
+		log:           c.log,
+		clock:         c.clock,
+		opts:          c.opts,
+		keyBindings:   c.keyBindings,
+		onlineUsersCh: make(chan []OnlineUser, 1),
+		relativeTimes: c.relativeTimes,
+		main:          c,
+		chatBoxName:   fmt.Sprintf("%v_%v", ChatBoxName, len(c.panes)+1),
+	}
+	c.panes = append(c.panes, pane)
+	return pane
+}
+
+// chatBoxView returns name of view messages of <c> are printed to: ChatBoxName for main chat or own one for pane.
+func (c *Chat) chatBoxView() string {
+	return lo.Ternary(c.chatBoxName == "", ChatBoxName, c.chatBoxName)
+}
+
+// root returns main chat of pane <c>, or <c> itself if it's not a pane.
+func (c *Chat) root() *Chat {
+	if c.main != nil {
+		return c.main
+	}
+	return c
+}
+
+// chats returns main chat followed by it's panes, in order they are shown.
+func (c *Chat) chats() []*Chat {
+	root := c.root()
+	if len(root.panes) == 0 {
+		return []*Chat{root}
+	}
+	return root.panes
+}
+
+// target returns chat which input field sends messages to and which status and online users are shown: main chat,
+// unless another pane is chosen with switchTarget.
+func (c *Chat) target() *Chat {
+	return c.chats()[c.root().targetIdx]
+}
+
+// switchTarget makes the next pane target of input field, cycling back to main chat after the last one. Status bar
+// and online users box are redrawn for the new target and it's online box open listeners run, so the list of users is
+// requested. It does nothing if there are no panes.
+func (c *Chat) switchTarget(gui *gocui.Gui, view *gocui.View) error {
+	if len(c.panes) == 0 {
+		return nil
+	}
+	c.targetIdx = (c.targetIdx + 1) % len(c.panes)
+	c.completion.reset()
+
+	target := c.target()
+	if err := target.drawStatusBar(gui); err != nil {
+		return err
+	}
+	if !c.onlineBoxOpen {
+		return nil
+	}
+	for _, listener := range target.onOnlineBoxOpen {
+		listener()
+	}
+	return target.drawOnlineBox(gui)
+}
+
+// panesLayout is a GUI manager function for chat boxes of panes, placed to the right of chat box of main chat in the
+// same rows, see chatBoxRows.
+func (c *Chat) panesLayout(gui *gocui.Gui) error {
+	maxX, maxY := gui.Size()
+	columns := paneColumns(maxX, len(c.chats()))
+	y0, y1 := c.chatBoxRows(maxY)
+	for i, pane := range lo.Drop(c.chats(), 1) {
+		if err := pane.paneLayout(gui, columns[i+1][0], y0, columns[i+1][1], y1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// paneLayout creates chat box of pane <c> at the given position or moves it there. Like with chat box of main chat,
+// title shows amount of unread messages while it's scrolled up.
+func (c *Chat) paneLayout(gui *gocui.Gui, x0 int, y0 int, x1 int, y1 int) error {
+	name := c.chatBoxView()
+	chatBox, err := gui.SetView(name, x0, y0, x1, y1)
+	if err != nil && !errors.Is(err, gocui.ErrUnknownView) {
+		return errors.Wrap(err, fmt.Sprintf("Create view for %v", name))
+	}
+	if errors.Is(err, gocui.ErrUnknownView) {
+		chatBox.Wrap = true
+		chatBox.Autoscroll = true
+	}
+
+	c.printMu.Lock()
+	defer c.printMu.Unlock()
+	if chatBox.Autoscroll {
+		c.unread = 0
+	}
+	chatBox.Title = c.chatBoxTitle(chatBox)
+	return nil
+}
+
+// paneColumns returns the first and the last column of each of <count> chat boxes splitting terminal <maxX> columns
+// wide into equal parts side by side. The last chat box also takes columns left after division.
+func paneColumns(maxX int, count int) [][2]int {
+	count = max(count, 1)
+	width := maxX / count
+	columns := make([][2]int, count)
+	for i := range columns {
+		columns[i] = [2]int{i * width, (i+1)*width - 1}
+	}
+	columns[count-1][1] = maxX - 1
+	return columns
+}
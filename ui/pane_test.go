@@ -0,0 +1,174 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go_chat_client/util/clock"
+
+	"github.com/jroimartin/gocui"
+)
+
+// newTestPanes returns main chat with one pane, both with status and online users set, and GUI which is never
+// initialized with status bar, open online users box and input field views.
+func newTestPanes(t *testing.T) (*Chat, *Chat, *gocui.Gui) {
+	t.Helper()
+	gui := &gocui.Gui{}
+	for _, name := range []string{statusBarName, onlineBoxName, inputFieldName} {
+		if _, err := gui.SetView(name, 0, 0, 30, 10); err != nil && err != gocui.ErrUnknownView {
+			t.Fatalf("Create view %v: %v", name, err)
+		}
+	}
+	c := &Chat{
+		clock:         clock.NewFake(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)),
+		history:       newHistory(historySize, ""),
+		opts:          Options{MaxMessageLength: 100},
+		onlineBoxOpen: true,
+	}
+	pane := c.AddPane()
+	c.status, pane.status = status{nickname: "bob", server: "main:1"}, status{nickname: "carol", server: "other:2"}
+	c.onlineUsers, pane.onlineUsers = []OnlineUser{{Nickname: "alice"}}, []OnlineUser{{Nickname: "dave"}}
+	return c, pane, gui
+}
+
+// viewBuffer returns contents of view with <name> of <gui>, failing the test if there is no such view.
+func viewBuffer(t *testing.T, gui *gocui.Gui, name string) string {
+	t.Helper()
+	view, err := gui.View(name)
+	if err != nil {
+		t.Fatalf("Get view %v: %v", name, err)
+	}
+	return view.Buffer()
+}
+
+func TestPaneColumns(t *testing.T) {
+	tests := []struct {
+		maxX  int
+		count int
+		want  [][2]int
+	}{
+		{100, 0, [][2]int{{0, 99}}},
+		{100, 1, [][2]int{{0, 99}}},
+		{100, 2, [][2]int{{0, 49}, {50, 99}}},
+		{101, 3, [][2]int{{0, 32}, {33, 65}, {66, 100}}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v columns, %v chat boxes", tt.maxX, tt.count), func(t *testing.T) {
+			if got := paneColumns(tt.maxX, tt.count); !slices.Equal(got, tt.want) {
+				t.Errorf("paneColumns(%v, %v) = %v, want %v", tt.maxX, tt.count, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddPane(t *testing.T) {
+	c := &Chat{}
+	first, second := c.AddPane(), c.AddPane()
+
+	if want := []*Chat{c, first, second}; !slices.Equal(first.chats(), want) {
+		t.Errorf("Chats of pane are %v, want %v", first.chats(), want)
+	}
+	for chat, want := range map[*Chat]string{c: ChatBoxName, first: "chat_box_2", second: "chat_box_3"} {
+		if name := chat.chatBoxView(); name != want {
+			t.Errorf("Chat box view is %v, want %v", name, want)
+		}
+	}
+	if target := second.target(); target != c {
+		t.Errorf("Target is %p, want main chat %p", target, c)
+	}
+}
+
+func TestSwitchTarget(t *testing.T) {
+	c, pane, gui := newTestPanes(t)
+	var requested []string
+	c.AddOnOnlineBoxOpenListener(func() { requested = append(requested, "main") })
+	pane.AddOnOnlineBoxOpenListener(func() { requested = append(requested, "pane") })
+	if err := c.drawOnlineBox(gui); err != nil {
+		t.Fatal(err)
+	}
+	if err := pane.drawOnlineBox(gui); err != nil {
+		t.Fatal(err)
+	}
+	if online := viewBuffer(t, gui, onlineBoxName); !strings.Contains(online, "alice") {
+		t.Errorf("Online box is %q before switch, want users of main chat", online)
+	}
+
+	if err := c.switchTarget(gui, nil); err != nil {
+		t.Fatal(err)
+	}
+	if target := c.target(); target != pane {
+		t.Fatalf("Target is %p, want pane %p", target, pane)
+	}
+	if online := viewBuffer(t, gui, onlineBoxName); !strings.Contains(online, "dave") || strings.Contains(online, "alice") {
+		t.Errorf("Online box is %q, want users of pane", online)
+	}
+	if statusBar := viewBuffer(t, gui, statusBarName); !strings.Contains(statusBar, "carol@other:2") {
+		t.Errorf("Status bar is %q, want status of pane", statusBar)
+	}
+	if err := c.drawStatusBar(gui); err != nil {
+		t.Fatal(err)
+	}
+	if statusBar := viewBuffer(t, gui, statusBarName); !strings.Contains(statusBar, "carol@other:2") {
+		t.Errorf("Status bar is %q after main chat status is drawn, want status of pane", statusBar)
+	}
+
+	if err := c.switchTarget(gui, nil); err != nil {
+		t.Fatal(err)
+	}
+	if target := c.target(); target != c {
+		t.Errorf("Target is %p after the last pane, want main chat %p", target, c)
+	}
+	if statusBar := viewBuffer(t, gui, statusBarName); !strings.Contains(statusBar, "bob@main:1") {
+		t.Errorf("Status bar is %q, want status of main chat", statusBar)
+	}
+	if want := []string{"pane", "main"}; !slices.Equal(requested, want) {
+		t.Errorf("Online users are requested by %v, want %v", requested, want)
+	}
+}
+
+func TestSendMessageToTarget(t *testing.T) {
+	c, pane, gui := newTestPanes(t)
+	var sent []string
+	c.AddOnMsgSendListener(func(msg string) { sent = append(sent, "main: "+msg) })
+	pane.AddOnMsgSendListener(func(msg string) { sent = append(sent, "pane: "+msg) })
+	send := func(msg string) {
+		t.Helper()
+		inputField, _ := gui.View(inputFieldName)
+		if _, err := fmt.Fprint(inputField, msg); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.sendMessage(gui, inputField); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	send("first")
+	if err := c.switchTarget(gui, nil); err != nil {
+		t.Fatal(err)
+	}
+	send("second")
+
+	if want := []string{"main: first", "pane: second"}; !slices.Equal(sent, want) {
+		t.Errorf("Sent messages are %v, want %v", sent, want)
+	}
+}
+
+func TestChatBoxTitle(t *testing.T) {
+	c, pane, _ := newTestPanes(t)
+	chatBox := newTestView(t, 10, 3, "")
+	chatBox.Autoscroll = true
+	pane.unread = 2
+
+	if title := c.chatBoxTitle(chatBox); title != paneTargetMark+"Chat: bob@main:1" {
+		t.Errorf("Title of target is %q", title)
+	}
+	if title := pane.chatBoxTitle(chatBox); title != "Chat: carol@other:2 (2 new)" {
+		t.Errorf("Title of pane is %q", title)
+	}
+	if title := (&Chat{}).chatBoxTitle(chatBox); title != "Chat" {
+		t.Errorf("Title without panes is %q, want %q", title, "Chat")
+	}
+}
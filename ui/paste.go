@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jroimartin/gocui"
+)
+
+// Sequences turning bracketed paste mode on and off. In this mode terminal encloses pasted text in "ESC[200~" and
+// "ESC[201~", so newlines in it can be told apart from Enter presses.
+const (
+	enableBracketedPaste  = "\x1b[?2004h"
+	disableBracketedPaste = "\x1b[?2004l"
+)
+
+// UI library doesn't know bracketed paste sequences, so they are typed as "[" with Alt modifier followed by the rest of
+// sequence, pasteStartSuffix or pasteEndSuffix.
+const (
+	pasteStartSuffix = "200~"
+	pasteEndSuffix   = "201~"
+)
+
+// pasteBurstInterval is the maximum interval between key presses at which they are considered pasted rather than
+// typed, for terminals not supporting bracketed paste.
+const pasteBurstInterval = 5 * time.Millisecond
+
+// paste represents state of text pasted to input field.
+type paste struct {
+	active bool   // Pasted text is being typed, between pasteStartSuffix and pasteEndSuffix
+	inSeq  bool   // Alt+[ is pressed, what follows can be bracketed paste sequence
+	seq    []rune // Runes typed after Alt+[ so far
+}
+
+// editPaste handles <key> and <ch> with <mod> typed in input field <view> if they are a part of bracketed paste
+// sequence, returning true. Runes turned out to be not a part of sequence are typed as usual.
+func (c *Chat) editPaste(view *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) bool {
+	if ch == '[' && mod == gocui.ModAlt {
+		c.paste.inSeq, c.paste.seq = true, nil
+		return true
+	}
+	if !c.paste.inSeq {
+		return false
+	}
+	seq := string(append(c.paste.seq, ch))
+	if ch != 0 && mod == 0 && (strings.HasPrefix(pasteStartSuffix, seq) || strings.HasPrefix(pasteEndSuffix, seq)) {
+		c.paste.seq = []rune(seq)
+		switch seq {
+		case pasteStartSuffix:
+			c.paste.inSeq, c.paste.active = false, true
+		case pasteEndSuffix:
+			c.paste.inSeq, c.paste.active = false, false
+		}
+		return true
+	}
+	typed := c.paste.seq
+	c.paste.inSeq, c.paste.seq = false, nil
+	for _, r := range typed {
+		c.editInput(view, 0, r, 0)
+	}
+	return false
+}
+
+// isPasting returns true if input field is receiving pasted text: inside of bracketed paste or, if terminal doesn't
+// support it, if previous key was pressed less than pasteBurstInterval ago.
+func (c *Chat) isPasting() bool {
+	return c.paste.active || c.clock.Now().Sub(c.LastKeyAt()) < pasteBurstInterval
+}
+
+// setBracketedPaste turns bracketed paste mode of terminal on if <enable> is true and off otherwise.
+func setBracketedPaste(enable bool) {
+	if enable {
+		fmt.Print(enableBracketedPaste)
+	} else {
+		fmt.Print(disableBracketedPaste)
+	}
+}
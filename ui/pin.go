@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jroimartin/gocui"
+)
+
+// maxPins is the maximum amount of pinned messages. Pinning more messages unpins the oldest one.
+const maxPins = 3
+
+// pins represents messages pinned above the chat box, in order of pinning.
+type pins struct {
+	lines []string
+}
+
+// toggle pins <line> if it's not pinned and unpins it otherwise. It returns true if <line> was pinned.
+func (p *pins) toggle(line string) bool {
+	if idx := slices.Index(p.lines, line); idx != -1 {
+		p.lines = slices.Delete(p.lines, idx, idx+1)
+		return false
+	}
+	p.lines = append(p.lines, line)
+	if len(p.lines) > maxPins {
+		p.lines = p.lines[len(p.lines)-maxPins:]
+	}
+	return true
+}
+
+// height returns amount of rows pinned area takes, including frame, or 0 if nothing is pinned.
+func (p *pins) height() int {
+	if len(p.lines) == 0 {
+		return 0
+	}
+	return len(p.lines) + 2
+}
+
+// togglePin pins message on the selected line of the chat box <view> or unpins it if it's already pinned.
+func (c *Chat) togglePin(gui *gocui.Gui, view *gocui.View) error {
+	if line := selectedLine(view); line != "" {
+		c.pins.toggle(line)
+	}
+	return nil
+}
+
+// pinsLayout is a GUI manager function for pinned messages area. It's shown only if there are pinned messages.
+func (c *Chat) pinsLayout(gui *gocui.Gui) error {
+	if len(c.pins.lines) == 0 {
+		if err := gui.DeleteView(pinsName); err != nil && !errors.Is(err, gocui.ErrUnknownView) {
+			return errors.Wrap(err, "Delete view")
+		}
+		return nil
+	}
+
+	maxX, _ := gui.Size()
+
+	pinsView, err := gui.SetView(pinsName, 0, 0, maxX-1, c.pins.height()-1)
+	if err != nil && !errors.Is(err, gocui.ErrUnknownView) {
+		return errors.Wrap(err, fmt.Sprintf("Create view for %v", pinsName))
+	}
+	pinsView.Title = "Pinned"
+
+	pinsView.Clear()
+	_, err = fmt.Fprint(pinsView, strings.Join(c.pins.lines, "\n"))
+	return errors.Wrap(err, "Print pinned messages")
+}
@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jroimartin/gocui"
+)
+
+// quitConfirmText is the question shown in quit confirmation view.
+const quitConfirmText = "Discard message and quit? y/n"
+
+// quit exits the <gui> if input field is empty or quit confirmation is disabled. Otherwise it opens quit confirmation
+// view, and exits if called again while the confirmation is shown.
+func (c *Chat) quit(gui *gocui.Gui, view *gocui.View) error {
+	if c.confirmingQuit || c.opts.SkipQuitConfirm {
+		return quit(gui, view)
+	}
+	inputField, err := gui.View(inputFieldName)
+	if err != nil || strings.TrimSpace(inputField.Buffer()) == "" {
+		return quit(gui, view)
+	}
+	c.confirmingQuit = true
+	return nil
+}
+
+// Quit closes UI, making Draw return, e.g. once program exits for reason other than user quitting. It does nothing if
+// UI is closed already.
+func (c *Chat) Quit() {
+	c.Gui.Update(func(gui *gocui.Gui) error {
+		return quit(gui, nil)
+	})
+}
+
+// closeQuitConfirm closes quit confirmation view and focuses input field back.
+func (c *Chat) closeQuitConfirm(gui *gocui.Gui) {
+	c.confirmingQuit = false
+	if err := gui.DeleteView(quitConfirmName); err != nil && !errors.Is(err, gocui.ErrUnknownView) {
+		c.log.WithError(err).Error("Delete view")
+	}
+	if _, err := gui.SetCurrentView(inputFieldName); err != nil {
+		c.log.WithError(err).Error(fmt.Sprintf("Focus view %v", inputFieldName))
+	}
+}
+
+// editQuitConfirm handles <key> and <ch> typed while quit confirmation is shown: "y" exits, "n" and Esc cancel.
+func (c *Chat) editQuitConfirm(gui *gocui.Gui, key gocui.Key, ch rune) {
+	switch {
+	case ch == 'y' || ch == 'Y':
+		gui.Update(func(g *gocui.Gui) error {
+			return quit(g, nil)
+		})
+	case ch == 'n' || ch == 'N' || key == gocui.KeyEsc:
+		c.closeQuitConfirm(gui)
+	}
+}
+
+// quitConfirmLayout is a GUI manager function for quit confirmation view. It's shown only while quit is being
+// confirmed.
+func (c *Chat) quitConfirmLayout(gui *gocui.Gui) error {
+	if !c.confirmingQuit {
+		return nil
+	}
+
+	maxX, maxY := gui.Size()
+	width := len(quitConfirmText) + 1
+	x0, y0 := (maxX-width)/2, maxY/2-1
+
+	confirm, err := gui.SetView(quitConfirmName, x0, y0, x0+width, y0+2)
+	if err != nil && !errors.Is(err, gocui.ErrUnknownView) {
+		return errors.Wrap(err, fmt.Sprintf("Create view for %v", quitConfirmName))
+	}
+	if errors.Is(err, gocui.ErrUnknownView) {
+		confirm.Title = "Quit"
+		confirm.Editable = true
+		confirm.Editor = gocui.EditorFunc(func(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+			c.editQuitConfirm(gui, key, ch)
+		})
+		if _, err = fmt.Fprint(confirm, quitConfirmText); err != nil {
+			return errors.Wrap(err, "Print quit confirmation")
+		}
+		if _, err = gui.SetCurrentView(quitConfirmName); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("Focus view %v", quitConfirmName))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jroimartin/gocui"
+)
+
+// reactionEmojis is the list of emojis available in reaction picker, in order of appearance.
+var reactionEmojis = []string{"👍", "👎", "😄", "🎉", "😕", "❤️", "🚀", "👀"}
+
+// reactionColumns is the amount of emojis in one row of reaction picker.
+const reactionColumns = 4
+
+// reactionPicker represents state of emoji picker overlay used to react to a message.
+type reactionPicker struct {
+	active bool
+	idx    int
+	line   string
+}
+
+// move moves selection <dx> columns right and <dy> rows down, staying within the grid. <dx> and <dy> can be negative.
+func (p *reactionPicker) move(dx int, dy int) {
+	rows := (len(reactionEmojis) + reactionColumns - 1) / reactionColumns
+	row := min(max(p.idx/reactionColumns+dy, 0), rows-1)
+	col := min(max(p.idx%reactionColumns+dx, 0), reactionColumns-1)
+	p.idx = min(row*reactionColumns+col, len(reactionEmojis)-1)
+}
+
+// selected returns currently selected emoji.
+func (p *reactionPicker) selected() string {
+	return reactionEmojis[p.idx]
+}
+
+// String returns emoji grid with selected emoji enclosed in brackets. Used to implement fmt.Stringer interface.
+func (p *reactionPicker) String() string {
+	var b strings.Builder
+	for i, emoji := range reactionEmojis {
+		if i > 0 && i%reactionColumns == 0 {
+			b.WriteString("\n")
+		}
+		if i == p.idx {
+			fmt.Fprintf(&b, "[%v]", emoji)
+		} else {
+			fmt.Fprintf(&b, " %v ", emoji)
+		}
+	}
+	return b.String()
+}
+
+// AddOnReactListener registers function <l> to be run when user reacts to chat box <line> with <emoji>.
+func (c *Chat) AddOnReactListener(l func(line string, emoji string)) {
+	c.onReact = append(c.onReact, l)
+}
+
+// openReactionPicker opens reaction picker for message on the selected line of the chat box <view>.
+func (c *Chat) openReactionPicker(gui *gocui.Gui, view *gocui.View) error {
+	if line := selectedLine(view); line != "" {
+		c.reaction = reactionPicker{active: true, line: line}
+	}
+	return nil
+}
+
+// closeReactionPicker closes reaction picker and focuses chat box back.
+func (c *Chat) closeReactionPicker(gui *gocui.Gui) {
+	c.reaction.active = false
+	if err := gui.DeleteView(reactionPickerName); err != nil && !errors.Is(err, gocui.ErrUnknownView) {
+		c.log.WithError(err).Error("Delete view")
+	}
+	if _, err := gui.SetCurrentView(ChatBoxName); err != nil {
+		c.log.WithError(err).Error(fmt.Sprintf("Focus view %v", ChatBoxName))
+	}
+}
+
+// editReactionPicker handles <key> pressed while reaction picker is open: arrows move selection, Enter runs react
+// listeners with selected emoji and Esc closes the picker.
+func (c *Chat) editReactionPicker(gui *gocui.Gui, key gocui.Key) {
+	switch key {
+	case gocui.KeyArrowLeft:
+		c.reaction.move(-1, 0)
+	case gocui.KeyArrowRight:
+		c.reaction.move(1, 0)
+	case gocui.KeyArrowUp:
+		c.reaction.move(0, -1)
+	case gocui.KeyArrowDown:
+		c.reaction.move(0, 1)
+	case gocui.KeyEnter:
+		for _, listener := range c.onReact {
+			listener(c.reaction.line, c.reaction.selected())
+		}
+		c.closeReactionPicker(gui)
+	case gocui.KeyEsc:
+		c.closeReactionPicker(gui)
+	}
+}
+
+// reactionPickerLayout is a GUI manager function for reaction picker. It's shown only while the picker is open.
+func (c *Chat) reactionPickerLayout(gui *gocui.Gui) error {
+	if !c.reaction.active {
+		return nil
+	}
+
+	maxX, maxY := gui.Size()
+	rows := (len(reactionEmojis) + reactionColumns - 1) / reactionColumns
+	width := reactionColumns*4 + 2
+	x0, y0 := (maxX-width)/2, (maxY-rows-2)/2
+
+	picker, err := gui.SetView(reactionPickerName, x0, y0, x0+width, y0+rows+1)
+	if err != nil && !errors.Is(err, gocui.ErrUnknownView) {
+		return errors.Wrap(err, fmt.Sprintf("Create view for %v", reactionPickerName))
+	}
+	if errors.Is(err, gocui.ErrUnknownView) {
+		picker.Title = "React"
+		picker.Editable = true
+		picker.Editor = gocui.EditorFunc(func(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+			c.editReactionPicker(gui, key)
+		})
+		if _, err = gui.SetCurrentView(reactionPickerName); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("Focus view %v", reactionPickerName))
+		}
+	}
+
+	picker.Clear()
+	_, err = fmt.Fprint(picker, c.reaction.String())
+	return errors.Wrap(err, "Print reaction picker")
+}
@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReactionPickerMove(t *testing.T) {
+	tests := []struct {
+		name   string
+		idx    int
+		dx, dy int
+		want   int
+	}{
+		{name: "right", idx: 0, dx: 1, want: 1},
+		{name: "down", idx: 1, dy: 1, want: 5},
+		{name: "left from first column", idx: 4, dx: -1, want: 4},
+		{name: "right from last column", idx: 3, dx: 1, want: 3},
+		{name: "up from first row", idx: 2, dy: -1, want: 2},
+		{name: "down from last row", idx: 6, dy: 1, want: 6},
+		{name: "far outside the grid", idx: 5, dx: 10, dy: -10, want: 3},
+		{name: "diagonal", idx: 0, dx: 1, dy: 1, want: 5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := reactionPicker{idx: test.idx}
+			p.move(test.dx, test.dy)
+			if p.idx != test.want {
+				t.Errorf("Selected index is %v, want %v", p.idx, test.want)
+			}
+		})
+	}
+}
+
+func TestReactionPickerMoveIncompleteRow(t *testing.T) {
+	emojis := reactionEmojis
+	reactionEmojis = []string{"a", "b", "c", "d", "e", "f"}
+	t.Cleanup(func() { reactionEmojis = emojis })
+
+	p := reactionPicker{idx: 3}
+	p.move(0, 1)
+	if p.idx != 5 {
+		t.Errorf("Selected index is %v after moving below to missing cell, want 5", p.idx)
+	}
+	p.move(1, 0)
+	if p.idx != 5 {
+		t.Errorf("Selected index is %v after moving right from the last emoji, want 5", p.idx)
+	}
+	p.move(0, -1)
+	if p.idx != 1 {
+		t.Errorf("Selected index is %v after moving up, want 1", p.idx)
+	}
+}
+
+func TestReactionPickerString(t *testing.T) {
+	p := reactionPicker{}
+	p.move(1, 1)
+	want := strings.Join([]string{" 👍  👎  😄  🎉 ", " 😕 [❤️] 🚀  👀 "}, "\n")
+	if grid := p.String(); grid != want {
+		t.Errorf("Grid is\n%v\nwant\n%v", grid, want)
+	}
+	if emoji := p.selected(); emoji != "❤️" {
+		t.Errorf("Selected emoji is %v, want ❤️", emoji)
+	}
+}
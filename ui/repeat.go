@@ -0,0 +1,158 @@
+package ui
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jroimartin/gocui"
+	"github.com/samber/lo"
+)
+
+// logEntry represents message or raw text, e.g. log line, printed to chat box at once.
+type logEntry struct {
+	msg      *Message  // Nil if entry is raw text
+	text     string    // Raw text, ending with new line. Used if <msg> is nil
+	at       time.Time // Time of the last print, including collapsed repeats
+	repeats  int       // Amount of times message is printed in a row, collapsed into this entry
+	revealed bool      // Spoilers of message are revealed
+}
+
+// chatBoxLog represents entries printed to chat box, used to redraw it when repeated message is collapsed or entries
+// are changed.
+type chatBoxLog struct {
+	entries []logEntry
+}
+
+// addMessage appends <msg> printed at <now> to the log and returns false. If <msg> is a repeat of the last message, it
+// counts one more repeat of the last entry instead and returns true.
+func (l *chatBoxLog) addMessage(msg Message, now time.Time) bool {
+	if len(l.entries) > 0 {
+		last := &l.entries[len(l.entries)-1]
+		if last.msg != nil && msg.isRepeatOf(*last.msg) {
+			last.repeats++
+			last.at = now
+			return true
+		}
+	}
+	l.entries = append(l.entries, logEntry{msg: &msg, at: now, repeats: 1})
+	return false
+}
+
+// addText appends raw <text>, ending with new line, printed at <now> to the log.
+func (l *chatBoxLog) addText(text string, now time.Time) {
+	l.entries = append(l.entries, logEntry{text: text, at: now})
+}
+
+// last returns the last entry of the log. The log should not be empty.
+func (l *chatBoxLog) last() logEntry {
+	return l.entries[len(l.entries)-1]
+}
+
+// prune removes entries printed before <before> and returns true if any entry was removed.
+func (l *chatBoxLog) prune(before time.Time) bool {
+	count := len(l.entries)
+	l.entries = slices.DeleteFunc(l.entries, func(e logEntry) bool {
+		return e.at.Before(before)
+	})
+	return len(l.entries) != count
+}
+
+// trim removes the oldest entries, so that at most <limit> of them remain, and returns removed entries.
+func (l *chatBoxLog) trim(limit int) []logEntry {
+	if len(l.entries) <= limit {
+		return nil
+	}
+	removed := slices.Clone(l.entries[:len(l.entries)-limit])
+	l.entries = slices.Delete(l.entries, 0, len(removed))
+	return removed
+}
+
+// trimScrollback removes the oldest entries from chat box log once there are more than Options.Scrollback of them.
+// Tenth of the limit is removed at once, so chat box is not redrawn on every new message after that. If chat box is
+// scrolled up, it stays at the same lines. It should be called in GUI goroutine with printMu locked.
+func (c *Chat) trimScrollback(chatBox *gocui.View) error {
+	limit := lo.Ternary(c.opts.Scrollback > 0, c.opts.Scrollback, DefaultScrollback)
+	if len(c.chatBoxLog.entries) <= limit {
+		return nil
+	}
+	removed := c.chatBoxLog.trim(limit - limit/10)
+	if err := c.redrawChatBox(chatBox); err != nil {
+		return err
+	}
+	if chatBox.Autoscroll {
+		return nil
+	}
+
+	width, _ := chatBox.Size()
+	var rows int
+	var lastTimestamp string
+	for _, entry := range removed {
+		rows += wrappedRows(c.renderEntry(entry, &lastTimestamp), width)
+	}
+	originX, originY := chatBox.Origin()
+	return errors.Wrap(chatBox.SetOrigin(originX, max(originY-rows, 0)), "Keep chat box scroll position")
+}
+
+// wrappedRows returns amount of rows <text>, ending with new line, takes in view <width> columns wide with wrapping
+// enabled.
+func wrappedRows(text string, width int) int {
+	var rows int
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		length := utf8.RuneCountInString(ansiEscape.ReplaceAllString(line, ""))
+		rows += lineRows(length, width)
+	}
+	return rows
+}
+
+// Write prints <p> to chat box view. Used to implement io.Writer interface, e.g. to redirect log to chat box. Like
+// AppendMessage, it's safe to call from any goroutine.
+func (c *Chat) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	c.printMu.Lock()
+	c.chatBoxLog.addText(string(p), c.clock.Now())
+	c.printMu.Unlock()
+
+	c.Gui.Update(func(g *gocui.Gui) error {
+		chatBox, err := g.View(c.chatBoxView())
+		if err != nil {
+			return nil
+		}
+		c.printMu.Lock()
+		defer c.printMu.Unlock()
+		return c.drawChatBox(chatBox)
+	})
+	return len(p), nil
+}
+
+// ExpireMessages removes messages older than Options.MessageTTL from chat box, checking them every second. It does
+// nothing if Options.MessageTTL is 0. It blocks current goroutine until <ctx> is cancelled.
+func (c *Chat) ExpireMessages(ctx context.Context) {
+	if c.opts.MessageTTL <= 0 {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.clock.After(time.Second):
+		}
+		c.Gui.Update(func(g *gocui.Gui) error {
+			c.printMu.Lock()
+			defer c.printMu.Unlock()
+			if !c.chatBoxLog.prune(c.clock.Now().Add(-c.opts.MessageTTL)) {
+				return nil
+			}
+			chatBox, err := g.View(c.chatBoxView())
+			if err != nil {
+				return nil
+			}
+			return c.redrawChatBox(chatBox)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChatBoxLogCollapse(t *testing.T) {
+	hi := Message{Nickname: "alice", Text: "hi"}
+	spoiler := Message{Nickname: "alice", Text: "it's " + spoilerMarker + "Bruce" + spoilerMarker}
+	tests := []struct {
+		name    string
+		msgs    []Message
+		repeats []int
+	}{
+		{"repeats in a row", []Message{hi, hi, hi}, []int{3}},
+		{"other user between", []Message{hi, {Nickname: "bob", Text: "hi"}, hi}, []int{1, 1, 1}},
+		{"other text", []Message{hi, {Nickname: "alice", Text: "hi!"}}, []int{1, 1}},
+		{"own messages", []Message{{Nickname: "bob", Text: "hi", LocalID: 1}, {Nickname: "bob", Text: "hi", LocalID: 2}},
+			[]int{1, 1}},
+		{"spoilers", []Message{spoiler, spoiler}, []int{1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var l chatBoxLog
+			now := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+			for i, msg := range tt.msgs {
+				// Repeats are collapsed regardless of time
+				msg.Time = now.Add(time.Duration(i) * time.Second)
+				l.addMessage(msg, msg.Time)
+			}
+			var repeats []int
+			for _, entry := range l.entries {
+				repeats = append(repeats, entry.repeats)
+			}
+			if !slices.Equal(repeats, tt.repeats) {
+				t.Errorf("Repeats of entries are %v, want %v", repeats, tt.repeats)
+			}
+		})
+	}
+}
+
+func TestChatBoxLogTextBreaksRepeats(t *testing.T) {
+	var l chatBoxLog
+	now := time.Now()
+	msg := Message{Nickname: "alice", Text: "hi"}
+	if l.addMessage(msg, now) {
+		t.Error("The first message is counted as repeat")
+	}
+	l.addText("log line\n", now)
+	if l.addMessage(msg, now) {
+		t.Error("Message after raw text is counted as repeat")
+	}
+	if !l.addMessage(msg, now.Add(time.Second)) {
+		t.Error("Message is not counted as repeat of the previous one")
+	}
+	if last := l.last(); last.repeats != 2 || !last.at.Equal(now.Add(time.Second)) {
+		t.Errorf("The last entry is printed %v times, the last one at %v, want 2 times at %v", last.repeats, last.at,
+			now.Add(time.Second))
+	}
+}
+
+func TestRenderRepeatCount(t *testing.T) {
+	withoutColors(t)
+	c := &Chat{opts: Options{TimestampFormat: TimestampsOff}}
+	entry := logEntry{msg: &Message{Nickname: "alice", Text: "hi"}, repeats: 5}
+	if text := c.renderEntry(entry, new(string)); !strings.HasSuffix(text, "hi (x5)\n") {
+		t.Errorf("Entry repeated 5 times is rendered as %q", text)
+	}
+}
+
+func TestChatBoxLogPrune(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		ttl        time.Duration
+		wantPruned bool
+		want       []string
+	}{
+		{"nothing expired", time.Hour, false, []string{"log line\n", "old", "hi"}},
+		{"oldest expired", time.Minute * 30, true, []string{"old", "hi"}},
+		{"repeat keeps entry", time.Minute * 5, true, []string{"hi"}},
+		{"all expired", time.Second * 30, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var l chatBoxLog
+			l.addText("log line\n", now.Add(-time.Minute*40))
+			l.addMessage(Message{Nickname: "alice", Text: "old"}, now.Add(-time.Minute*20))
+			l.addMessage(Message{Nickname: "bob", Text: "hi"}, now.Add(-time.Minute*10))
+			l.addMessage(Message{Nickname: "bob", Text: "hi"}, now.Add(-time.Minute))
+
+			if pruned := l.prune(now.Add(-tt.ttl)); pruned != tt.wantPruned {
+				t.Errorf("prune returned %v, want %v", pruned, tt.wantPruned)
+			}
+			var texts []string
+			for _, entry := range l.entries {
+				if entry.msg == nil {
+					texts = append(texts, entry.text)
+					continue
+				}
+				texts = append(texts, entry.msg.Text)
+			}
+			if !slices.Equal(texts, tt.want) {
+				t.Errorf("Entries left are %q, want %q", texts, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// escapeSequence matches terminal escape sequences: CSI (e.g. colors, cursor movement, screen clearing), OSC (e.g.
+// window title) and two-character ones.
+var escapeSequence = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)?|\x1b[@-_]?`)
+
+// sanitize returns <text> received from other users with terminal escape sequences and control characters removed,
+// except new lines and tabs, so it can't alter terminal state.
+func sanitize(text string) string {
+	text = escapeSequence.ReplaceAllString(text, "")
+	return strings.Map(func(r rune) rune {
+		if r != '\n' && r != '\t' && unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
@@ -0,0 +1,34 @@
+package ui
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "plain text", text: "hello, world", want: "hello, world"},
+		{name: "unicode", text: "привет 👋", want: "привет 👋"},
+		{name: "new lines and tabs", text: "a\nb\tc", want: "a\nb\tc"},
+		{name: "color", text: "\x1b[31mred\x1b[0m", want: "red"},
+		{name: "color with parameters", text: "\x1b[1;38;5;196mbold\x1b[m", want: "bold"},
+		{name: "screen clearing", text: "\x1b[2J\x1b[Hhi", want: "hi"},
+		{name: "cursor movement", text: "a\x1b[10Ab", want: "ab"},
+		{name: "window title with bell", text: "\x1b]0;pwned\x07hi", want: "hi"},
+		{name: "window title with string terminator", text: "\x1b]2;pwned\x1b\\hi", want: "hi"},
+		{name: "unterminated window title", text: "hi\x1b]0;pwned", want: "hi"},
+		{name: "two-character sequence", text: "a\x1bMb", want: "ab"},
+		{name: "escape before lowercase letter", text: "\x1bcb", want: "cb"},
+		{name: "lone escape", text: "a\x1b", want: "a"},
+		{name: "control characters", text: "a\rb\x00c\x07d\x08e\x7f", want: "abcde"},
+		{name: "C1 control characters", text: "a\u009bb\u0085c", want: "abc"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if text := sanitize(test.text); text != test.want {
+				t.Errorf("sanitize(%q) is %q, want %q", test.text, text, test.want)
+			}
+		})
+	}
+}
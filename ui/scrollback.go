@@ -0,0 +1,164 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jroimartin/gocui"
+	"github.com/samber/lo"
+)
+
+// scrollbackSearchTitle is the title of scrollback search view.
+const scrollbackSearchTitle = "Search chat (Up - older, Down - newer, Esc - close)"
+
+// scrollbackSearch represents state of search in the chat box scrollback.
+type scrollbackSearch struct {
+	active bool
+	query  string
+	hit    int
+}
+
+// findMatches returns indexes of <lines> containing <query>, ignoring case.
+func findMatches(lines []string, query string) []int {
+	var hits []int
+	if query == "" {
+		return hits
+	}
+	query = strings.ToLower(query)
+	for i, line := range lines {
+		if strings.Contains(strings.ToLower(line), query) {
+			hits = append(hits, i)
+		}
+	}
+	return hits
+}
+
+// nextMatch returns the first of <hits> after <from> if <older> is false or the last one before <from> if <older> is
+// true, wrapping around at the end of <hits>. It returns false if <hits> are empty.
+func nextMatch(hits []int, from int, older bool) (int, bool) {
+	if len(hits) == 0 {
+		return 0, false
+	}
+	if older {
+		for i := len(hits) - 1; i >= 0; i-- {
+			if hits[i] < from {
+				return hits[i], true
+			}
+		}
+		return hits[len(hits)-1], true
+	}
+	for _, hit := range hits {
+		if hit > from {
+			return hit, true
+		}
+	}
+	return hits[0], true
+}
+
+// openScrollbackSearch opens search input for the chat box scrollback.
+func (c *Chat) openScrollbackSearch(gui *gocui.Gui, view *gocui.View) error {
+	c.chatSearch = scrollbackSearch{active: true}
+	return nil
+}
+
+// closeScrollbackSearch closes search input, removes highlighting of found line and focuses chat box back.
+func (c *Chat) closeScrollbackSearch(gui *gocui.Gui) {
+	c.chatSearch.active = false
+	if chatBox, err := gui.View(ChatBoxName); err == nil {
+		chatBox.Highlight = false
+	}
+	if err := gui.DeleteView(scrollbackSearchName); err != nil && !errors.Is(err, gocui.ErrUnknownView) {
+		c.log.WithError(err).Error("Delete view")
+	}
+	if _, err := gui.SetCurrentView(ChatBoxName); err != nil {
+		c.log.WithError(err).Error(fmt.Sprintf("Focus view %v", ChatBoxName))
+	}
+}
+
+// editScrollbackSearch handles <key> and <ch> typed in search input <view>: arrows jump to older or newer match, Esc
+// closes the search and other keys edit the query, jumping to the newest match of it.
+func (c *Chat) editScrollbackSearch(gui *gocui.Gui, view *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	switch key {
+	case gocui.KeyArrowUp, gocui.KeyEnter:
+		c.jumpToMatch(gui, view, c.chatSearch.hit, true)
+	case gocui.KeyArrowDown:
+		c.jumpToMatch(gui, view, c.chatSearch.hit, false)
+	case gocui.KeyEsc:
+		c.closeScrollbackSearch(gui)
+	default:
+		gocui.DefaultEditor.Edit(view, key, ch, mod)
+		c.chatSearch.query = strings.TrimSpace(view.Buffer())
+		// Start from the bottom to find the newest match first
+		c.jumpToMatch(gui, view, len(c.chatBoxLines(gui)), true)
+	}
+}
+
+// jumpToMatch scrolls chat box to the next match of the query after line <from> and highlights it. If <older> is
+// true, it searches upwards. Search status is shown in the title of search input <view>.
+func (c *Chat) jumpToMatch(gui *gocui.Gui, view *gocui.View, from int, older bool) {
+	chatBox, err := gui.View(ChatBoxName)
+	if err != nil {
+		return
+	}
+
+	lines := c.chatBoxLines(gui)
+	hits := findMatches(lines, c.chatSearch.query)
+	hit, ok := nextMatch(hits, from, older)
+	if !ok {
+		chatBox.Highlight = false
+		view.Title = scrollbackSearchTitle + lo.Ternary(c.chatSearch.query == "", "", ": not found")
+		return
+	}
+	c.chatSearch.hit = hit
+	view.Title = fmt.Sprintf("%v: %v/%v", scrollbackSearchTitle, lo.IndexOf(hits, hit)+1, len(hits))
+
+	_, sizeY := chatBox.Size()
+	originY := min(hit, max(len(lines)-sizeY, 0))
+	chatBox.Autoscroll = false
+	chatBox.Highlight = true
+	chatBox.SelBgColor = gocui.ColorYellow
+	chatBox.SelFgColor = gocui.ColorBlack
+	if err := chatBox.SetOrigin(0, originY); err != nil {
+		c.log.WithError(err).Error("Scroll chat box to match")
+	}
+	if err := chatBox.SetCursor(0, hit-originY); err != nil {
+		c.log.WithError(err).Error("Highlight match in chat box")
+	}
+}
+
+// chatBoxLines returns lines of the chat box as they are shown, with long lines wrapped.
+func (c *Chat) chatBoxLines(gui *gocui.Gui) []string {
+	chatBox, err := gui.View(ChatBoxName)
+	if err != nil {
+		return nil
+	}
+	return chatBox.ViewBufferLines()
+}
+
+// scrollbackSearchLayout is a GUI manager function for scrollback search input. It's shown at the bottom of chat box
+// while search is active.
+func (c *Chat) scrollbackSearchLayout(gui *gocui.Gui) error {
+	if !c.chatSearch.active {
+		return nil
+	}
+
+	maxX, maxY := gui.Size()
+
+	search, err := gui.SetView(scrollbackSearchName, 0, maxY-11, maxX-1, maxY-9)
+	if err != nil && !errors.Is(err, gocui.ErrUnknownView) {
+		return errors.Wrap(err, fmt.Sprintf("Create view for %v", scrollbackSearchName))
+	}
+	if errors.Is(err, gocui.ErrUnknownView) {
+		search.Title = scrollbackSearchTitle
+		search.Editable = true
+		search.Editor = gocui.EditorFunc(func(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+			c.editScrollbackSearch(gui, v, key, ch, mod)
+		})
+		if _, err = gui.SetCurrentView(scrollbackSearchName); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("Focus view %v", scrollbackSearchName))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jroimartin/gocui"
+)
+
+// selectedLine returns trimmed selected line of chat box <view>, or empty string if there is no text on it. Selected
+// line is the row of view cursor, moved with arrows or mouse click and set to the found line by chat search.
+func selectedLine(view *gocui.View) string {
+	_, cursorY := view.Cursor()
+	line, err := view.Line(cursorY)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(line)
+}
+
+// selectPrevious moves selection of chat box <view> one row up, scrolling it once the top row is passed.
+func selectPrevious(gui *gocui.Gui, view *gocui.View) error {
+	return moveSelection(-1, view)
+}
+
+// selectNext moves selection of chat box <view> one row down, scrolling it once the bottom row is passed.
+func selectNext(gui *gocui.Gui, view *gocui.View) error {
+	return moveSelection(1, view)
+}
+
+// moveSelection moves cursor of the <view> <step> rows lower, scrolling the view by the rows cursor would leave it.
+// <step> can be negative.
+func moveSelection(step int, view *gocui.View) error {
+	_, sizeY := view.Size()
+	_, originY := view.Origin()
+	cursorX, cursorY := view.Cursor()
+
+	cursorY, scrollStep := selectionRow(cursorY, step, viewRows(view)-originY, sizeY)
+	if scrollStep != 0 {
+		scroll(scrollStep, view)
+	}
+	return errors.Wrap(view.SetCursor(cursorX, cursorY), "Move selection")
+}
+
+// selectionRow returns row of the view <sizeY> rows high to move cursor from <cursorY> by <step> rows to, and amount of
+// rows to scroll the view by, since cursor stays within the view. <rows> is the amount of rows from the top visible row
+// to the end of the buffer, so cursor never goes below the last line.
+func selectionRow(cursorY int, step int, rows int, sizeY int) (int, int) {
+	last := max(min(rows, sizeY)-1, 0)
+	// Buffer could shrink below cursor, e.g. after chat box is cleared
+	cursorY = min(cursorY, last) + step
+	switch {
+	case cursorY < 0:
+		return 0, cursorY
+	case cursorY > last:
+		return last, cursorY - last
+	default:
+		return cursorY, 0
+	}
+}
+
+// highlightSelection highlights the selected line of <chatBox> while it's focused. Line found by chat search is
+// highlighted by search instead.
+func (c *Chat) highlightSelection(gui *gocui.Gui, chatBox *gocui.View) {
+	if c.chatSearch.active {
+		return
+	}
+	chatBox.Highlight = gui.CurrentView() == chatBox
+	chatBox.SelBgColor = gocui.ColorGreen
+	chatBox.SelFgColor = gocui.ColorBlack
+}
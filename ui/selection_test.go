@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSelectionRow(t *testing.T) {
+	tests := []struct {
+		name       string
+		cursorY    int
+		step       int
+		rows       int
+		wantY      int
+		wantScroll int
+	}{
+		{name: "down", cursorY: 0, step: 1, rows: 20, wantY: 1},
+		{name: "up", cursorY: 2, step: -1, rows: 20, wantY: 1},
+		{name: "up from top row", cursorY: 0, step: -1, rows: 20, wantY: 0, wantScroll: -1},
+		{name: "down from bottom row", cursorY: 4, step: 1, rows: 20, wantY: 4, wantScroll: 1},
+		{name: "down from the last line", cursorY: 2, step: 1, rows: 3, wantY: 2, wantScroll: 1},
+		{name: "cursor below the last line", cursorY: 4, step: -1, rows: 2, wantY: 0},
+		{name: "empty buffer", cursorY: 0, step: 1, rows: 0, wantY: 0, wantScroll: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cursorY, scrollStep := selectionRow(test.cursorY, test.step, test.rows, 5)
+			if cursorY != test.wantY || scrollStep != test.wantScroll {
+				t.Errorf("selectionRow is (%v, %v), want (%v, %v)", cursorY, scrollStep, test.wantY,
+					test.wantScroll)
+			}
+		})
+	}
+}
+
+func TestMoveSelection(t *testing.T) {
+	lines := make([]string, 10)
+	for i := range lines {
+		lines[i] = strings.Repeat(string(rune('a'+i)), 3)
+	}
+	view := newTestView(t, 10, 3, strings.Join(lines, "\n"))
+
+	var selected []string
+	step := func(step int, times int) {
+		for i := 0; i < times; i++ {
+			if err := moveSelection(step, view); err != nil {
+				t.Fatalf("Move selection: %v", err)
+			}
+			selected = append(selected, selectedLine(view))
+		}
+	}
+	step(1, 4)
+	step(-1, 4)
+	want := []string{"bbb", "ccc", "ddd", "eee", "ddd", "ccc", "bbb", "aaa"}
+	if !slices.Equal(selected, want) {
+		t.Errorf("Selected lines are %v, want %v", selected, want)
+	}
+	if view.Autoscroll {
+		t.Error("Autoscroll is on after selecting line above the last page")
+	}
+}
+
+func TestTogglePinSelectedLine(t *testing.T) {
+	c := &Chat{}
+	view := newTestView(t, 10, 3, "first\nsecond\n")
+	if err := view.SetCursor(0, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.togglePin(nil, view); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"second"}; !slices.Equal(c.pins.lines, want) {
+		t.Errorf("Pinned lines are %v, want %v", c.pins.lines, want)
+	}
+	if err := view.SetCursor(0, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.togglePin(nil, view); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"second"}; !slices.Equal(c.pins.lines, want) {
+		t.Errorf("Pinned lines are %v after pinning empty line, want %v", c.pins.lines, want)
+	}
+}
+
+func TestReactToSelectedLine(t *testing.T) {
+	c := &Chat{}
+	view := newTestView(t, 10, 3, "first\n  second  ")
+	if err := view.SetCursor(0, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.openReactionPicker(nil, view); err != nil {
+		t.Fatal(err)
+	}
+	if !c.reaction.active || c.reaction.line != "second" {
+		t.Errorf("Reaction picker is %+v, want active for line %q", c.reaction, "second")
+	}
+}
@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jroimartin/gocui"
+)
+
+// represents the minimum size of terminal fitting chat layout, with at least one row of chat box and minimum width of
+// online users box. Pinned messages take extra rows.
+const (
+	minTerminalWidth  = minOnlineBoxWidth
+	minTerminalHeight = 12
+)
+
+// minTerminalSize returns the minimum amount of columns and rows of terminal fitting chat layout.
+func (c *Chat) minTerminalSize() (int, int) {
+	return minTerminalWidth, minTerminalHeight + c.pins.height()
+}
+
+// isTooSmall returns true if terminal of <gui> is smaller than minTerminalSize, so views of chat layout would get
+// invalid dimensions.
+func (c *Chat) isTooSmall(gui *gocui.Gui) bool {
+	maxX, maxY := gui.Size()
+	minX, minY := c.minTerminalSize()
+	return maxX < minX || maxY < minY
+}
+
+// unlessTooSmall returns GUI manager running <layout> only while terminal fits chat layout. Otherwise views keep
+// their last positions, hidden by tooSmallLayout.
+func (c *Chat) unlessTooSmall(layout func(gui *gocui.Gui) error) gocui.ManagerFunc {
+	return func(gui *gocui.Gui) error {
+		if c.isTooSmall(gui) {
+			return nil
+		}
+		return layout(gui)
+	}
+}
+
+// tooSmallLayout is a GUI manager function for notice shown over the whole terminal while it's too small for chat
+// layout. Notice is removed once terminal is enlarged. Intended to be placed after managers of chat layout.
+func (c *Chat) tooSmallLayout(gui *gocui.Gui) error {
+	maxX, maxY := gui.Size()
+	if !c.isTooSmall(gui) || maxX == 0 || maxY == 0 {
+		if err := gui.DeleteView(tooSmallName); err != nil && !errors.Is(err, gocui.ErrUnknownView) {
+			return errors.Wrap(err, "Delete view")
+		}
+		return nil
+	}
+
+	view, err := gui.SetView(tooSmallName, -1, -1, maxX, maxY)
+	if err != nil && !errors.Is(err, gocui.ErrUnknownView) {
+		return errors.Wrap(err, fmt.Sprintf("Create view for %v", tooSmallName))
+	}
+	view.Frame = false
+	view.Wrap = true
+	if _, err = gui.SetViewOnTop(tooSmallName); err != nil {
+		return errors.Wrap(err, "Show terminal size notice on top")
+	}
+
+	minX, minY := c.minTerminalSize()
+	view.Clear()
+	_, err = fmt.Fprintf(view, "Terminal is too small, need at least %v×%v", minX, minY)
+	return errors.Wrap(err, "Print terminal size notice")
+}
@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go_chat_client/util/clock"
+)
+
+func TestLayoutTooSmall(t *testing.T) {
+	tests := []struct {
+		maxX     int
+		maxY     int
+		tooSmall bool
+	}{
+		{1, 1, true},
+		{2, 2, true},
+		{minTerminalWidth - 1, 40, true},
+		{80, minTerminalHeight - 1, true},
+		{minTerminalWidth, minTerminalHeight, false},
+		{80, 40, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%vx%v", tt.maxX, tt.maxY), func(t *testing.T) {
+			gui := newSizedGui(tt.maxX, tt.maxY)
+			c := &Chat{
+				clock:         clock.NewFake(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)),
+				history:       newHistory(historySize, ""),
+				opts:          Options{MaxMessageLength: 100},
+				onlineBoxOpen: true,
+			}
+			layout := func() {
+				t.Helper()
+				for _, manager := range c.managers() {
+					if err := manager.Layout(gui); err != nil {
+						t.Fatalf("Layout returned %v", err)
+					}
+				}
+			}
+
+			layout()
+			notice, err := gui.View(tooSmallName)
+			if tt.tooSmall != (err == nil) {
+				t.Fatalf("Terminal size notice is shown: %v, want %v", err == nil, tt.tooSmall)
+			}
+			if _, err = gui.View(ChatBoxName); tt.tooSmall == (err == nil) {
+				t.Errorf("Chat box is created: %v, want %v", err == nil, !tt.tooSmall)
+			}
+			if !tt.tooSmall {
+				return
+			}
+			want := fmt.Sprintf("need at least %v×%v", minTerminalWidth, minTerminalHeight)
+			if text := notice.Buffer(); !strings.Contains(text, want) {
+				t.Errorf("Notice is %q, want %q", text, want)
+			}
+
+			// Recovers once terminal is enlarged
+			resize(gui, 80, 40)
+			layout()
+			if _, err = gui.View(tooSmallName); err == nil {
+				t.Error("Terminal size notice is shown after terminal is enlarged")
+			}
+			if _, err = gui.View(ChatBoxName); err != nil {
+				t.Error("Chat box is not created after terminal is enlarged")
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/jroimartin/gocui"
+)
+
+// spoilerMarker is the delimiter of spoiler in message, e.g. "||hidden text||".
+const spoilerMarker = "||"
+
+// spoilerMask is the character hidden spoiler text is replaced with.
+const spoilerMask = "█"
+
+// spoilerColor is the color of hidden spoiler.
+var spoilerColor = color.New(color.FgHiBlack)
+
+// ansiEscape matches ANSI escape sequences used to color text.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// hasSpoilers returns true if <msg> contains at least one spoiler enclosed in spoilerMarker.
+func hasSpoilers(msg string) bool {
+	return len(strings.Split(msg, spoilerMarker)) >= 3
+}
+
+// renderSpoilers returns <msg> with spoilerMarker removed around every spoiler and spoiler text replaced with
+// spoilerMask, unless <reveal> is true. Unclosed marker is left as is.
+func renderSpoilers(msg string, reveal bool) string {
+	parts := strings.Split(msg, spoilerMarker)
+	if len(parts) < 3 {
+		return msg
+	}
+
+	var sb strings.Builder
+	for i, part := range parts {
+		isLast := i == len(parts)-1
+		switch {
+		case i%2 == 0:
+			sb.WriteString(part)
+		case isLast:
+			// Unclosed marker
+			sb.WriteString(spoilerMarker + part)
+		case reveal:
+			sb.WriteString(part)
+		default:
+			sb.WriteString(spoilerColor.Sprint(strings.Repeat(spoilerMask, utf8.RuneCountInString(part))))
+		}
+	}
+	return sb.String()
+}
+
+// toggleSpoilerOnLine reveals spoilers of the newest message containing spoilers and displayed <line>, or hides them if
+// they are already revealed. It returns false if there is no such message. It should be called with printMu locked.
+func (c *Chat) toggleSpoilerOnLine(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return false
+	}
+	texts := c.renderEntries()
+	for i := len(c.chatBoxLog.entries) - 1; i >= 0; i-- {
+		entry := &c.chatBoxLog.entries[i]
+		if entry.msg == nil || !hasSpoilers(entry.msg.Text) ||
+			!strings.Contains(ansiEscape.ReplaceAllString(texts[i], ""), line) {
+			continue
+		}
+		entry.revealed = !entry.revealed
+		return true
+	}
+	return false
+}
+
+// toggleSpoiler reveals or hides spoilers of the message on the selected line of chat box <view>.
+func (c *Chat) toggleSpoiler(gui *gocui.Gui, view *gocui.View) error {
+	line := selectedLine(view)
+
+	c.printMu.Lock()
+	defer c.printMu.Unlock()
+
+	if !c.toggleSpoilerOnLine(line) {
+		return nil
+	}
+	return c.redrawChatBox(view)
+}
@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRenderSpoilers(t *testing.T) {
+	withoutColors(t)
+	tests := []struct {
+		name   string
+		msg    string
+		reveal bool
+		want   string
+	}{
+		{"no spoilers", "plain text", false, "plain text"},
+		{"hidden", "it's ||Bruce|| Wayne", false, "it's █████ Wayne"},
+		{"revealed", "it's ||Bruce|| Wayne", true, "it's Bruce Wayne"},
+		{"multibyte", "||ёж||", false, "██"},
+		{"several", "||a|| and ||bc||", false, "█ and ██"},
+		{"unclosed", "||a|| and ||bc", false, "█ and ||bc"},
+		{"single marker", "a || b", false, "a || b"},
+		{"empty", "||||", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderSpoilers(tt.msg, tt.reveal); got != tt.want {
+				t.Errorf("renderSpoilers(%q, %v) = %q, want %q", tt.msg, tt.reveal, got, tt.want)
+			}
+			if got, want := hasSpoilers(tt.msg), tt.name != "no spoilers" && tt.name != "single marker"; got != want {
+				t.Errorf("hasSpoilers(%q) = %v, want %v", tt.msg, got, want)
+			}
+		})
+	}
+}
+
+func TestToggleSpoilerOnLine(t *testing.T) {
+	withoutColors(t)
+	c := &Chat{opts: Options{TimestampFormat: TimestampsOff}}
+	now := time.Now()
+	c.chatBoxLog.addMessage(Message{Nickname: "alice", Text: "it's ||Bruce||"}, now)
+	c.chatBoxLog.addMessage(Message{Nickname: "bob", Text: "no spoilers"}, now)
+	c.chatBoxLog.addMessage(Message{Nickname: "carol", Text: "it's ||Bruce||"}, now)
+	revealed := func() []bool {
+		var states []bool
+		for _, entry := range c.chatBoxLog.entries {
+			states = append(states, entry.revealed)
+		}
+		return states
+	}
+
+	steps := []struct {
+		line     string
+		wantOk   bool
+		revealed []bool
+	}{
+		{"alice it's █████", true, []bool{true, false, false}},
+		{"it's █████", true, []bool{true, false, true}},
+		{"carol it's Bruce", true, []bool{true, false, false}},
+		{"bob no spoilers", false, []bool{true, false, false}},
+		{"  ", false, []bool{true, false, false}},
+		{"alice it's Bruce", true, []bool{false, false, false}},
+	}
+	for i, step := range steps {
+		if ok := c.toggleSpoilerOnLine(step.line); ok != step.wantOk {
+			t.Errorf("Step %v: toggleSpoilerOnLine(%q) = %v, want %v", i, step.line, ok, step.wantOk)
+		}
+		if got := revealed(); !slices.Equal(got, step.revealed) {
+			t.Errorf("Step %v: revealed are %v, want %v", i, got, step.revealed)
+		}
+	}
+}
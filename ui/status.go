@@ -0,0 +1,187 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/fatih/color"
+	"github.com/jroimartin/gocui"
+	"github.com/samber/lo"
+)
+
+// represents connection states to show in status bar.
+const (
+	StatusConnecting   = "Connecting"
+	StatusLoggingIn    = "Logging in"
+	StatusOnline       = "Online"
+	StatusDisconnected = "Disconnected"
+	StatusReconnecting = "Reconnecting"
+	StatusKicked       = "Kicked"
+)
+
+// reconnectFlashDuration is the time status bar is highlighted for after reconnection.
+const reconnectFlashDuration = time.Second
+
+// typingTimeout is the time after which user is no longer shown as typing, unless new typing signal is received.
+const typingTimeout = time.Second * 3
+
+// status represents contents of status bar.
+type status struct {
+	state    string
+	nickname string
+	server   string
+	room     string
+	unread   int
+	pending  int
+	away     bool
+	reason   string
+	flash    bool
+	typing   map[string]time.Time
+}
+
+// render returns colored one-line representation of the status at <now>, showing users whose typing signals are not
+// expired by then.
+func (s status) render(now time.Time) string {
+	state := s.state
+	if state == StatusOnline {
+		state = color.GreenString("%v", state)
+	} else {
+		state = color.RedString("%v", state)
+	}
+	str := fmt.Sprintf(" %v | %v@%v", state, s.nickname, s.server)
+	if s.room != "" {
+		str += " | " + s.room
+		if s.unread > 0 {
+			str += color.YellowString(" (%v unread in other rooms)", s.unread)
+		}
+	}
+	str += fmt.Sprintf(" | %v pending", s.pending)
+	if s.away {
+		str += color.YellowString(" | away")
+		if s.reason != "" {
+			str += color.YellowString(": %v", s.reason)
+		}
+	}
+
+	var typing []string
+	for nickname, until := range s.typing {
+		if now.Before(until) {
+			typing = append(typing, nickname)
+		}
+	}
+	if len(typing) > 0 {
+		slices.Sort(typing)
+		str += fmt.Sprintf(" | %v typing…", strings.Join(typing, ", "))
+	}
+
+	return str
+}
+
+// SetStatus sets connection <state> to show in status bar and redraws it.
+func (c *Chat) SetStatus(state string) {
+	c.Gui.Update(func(g *gocui.Gui) error {
+		c.status.state = state
+		return c.drawStatusBar(g)
+	})
+}
+
+// SetIdentity sets <nickname> and <server> address to show in status bar and redraws it.
+func (c *Chat) SetIdentity(nickname string, server string) {
+	c.Gui.Update(func(g *gocui.Gui) error {
+		c.status.nickname = nickname
+		c.status.server = server
+		return c.drawStatusBar(g)
+	})
+}
+
+// SetRoom sets active <room> and amount of <unread> messages in other rooms to show in status bar and redraws it.
+func (c *Chat) SetRoom(room string, unread int) {
+	c.Gui.Update(func(g *gocui.Gui) error {
+		c.status.room = room
+		c.status.unread = unread
+		return c.drawStatusBar(g)
+	})
+}
+
+// SetPending sets <count> of messages waiting for confirmation from server to show in status bar and redraws it.
+func (c *Chat) SetPending(count int) {
+	c.Gui.Update(func(g *gocui.Gui) error {
+		c.status.pending = count
+		return c.drawStatusBar(g)
+	})
+}
+
+// SetAway sets whether user is <away> with optional <reason> to show in status bar and redraws it.
+func (c *Chat) SetAway(away bool, reason string) {
+	c.Gui.Update(func(g *gocui.Gui) error {
+		c.status.away = away
+		c.status.reason = reason
+		return c.drawStatusBar(g)
+	})
+}
+
+// IndicateReconnect draws user attention to restored connection, distinctly from message notifications, by ringing the
+// terminal bell twice and highlighting status bar for reconnectFlashDuration.
+func (c *Chat) IndicateReconnect() {
+	fmt.Print("\a\a")
+	c.Gui.Update(func(g *gocui.Gui) error {
+		c.status.flash = true
+		return c.drawStatusBar(g)
+	})
+	c.clock.AfterFunc(reconnectFlashDuration, func() {
+		c.Gui.Update(func(g *gocui.Gui) error {
+			c.status.flash = false
+			return c.drawStatusBar(g)
+		})
+	})
+}
+
+// SetTyping shows in status bar that user with <nickname> is typing. It's cleared after typingTimeout, unless called
+// again.
+func (c *Chat) SetTyping(nickname string) {
+	nickname = sanitize(nickname)
+	c.Gui.Update(func(g *gocui.Gui) error {
+		if c.status.typing == nil {
+			c.status.typing = map[string]time.Time{}
+		}
+		c.status.typing[nickname] = c.clock.Now().Add(typingTimeout)
+		return c.drawStatusBar(g)
+	})
+	c.clock.AfterFunc(typingTimeout, func() {
+		c.Gui.Update(func(g *gocui.Gui) error {
+			if until, ok := c.status.typing[nickname]; ok && !c.clock.Now().Before(until) {
+				delete(c.status.typing, nickname)
+			}
+			return c.drawStatusBar(g)
+		})
+	})
+}
+
+// statusBarLayout is a GUI manager function for status bar, showing status of target of input field.
+func (c *Chat) statusBarLayout(gui *gocui.Gui) error {
+	maxX, maxY := gui.Size()
+
+	statusBar, err := gui.SetView(statusBarName, -1, maxY-2, maxX, maxY)
+	if !errors.Is(err, gocui.ErrUnknownView) {
+		return errors.Wrap(err, fmt.Sprintf("Create view for %v", statusBarName))
+	}
+	statusBar.Frame = false
+
+	return c.target().drawStatusBar(gui)
+}
+
+// drawStatusBar prints current status to status bar view, if it exists and <c> is target of input field.
+func (c *Chat) drawStatusBar(gui *gocui.Gui) error {
+	statusBar, err := gui.View(statusBarName)
+	if err != nil || c.target() != c {
+		return nil
+	}
+
+	statusBar.BgColor = lo.Ternary(c.status.flash, gocui.ColorGreen, gocui.ColorDefault)
+	statusBar.Clear()
+	_, err = fmt.Fprint(statusBar, c.status.render(c.clock.Now()))
+	return errors.Wrap(err, "Print status")
+}
@@ -0,0 +1,42 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"go_chat_client/util/clock"
+
+	"github.com/jroimartin/gocui"
+)
+
+func TestStatusBarReconnectFlash(t *testing.T) {
+	tests := []struct {
+		name  string
+		flash bool
+		want  gocui.Attribute
+	}{
+		{"connection restored", true, gocui.ColorGreen},
+		{"flash is over", false, gocui.ColorDefault},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gui := &gocui.Gui{}
+			statusBar, err := gui.SetView(statusBarName, 0, 0, 60, 2)
+			if err != nil && err != gocui.ErrUnknownView {
+				t.Fatal(err)
+			}
+			statusBar.BgColor = gocui.ColorRed
+			c := &Chat{
+				clock:  clock.NewFake(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)),
+				status: status{nickname: "bob", server: "main:1", flash: tt.flash},
+			}
+
+			if err = c.drawStatusBar(gui); err != nil {
+				t.Fatal(err)
+			}
+			if statusBar.BgColor != tt.want {
+				t.Errorf("Status bar background is %v, want %v", statusBar.BgColor, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jroimartin/gocui"
+	"github.com/samber/lo"
+)
+
+// relativeTimestampsInterval is the interval between redraws of chat box while relative timestamps are shown, so they
+// don't go stale.
+const relativeTimestampsInterval = time.Second * 30
+
+// timestamp returns time of <msg> to print before it: relative to now if relative timestamps are shown, formatted
+// with Options.TimestampFormat otherwise. It should be called with printMu locked.
+func (c *Chat) timestamp(msg Message) string {
+	if c.relativeTimes {
+		return relativeTime(msg.Time, c.clock.Now())
+	}
+	return msg.Time.Local().Format(lo.Ternary(c.opts.TimestampFormat == "", DefaultTimestampFormat,
+		c.opts.TimestampFormat))
+}
+
+// relativeTime returns time <t> relative to <now> in the largest whole units, e.g. "just now", "2m ago", "1h ago" or
+// "3d ago". Time in the future, e.g. because of clock difference with server, is "just now".
+func relativeTime(t time.Time, now time.Time) string {
+	ago := now.Sub(t)
+	switch {
+	case ago < time.Minute:
+		return "just now"
+	case ago < time.Hour:
+		return fmt.Sprintf("%vm ago", int(ago/time.Minute))
+	case ago < 24*time.Hour:
+		return fmt.Sprintf("%vh ago", int(ago/time.Hour))
+	default:
+		return fmt.Sprintf("%vd ago", int(ago/(24*time.Hour)))
+	}
+}
+
+// toggleRelativeTimestamps switches message time in chat box and chat boxes of panes between relative and absolute
+// one.
+func (c *Chat) toggleRelativeTimestamps(gui *gocui.Gui, view *gocui.View) error {
+	for _, chat := range c.chats() {
+		chatBox, err := gui.View(chat.chatBoxView())
+		if err != nil {
+			continue
+		}
+		chat.printMu.Lock()
+		chat.relativeTimes = !chat.relativeTimes
+		err = chat.redrawChatBox(chatBox)
+		chat.printMu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RefreshTimestamps redraws chat box every relativeTimestampsInterval while relative timestamps are shown, so they
+// stay up to date. It does nothing if timestamps are off. It blocks current goroutine until <ctx> is cancelled.
+func (c *Chat) RefreshTimestamps(ctx context.Context) {
+	if c.opts.TimestampFormat == TimestampsOff {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.clock.After(relativeTimestampsInterval):
+		}
+		c.Gui.Update(func(g *gocui.Gui) error {
+			c.printMu.Lock()
+			defer c.printMu.Unlock()
+			if !c.relativeTimes {
+				return nil
+			}
+			chatBox, err := g.View(c.chatBoxView())
+			if err != nil {
+				return nil
+			}
+			return c.redrawChatBox(chatBox)
+		})
+	}
+}
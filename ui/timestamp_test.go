@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{ago: -time.Hour, want: "just now"},
+		{ago: 0, want: "just now"},
+		{ago: time.Second * 59, want: "just now"},
+		{ago: time.Minute - time.Nanosecond, want: "just now"},
+		{ago: time.Minute, want: "1m ago"},
+		{ago: time.Minute*2 - time.Nanosecond, want: "1m ago"},
+		{ago: time.Minute * 2, want: "2m ago"},
+		{ago: time.Minute*2 + time.Second*59, want: "2m ago"},
+		{ago: time.Hour - time.Nanosecond, want: "59m ago"},
+		{ago: time.Hour, want: "1h ago"},
+		{ago: time.Hour*2 - time.Nanosecond, want: "1h ago"},
+		{ago: time.Hour*24 - time.Nanosecond, want: "23h ago"},
+		{ago: time.Hour * 24, want: "1d ago"},
+		{ago: time.Hour*24*3 + time.Hour*23, want: "3d ago"},
+		{ago: time.Hour * 24 * 400, want: "400d ago"},
+	}
+	for _, test := range tests {
+		if text := relativeTime(now.Add(-test.ago), now); text != test.want {
+			t.Errorf("relativeTime of %v ago is %q, want %q", test.ago, text, test.want)
+		}
+	}
+}
+
+func TestCompactTimestamps(t *testing.T) {
+	withoutColors(t)
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.Local)
+	times := []time.Time{start, start.Add(time.Millisecond * 500), start.Add(time.Second), start.Add(time.Second)}
+	tests := []struct {
+		compact bool
+		want    []string
+	}{
+		{false, []string{"12:00:00 alice a\n", "12:00:00 bob b\n", "12:00:01 alice c\n", "12:00:01 bob d\n"}},
+		{true, []string{"12:00:00 alice a\n", "         bob b\n", "12:00:01 alice c\n", "         bob d\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("compact %v", tt.compact), func(t *testing.T) {
+			c := &Chat{opts: Options{CompactTimestamps: tt.compact}}
+			for i, text := range []string{"a", "b", "c", "d"} {
+				msg := Message{Nickname: lo.Ternary(i%2 == 0, "alice", "bob"), Text: text, Time: times[i]}
+				c.chatBoxLog.entries = append(c.chatBoxLog.entries, logEntry{msg: &msg, repeats: 1})
+			}
+			if got := c.renderEntries(); !slices.Equal(got, tt.want) {
+				t.Errorf("Rendered entries are %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"go_chat_client/util/browser"
+
+	"github.com/fatih/color"
+	"github.com/jroimartin/gocui"
+)
+
+// urlsLimit is the maximum amount of recent URLs from chat box to cycle through.
+const urlsLimit = 100
+
+// urlAttrs are the attributes URLs in messages are styled with.
+var urlAttrs = []color.Attribute{color.FgBlue, color.Underline}
+
+// findURLs returns http and https URLs found in <msg>, in order of appearance.
+func findURLs(msg string) []string {
+	return urlPattern.FindAllString(msg, -1)
+}
+
+// recentURLs represents URLs from chat box messages, cycled through from the newest to the oldest.
+type recentURLs struct {
+	urls []string
+	next int // Position of URL to open next, counted from the newest
+}
+
+// add adds <urls> as the newest ones and restarts cycling from the newest URL.
+func (r *recentURLs) add(urls []string) {
+	if len(urls) == 0 {
+		return
+	}
+	r.urls = append(r.urls, urls...)
+	if len(r.urls) > urlsLimit {
+		r.urls = r.urls[len(r.urls)-urlsLimit:]
+	}
+	r.next = 0
+}
+
+// cycle returns URL to open, it's position counted from the newest starting from 1 and false if there are no URLs.
+// Every call returns older URL, starting over from the newest after the oldest.
+func (r *recentURLs) cycle() (string, int, bool) {
+	if len(r.urls) == 0 {
+		return "", 0, false
+	}
+	pos := r.next
+	r.next = (r.next + 1) % len(r.urls)
+	return r.urls[len(r.urls)-1-pos], pos + 1, true
+}
+
+// openURL opens the newest URL in chat box in default browser. Every next call opens older URL, until new URL is
+// printed.
+func (c *Chat) openURL(gui *gocui.Gui, view *gocui.View) error {
+	if c.opts.DisableOpenURL {
+		c.log.Warn("Opening URLs is disabled in config")
+		return nil
+	}
+	c.printMu.Lock()
+	url, pos, ok := c.urls.cycle()
+	count := len(c.urls.urls)
+	c.printMu.Unlock()
+	if !ok {
+		c.log.Info("No URLs in chat window")
+		return nil
+	}
+	c.log.Infof("Opening URL %v/%v: %v", pos, count, url)
+	if err := browser.Open(url); err != nil {
+		c.log.WithError(err).Error()
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jroimartin/gocui"
+	"github.com/samber/lo"
+)
+
+// viewWaiter represents a caller waiting for view to be created.
+type viewWaiter struct {
+	name   string
+	viewCh chan *gocui.View
+}
+
+// viewWaiters represents callers waiting for views to be created.
+type viewWaiters struct {
+	mu      sync.Mutex
+	waiters []*viewWaiter
+}
+
+// WaitForView returns view with the specified <name> as soon as it becomes available.
+func (c *Chat) WaitForView(name string) *gocui.View {
+	view, _ := c.waitForView(name, nil)
+	return view
+}
+
+// WaitForViewTimeout returns view with the specified <name> as soon as it becomes available, or error if it's not
+// available within <timeout>.
+func (c *Chat) WaitForViewTimeout(name string, timeout time.Duration) (*gocui.View, error) {
+	timer := c.clock.NewTimer(timeout)
+	defer timer.Stop()
+	return c.waitForView(name, timer.C())
+}
+
+// waitForView returns view with the specified <name> once layout creates it, or error if <timeoutCh> fires first.
+// <timeoutCh> can be nil to wait forever.
+func (c *Chat) waitForView(name string, timeoutCh <-chan time.Time) (*gocui.View, error) {
+	w := &viewWaiter{name: name, viewCh: make(chan *gocui.View, 1)}
+	c.viewWaiters.mu.Lock()
+	c.viewWaiters.waiters = append(c.viewWaiters.waiters, w)
+	c.viewWaiters.mu.Unlock()
+
+	// Trigger layout in case view already exists and no redraw is pending
+	c.Gui.Update(func(*gocui.Gui) error { return nil })
+
+	select {
+	case view := <-w.viewCh:
+		return view, nil
+	case <-timeoutCh:
+		c.viewWaiters.mu.Lock()
+		c.viewWaiters.waiters = lo.Without(c.viewWaiters.waiters, w)
+		c.viewWaiters.mu.Unlock()
+		return nil, errors.Newf("View %v is not created within timeout", name)
+	}
+}
+
+// viewWaitersLayout passes views created by the previous layout managers to callers waiting for them. Intended to be
+// the last layout manager.
+func (c *Chat) viewWaitersLayout(gui *gocui.Gui) error {
+	c.viewWaiters.mu.Lock()
+	defer c.viewWaiters.mu.Unlock()
+	c.viewWaiters.waiters = lo.Filter(c.viewWaiters.waiters, func(w *viewWaiter, _ int) bool {
+		view, err := gui.View(w.name)
+		if err != nil {
+			return true
+		}
+		w.viewCh <- view
+		return false
+	})
+	return nil
+}
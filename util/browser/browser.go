@@ -0,0 +1,26 @@
+package browser
+
+import (
+	"os/exec"
+	"runtime"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Open opens <url> in default browser without waiting for it to exit.
+func Open(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return errors.Wrap(err, "Open URL in browser")
+	}
+	go cmd.Wait() //nolint:errcheck // Release process resources, browser exit status doesn't matter
+	return nil
+}
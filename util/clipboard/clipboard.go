@@ -0,0 +1,13 @@
+//go:build clipboard
+
+package clipboard
+
+import (
+	"github.com/atotto/clipboard"
+	"github.com/cockroachdb/errors"
+)
+
+// Write copies <text> to system clipboard.
+func Write(text string) error {
+	return errors.Wrap(clipboard.WriteAll(text), "Write to clipboard")
+}
@@ -0,0 +1,12 @@
+//go:build !clipboard
+
+package clipboard
+
+import (
+	"github.com/cockroachdb/errors"
+)
+
+// Write returns error, because program is built without clipboard support. Build with "clipboard" tag to enable it.
+func Write(text string) error {
+	return errors.New("Clipboard is not supported by this build. Build with '-tags clipboard' to enable it")
+}
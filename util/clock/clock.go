@@ -0,0 +1,200 @@
+package clock
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Clock represents source of current time and of waiting, which can be replaced with Fake to check time-based
+// behavior without real delays.
+type Clock interface {
+	// Now returns current time.
+	Now() time.Time
+	// Sleep blocks current goroutine for duration <d>.
+	Sleep(d time.Duration)
+	// After returns channel receiving current time once duration <d> passes.
+	After(d time.Duration) <-chan time.Time
+	// NewTimer returns timer sending current time to it's channel once duration <d> passes.
+	NewTimer(d time.Duration) Timer
+	// AfterFunc returns timer running <f> once duration <d> passes.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer represents single event scheduled with Clock.NewTimer or Clock.AfterFunc.
+type Timer interface {
+	// C returns channel receiving current time once timer fires. It's nil for timers created with Clock.AfterFunc.
+	C() <-chan time.Time
+	// Stop prevents timer from firing. It returns false if timer has already fired or been stopped.
+	Stop() bool
+}
+
+// Real represents Clock using system time. Used by default.
+type Real struct{}
+
+// Now returns current system time. Used to implement Clock interface.
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Sleep blocks current goroutine for duration <d>. Used to implement Clock interface.
+func (Real) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// After returns channel receiving current time once duration <d> passes. Used to implement Clock interface.
+func (Real) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// NewTimer returns timer sending current time to it's channel once duration <d> passes. Used to implement Clock
+// interface.
+func (Real) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+// AfterFunc returns timer running <f> in it's own goroutine once duration <d> passes. Used to implement Clock
+// interface.
+func (Real) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+// realTimer represents Timer using system time.
+type realTimer struct {
+	*time.Timer
+}
+
+// C returns channel receiving current time once timer fires. Used to implement Timer interface.
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// waiter represents channel waiting for fake time to reach <at>, or function to run then if <f> is not nil.
+type waiter struct {
+	at time.Time
+	ch chan time.Time
+	f  func()
+}
+
+// Fake represents Clock which time moves only when Advance is called. It's safe for concurrent use.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+}
+
+// NewFake returns new fake clock showing time <now>.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns current fake time. Used to implement Clock interface.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Sleep blocks current goroutine until fake time is advanced by duration <d>. Used to implement Clock interface.
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+// After returns channel receiving fake time once it's advanced by duration <d>. Used to implement Clock interface.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// NewTimer returns timer sending fake time to it's channel once it's advanced by duration <d>. Used to implement Clock
+// interface.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	return f.schedule(&waiter{ch: make(chan time.Time, 1)}, d)
+}
+
+// AfterFunc returns timer running <fn> once fake time is advanced by duration <d>. Unlike with Real clock, <fn> is run
+// by Advance itself, so it's finished once Advance returns. Used to implement Clock interface.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	return f.schedule(&waiter{f: fn}, d)
+}
+
+// Advance moves fake time forward by duration <d>, waking up callers of Sleep and After waiting for it and running
+// functions of AfterFunc, in order of time they wait for.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	var due []*waiter
+	pending := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.at.After(f.now) {
+			pending = append(pending, w)
+		} else {
+			due = append(due, w)
+		}
+	}
+	f.waiters = pending
+	now := f.now
+	f.mu.Unlock()
+
+	slices.SortStableFunc(due, func(a, b *waiter) int {
+		return a.at.Compare(b.at)
+	})
+	for _, w := range due {
+		w.fire(now)
+	}
+}
+
+// Waiters returns amount of timers waiting for fake time to be advanced, e.g. to make sure another goroutine waits for
+// it before calling Advance.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+// schedule returns timer firing <w> once fake time is advanced by duration <d>. If <d> is not positive, it fires
+// right away.
+func (f *Fake) schedule(w *waiter, d time.Duration) Timer {
+	f.mu.Lock()
+	w.at = f.now.Add(d)
+	if d > 0 {
+		f.waiters = append(f.waiters, w)
+	}
+	f.mu.Unlock()
+	if d <= 0 {
+		w.fire(w.at)
+	}
+	return fakeTimer{f: f, w: w}
+}
+
+// fire sends <now> to waiting channel or runs waiting function.
+func (w *waiter) fire(now time.Time) {
+	if w.f != nil {
+		w.f()
+		return
+	}
+	w.ch <- now
+}
+
+// fakeTimer represents Timer of Fake clock.
+type fakeTimer struct {
+	f *Fake
+	w *waiter
+}
+
+// C returns channel receiving fake time once timer fires. Used to implement Timer interface.
+func (t fakeTimer) C() <-chan time.Time {
+	return t.w.ch
+}
+
+// Stop prevents timer from firing. It returns false if timer has already fired or been stopped. Used to implement
+// Timer interface.
+func (t fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	i := slices.Index(t.f.waiters, t.w)
+	if i < 0 {
+		return false
+	}
+	t.f.waiters = slices.Delete(t.f.waiters, i, i+1)
+	return true
+}
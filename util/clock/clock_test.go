@@ -0,0 +1,114 @@
+package clock
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// start is the time fake clocks in tests start at.
+var start = time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// fired returns true if <ch> has received time.
+func fired(ch <-chan time.Time) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestFakeAfter(t *testing.T) {
+	f := NewFake(start)
+	ch := f.After(time.Second)
+
+	f.Advance(time.Millisecond * 999)
+	if fired(ch) {
+		t.Fatal("After fired before duration passed")
+	}
+	f.Advance(time.Millisecond)
+	if !fired(ch) {
+		t.Fatal("After didn't fire once duration passed")
+	}
+	if now := f.Now(); !now.Equal(start.Add(time.Second)) {
+		t.Errorf("Now is %v, want %v", now, start.Add(time.Second))
+	}
+	if !fired(f.After(0)) {
+		t.Error("After with zero duration didn't fire right away")
+	}
+}
+
+func TestFakeTimerStop(t *testing.T) {
+	f := NewFake(start)
+	timer := f.NewTimer(time.Second)
+	if waiters := f.Waiters(); waiters != 1 {
+		t.Fatalf("Waiters is %v, want 1", waiters)
+	}
+
+	if !timer.Stop() {
+		t.Error("Stop of pending timer returned false")
+	}
+	if timer.Stop() {
+		t.Error("Stop of stopped timer returned true")
+	}
+	f.Advance(time.Second)
+	if fired(timer.C()) {
+		t.Error("Stopped timer fired")
+	}
+	if waiters := f.Waiters(); waiters != 0 {
+		t.Errorf("Waiters is %v after Stop, want 0", waiters)
+	}
+}
+
+func TestFakeAfterFunc(t *testing.T) {
+	f := NewFake(start)
+	var order []string
+	f.AfterFunc(time.Second*2, func() { order = append(order, "second") })
+	f.AfterFunc(time.Second, func() { order = append(order, "first") })
+	stopped := f.AfterFunc(time.Second, func() { order = append(order, "stopped") })
+	f.AfterFunc(time.Second*3, func() { order = append(order, "later") })
+	stopped.Stop()
+
+	f.Advance(time.Second * 2)
+	if want := []string{"first", "second"}; !slices.Equal(order, want) {
+		t.Errorf("Functions run are %v, want %v", order, want)
+	}
+	if waiters := f.Waiters(); waiters != 1 {
+		t.Errorf("Waiters is %v, want 1", waiters)
+	}
+}
+
+func TestFakeAfterFuncReschedules(t *testing.T) {
+	f := NewFake(start)
+	var runs int
+	var schedule func()
+	schedule = func() {
+		runs++
+		f.AfterFunc(time.Second, schedule)
+	}
+	f.AfterFunc(time.Second, schedule)
+
+	f.Advance(time.Second)
+	f.Advance(time.Second)
+	if runs != 2 {
+		t.Errorf("Function is run %v times, want 2", runs)
+	}
+}
+
+func TestRealTimer(t *testing.T) {
+	timer := Real{}.NewTimer(time.Millisecond)
+	select {
+	case <-timer.C():
+	case <-time.After(time.Second):
+		t.Fatal("Real timer didn't fire")
+	}
+
+	done := make(chan struct{})
+	Real{}.AfterFunc(time.Millisecond, func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Real AfterFunc didn't run function")
+	}
+}
@@ -3,73 +3,130 @@ package stdin
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/cockroachdb/errors"
 	"github.com/samber/lo"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
 )
 
-// AskServerAddress returns address of server to connect to, taking it from standard input.
-func AskServerAddress(log *logrus.Logger) string {
-	return ask(log, true, "Enter server address in format of 'host:port': ", func(input string) bool {
-		return input == ""
+// ErrCancelled is returned when user cancels the prompt by closing standard input, e.g. with Ctrl+D.
+var ErrCancelled = errors.New("Prompt cancelled")
+
+// Prompter asks user for input, reading answers line by line from a single reader, so lines piped to input are not
+// lost in buffer of the previous prompt.
+type Prompter struct {
+	log         *logrus.Logger
+	input       *bufio.Reader
+	fd          int
+	interactive bool
+}
+
+// NewPrompter returns new Prompter reading from <input>. If <input> is a terminal, invalid answers are asked again
+// and password is read without echoing it. Otherwise, e.g. if input is piped, each prompt reads a single line and
+// fails if it's invalid.
+func NewPrompter(log *logrus.Logger, input io.Reader) *Prompter {
+	p := &Prompter{log: log, input: bufio.NewReader(input), fd: -1}
+	if file, ok := input.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		p.fd = int(file.Fd())
+		p.interactive = true
+	}
+	return p
+}
+
+// AskServerAddress returns address of server to connect to, taking it from input.
+func (p *Prompter) AskServerAddress() (string, error) {
+	return p.ask(true, "Enter server address in format of 'host:port': ", func(input string) error {
+		return lo.Ternary(input == "", errors.New("Server address is empty"), nil)
 	})
 }
 
-// AskServerAddress returns true if need to establish secure connection to server, taking y/n value from standard input.
-func AskTLSMode(log *logrus.Logger) *bool {
-	tls := askYesNo(log, "Connect to server using TLS protocol? (y/n): ")
-	return &tls
+// AskTLSMode returns true if need to establish secure connection to server, taking y/n value from input.
+func (p *Prompter) AskTLSMode() (*bool, error) {
+	tls, err := p.askYesNo("Connect to server using TLS protocol? (y/n): ")
+	return &tls, err
+}
+
+// AskNickname returns nickname to use to log in, taking it from input. It asks again while <validate> returns error.
+func (p *Prompter) AskNickname(validate func(string) error) (string, error) {
+	return p.ask(true, "Enter your nickname: ", validate)
 }
 
-// AskNickname returns nickname to use to log in, taking it from standard input.
-func AskNickname(log *logrus.Logger) string {
-	return ask(log, true, "Enter your nickname: ", func(input string) bool {
-		if input == "" {
-			return true
+// AskPassword returns password to log in with, taking it from input without echoing it. If input is not a terminal,
+// password is read as a regular line.
+func (p *Prompter) AskPassword() (string, error) {
+	if !p.interactive {
+		return p.ask(false, "Enter your password: ", func(input string) error {
+			return lo.Ternary(input == "", errors.New("Password is empty"), nil)
+		})
+	}
+	for {
+		fmt.Print("Enter your password: ")
+		password, err := term.ReadPassword(p.fd)
+		fmt.Println()
+		if errors.Is(err, io.EOF) {
+			return "", ErrCancelled
 		}
-		maxSymbols := 20
-		if len(input) > maxSymbols {
-			log.Warnf("Nicknames with length > %v symbols are not allowed", maxSymbols)
-			return true
+		if err != nil {
+			return "", errors.Wrap(err, "Read password from standard input")
 		}
-		return false
-	})
+		if len(password) > 0 {
+			return string(password), nil
+		}
+	}
 }
 
 // askYesNo returns true if user input is 'y' or 'Y'. If user types neither 'y', 'Y', 'n' or 'N', it asks again.
-func askYesNo(log *logrus.Logger, prompt string) bool {
-	answer := ask(log, true, prompt, func(input string) bool {
-		if input == "" {
-			return true
+func (p *Prompter) askYesNo(prompt string) (bool, error) {
+	answer, err := p.ask(true, prompt, func(input string) error {
+		if input = strings.ToLower(input); input != "y" && input != "n" {
+			return errors.Newf("Answer '%v' is neither 'y' nor 'n'", input)
 		}
-		input = strings.ToLower(input)
-		if input != "y" && input != "n" {
-			return true
-		}
-		return false
+		return nil
 	})
-	return lo.Ternary(answer == "y", true, false)
+	return strings.ToLower(answer) == "y", err
 }
 
-// ask returns user input, preliminarily printing <prompt>. It runs forever until read is successfull and <callback>
-// returns false. If <trim> is true, trim space from user input before passing it to <callback>.
-func ask(log *logrus.Logger, trim bool, prompt string, callback func(string) bool) string {
+// ask returns user input, preliminarily printing <prompt>. If input is a terminal, it runs forever until read is
+// successfull and <validate> returns nil, warning about invalid non-empty input. Otherwise it reads a single line and
+// returns error if it's invalid or there is no line to read. If <trim> is true, trim space from user input before
+// passing it to <validate>. It returns ErrCancelled if terminal input is closed.
+func (p *Prompter) ask(trim bool, prompt string, validate func(string) error) (string, error) {
 	for {
 		fmt.Print(prompt)
-		reader := bufio.NewReader(os.Stdin)
-		input, err := reader.ReadString('\n')
-		if trim {
-			input = strings.TrimSpace(input)
+		line, err := p.input.ReadString('\n')
+		if errors.Is(err, io.EOF) && (p.interactive || line == "") {
+			fmt.Println()
+			return "", lo.Ternary(p.interactive, ErrCancelled,
+				errors.New("Standard input is not a terminal and has no more lines to read"))
 		}
-		if callback(input) {
+		if err != nil && !errors.Is(err, io.EOF) {
+			if !p.interactive {
+				return "", errors.Wrap(err, "Read from standard input")
+			}
+			p.log.WithError(err).Error("Read from standard input")
 			continue
 		}
+		if !p.interactive {
+			fmt.Println()
+		}
+		if trim {
+			line = strings.TrimSpace(line)
+		} else {
+			line = strings.TrimRight(line, "\r\n")
+		}
+		err = validate(line)
 		if err == nil {
-			return input
+			return line, nil
+		}
+		if !p.interactive {
+			return "", errors.Wrap(err, "Invalid value in standard input")
+		}
+		if line != "" {
+			p.log.WithError(err).Warn()
 		}
-		log.Error(errors.Wrap(err, "Read from standard input"))
 	}
 }
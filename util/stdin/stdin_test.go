@@ -0,0 +1,114 @@
+package stdin
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newTestPrompter returns Prompter reading <input>, which acts as a terminal if <interactive> is true.
+func newTestPrompter(input string, interactive bool) *Prompter {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return &Prompter{log: log, input: bufio.NewReader(strings.NewReader(input)), fd: -1, interactive: interactive}
+}
+
+func TestAskYesNo(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"y\n", true},
+		{"Y\n", true},
+		{" y \n", true},
+		{"n\n", false},
+		{"N\n", false},
+		{"yes\nn\n", false},
+		{"\nmaybe\ny\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := newTestPrompter(tt.input, true).askYesNo("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("askYesNo returned %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAskNicknameAgain(t *testing.T) {
+	var asked []string
+	validate := func(nickname string) error {
+		asked = append(asked, nickname)
+		if nickname == "" || len(nickname) > 5 {
+			return errors.New("Nickname should be 1 to 5 characters long")
+		}
+		return nil
+	}
+	p := newTestPrompter("alice_long\n\nalice\nbob\n", true)
+
+	got, err := p.AskNickname(validate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "alice" {
+		t.Errorf("AskNickname returned %q, want %q", got, "alice")
+	}
+	if want := []string{"alice_long", "", "alice"}; strings.Join(asked, ",") != strings.Join(want, ",") {
+		t.Errorf("Validated nicknames are %q, want %q", asked, want)
+	}
+}
+
+func TestAskCancelled(t *testing.T) {
+	for _, input := range []string{"", "maybe\n"} {
+		t.Run(input, func(t *testing.T) {
+			if _, err := newTestPrompter(input, true).askYesNo(""); !errors.Is(err, ErrCancelled) {
+				t.Errorf("askYesNo returned %v once input is closed, want %v", err, ErrCancelled)
+			}
+		})
+	}
+}
+
+func TestAskNotInteractive(t *testing.T) {
+	p := NewPrompter(logrus.New(), strings.NewReader(" example.com:8080 \ny\n"))
+	if p.interactive {
+		t.Fatal("Prompter reading from string is interactive")
+	}
+
+	addr, err := p.AskServerAddress()
+	if err != nil || addr != "example.com:8080" {
+		t.Errorf("AskServerAddress returned %q, %v, want %q", addr, err, "example.com:8080")
+	}
+	tls, err := p.AskTLSMode()
+	if err != nil || !*tls {
+		t.Errorf("AskTLSMode returned %v, %v, want true", *tls, err)
+	}
+	if _, err = p.AskNickname(func(string) error { return nil }); err == nil || errors.Is(err, ErrCancelled) {
+		t.Errorf("AskNickname returned %v without lines to read, want error other than %v", err, ErrCancelled)
+	}
+}
+
+func TestAskNotInteractiveLastLine(t *testing.T) {
+	p := newTestPrompter("alice", false)
+	if nickname, err := p.AskNickname(func(string) error { return nil }); err != nil || nickname != "alice" {
+		t.Errorf("AskNickname returned %q, %v for line without newline, want %q", nickname, err, "alice")
+	}
+}
+
+func TestAskNotInteractiveInvalid(t *testing.T) {
+	p := newTestPrompter("maybe\ny\n", false)
+	if _, err := p.askYesNo(""); err == nil {
+		t.Error("askYesNo returned no error for invalid line")
+	}
+	// Invalid line is not asked again, so the next prompt reads the next line.
+	if password, err := p.AskPassword(); err != nil || password != "y" {
+		t.Errorf("AskPassword returned %q, %v, want %q", password, err, "y")
+	}
+}
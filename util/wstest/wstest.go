@@ -0,0 +1,136 @@
+// Package wstest provides websocket server to test chat clients against. Tests script it, reading what client sends
+// and writing responses as chat server would do.
+package wstest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Timeout is the maximum time to wait for client to connect or send a message.
+const Timeout = time.Second * 5
+
+// Server represents websocket server accepting any amount of connections on any path. Its methods should be called
+// from the test goroutine, since they fail the test on errors.
+type Server struct {
+	t     testing.TB
+	http  *httptest.Server
+	conns chan *Conn
+}
+
+// Conn represents connection of client to Server.
+type Conn struct {
+	t      testing.TB
+	ws     *websocket.Conn
+	header http.Header
+}
+
+// NewServer starts new server, which is closed once test <t> finishes.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	return newServer(t, websocket.Upgrader{})
+}
+
+// NewCompressionServer starts new server like NewServer, which also negotiates permessage-deflate compression with
+// clients supporting it and compresses messages it writes.
+func NewCompressionServer(t testing.TB) *Server {
+	t.Helper()
+	return newServer(t, websocket.Upgrader{EnableCompression: true})
+}
+
+// newServer starts new server accepting connections with <upgrader>, which is closed once test <t> finishes.
+func newServer(t testing.TB, upgrader websocket.Upgrader) *Server {
+	t.Helper()
+	s := &Server{t: t, conns: make(chan *Conn, 16)}
+	s.http = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade connection: %v", err)
+			return
+		}
+		ws.EnableWriteCompression(upgrader.EnableCompression)
+		s.conns <- &Conn{t: t, ws: ws, header: r.Header}
+	}))
+	t.Cleanup(s.http.Close)
+	return s
+}
+
+// Addr returns address of server in form of 'host:port'.
+func (s *Server) Addr() string {
+	s.t.Helper()
+	u, err := url.Parse(s.http.URL)
+	if err != nil {
+		s.t.Fatalf("Parse server URL: %v", err)
+	}
+	return u.Host
+}
+
+// Accept returns the next connection of client, failing the test if client doesn't connect within Timeout.
+func (s *Server) Accept() *Conn {
+	s.t.Helper()
+	select {
+	case conn := <-s.conns:
+		s.t.Cleanup(func() { _ = conn.ws.Close() })
+		return conn
+	case <-time.After(Timeout):
+		s.t.Fatalf("Client didn't connect within %v", Timeout)
+		return nil
+	}
+}
+
+// Header returns headers of websocket handshake request client connected with.
+func (c *Conn) Header() http.Header {
+	return c.header
+}
+
+// Read returns the next message sent by client, failing the test if it's not received within Timeout.
+func (c *Conn) Read() map[string]any {
+	c.t.Helper()
+	if err := c.ws.SetReadDeadline(time.Now().Add(Timeout)); err != nil {
+		c.t.Fatalf("Set read deadline: %v", err)
+	}
+	var msg map[string]any
+	if err := c.ws.ReadJSON(&msg); err != nil {
+		c.t.Fatalf("Read message from client: %v", err)
+	}
+	return msg
+}
+
+// ReadType returns the next message of <msgType> sent by client, skipping messages of other types.
+func (c *Conn) ReadType(msgType float64) map[string]any {
+	c.t.Helper()
+	for {
+		if msg := c.Read(); msg["type"] == msgType {
+			return msg
+		}
+	}
+}
+
+// Write sends JSON encoding of <msg> to client.
+func (c *Conn) Write(msg any) {
+	c.t.Helper()
+	bytes, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatalf("Encode message: %v", err)
+	}
+	c.WriteRaw(string(bytes))
+}
+
+// WriteRaw sends <data> to client as is, e.g. to check how client handles malformed messages.
+func (c *Conn) WriteRaw(data string) {
+	c.t.Helper()
+	if err := c.ws.WriteMessage(websocket.TextMessage, []byte(data)); err != nil {
+		c.t.Fatalf("Write message to client: %v", err)
+	}
+}
+
+// Drop closes connection without close message, as if network failed.
+func (c *Conn) Drop() {
+	_ = c.ws.Close()
+}
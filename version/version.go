@@ -0,0 +1,28 @@
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Build metadata, set at build time with -ldflags "-X go_chat_client/version.Version=v1.0.0", same for Commit and Date.
+var (
+	Version = "dev" // Program version
+	Commit  = ""    // Git commit the program is built from
+	Date    = ""    // Build date
+)
+
+// String returns program version with build metadata which is known, and Go version the program is built with, e.g.
+// "v1.0.0 (commit 3eb61d3, built 2024-01-02, go1.21.4)".
+func String() string {
+	details := []string{}
+	if Commit != "" {
+		details = append(details, "commit "+Commit)
+	}
+	if Date != "" {
+		details = append(details, "built "+Date)
+	}
+	details = append(details, runtime.Version())
+	return fmt.Sprintf("%v (%v)", Version, strings.Join(details, ", "))
+}